@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/database"
+	"clouding/backend/internal/logger"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// command is a subcommand reached by its path, e.g. ["migrate", "up"]
+type command struct {
+	path    []string
+	summary string
+	run     func(flags *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{[]string{"serve"}, "Run the API server (default)", runServe},
+	{[]string{"migrate", "up"}, "Apply pending migrations", runMigrateUp},
+	{[]string{"migrate", "down"}, "Revert the last applied migrations", runMigrateDown},
+	{[]string{"migrate", "status"}, "List migrations and whether they are applied", runMigrateStatus},
+	{[]string{"seed", "components"}, "Insert or refresh the built-in components", runSeedComponents},
+	{[]string{"user", "create"}, "Create or update a user and their organization membership", runUserCreate},
+	{[]string{"secrets", "check"}, "Verify every credential has a matching secret", runSecretsCheck},
+	{[]string{"secrets", "migrate"}, "Rename secrets still named after their creator", runSecretsMigrate},
+	{[]string{"config", "print"}, "Print the effective configuration with secrets redacted", runConfigPrint},
+}
+
+// Execute runs the subcommand named by args and returns the process exit code.
+// Without a subcommand the server is started, so existing deployments keep working.
+func Execute(args []string) int {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		usage()
+		return 0
+	}
+
+	cmd, rest, ok := findCommand(args)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.Join(args, " "))
+		usage()
+		return 2
+	}
+
+	name := strings.Join(cmd.path, " ")
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := cmd.run(flags, rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func findCommand(args []string) (command, []string, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, true
+	}
+	for _, cmd := range commands {
+		if len(args) >= len(cmd.path) && strings.Join(args[:len(cmd.path)], " ") == strings.Join(cmd.path, " ") {
+			return cmd, args[len(cmd.path):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: clouding <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", strings.Join(cmd.path, " "), cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nEvery command accepts the configuration flags, see clouding <command> -h")
+}
+
+// setup loads the configuration, with the command's own flags already on flags, validates
+// the sections the command uses and installs the logger
+func setup(flags *flag.FlagSet, args []string, sections config.Section) error {
+	if err := config.LoadCloudingConfig(flags, args, sections); err != nil {
+		return err
+	}
+	slog.SetDefault(logger.New())
+	return nil
+}
+
+// openDatabase is setup followed by connecting to Postgres, the database section is
+// always validated
+func openDatabase(flags *flag.FlagSet, args []string, sections config.Section) (*sqlx.DB, error) {
+	if err := setup(flags, args, sections|config.SectionDatabase); err != nil {
+		return nil, err
+	}
+	return database.NewSqlDatabase(context.Background())
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"clouding/backend/internal/config"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runConfigPrint prints the configuration even when it is invalid, the errors follow it.
+// Only settings that can't be parsed are errors unless --validate is given.
+func runConfigPrint(flags *flag.FlagSet, args []string) error {
+	validate := flags.Bool("validate", false, "Also report settings the server would reject")
+	loadErr := config.LoadCloudingConfig(flags, args, 0)
+	if loadErr == nil && *validate {
+		loadErr = config.Validate(config.AllSections)
+	}
+	if config.Config == nil {
+		return loadErr
+	}
+	if err := config.Print(os.Stdout); err != nil {
+		return err
+	}
+	if loadErr != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+	return loadErr
+}
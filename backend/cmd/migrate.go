@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/database"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func runMigrateUp(flags *flag.FlagSet, args []string) error {
+	db, err := openDatabase(flags, args, config.SectionDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := database.Migrate(context.Background(), db); err != nil {
+		return err
+	}
+	return printMigrationStatus(db)
+}
+
+func runMigrateDown(flags *flag.FlagSet, args []string) error {
+	steps := flags.Int("steps", 1, "Number of migrations to revert")
+	db, err := openDatabase(flags, args, config.SectionDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *steps < 1 {
+		return fmt.Errorf("--steps must be at least 1")
+	}
+	if err := database.Rollback(context.Background(), db, *steps); err != nil {
+		return err
+	}
+	return printMigrationStatus(db)
+}
+
+func runMigrateStatus(flags *flag.FlagSet, args []string) error {
+	db, err := openDatabase(flags, args, config.SectionDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return printMigrationStatus(db)
+}
+
+func printMigrationStatus(db *sqlx.DB) error {
+	statuses, err := database.GetMigrationStatus(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+	secretmanager "clouding/backend/internal/utils/secretManager"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func runSecretsCheck(flags *flag.FlagSet, args []string) error {
+	db, err := openDatabase(flags, args, config.SectionSecrets)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	secretsManager, err := secretmanager.NewSecretManager()
+	if err != nil {
+		return err
+	}
+	credentialService := service.NewCredentialService(repository.NewCredentialRepository(db, secretsManager))
+	checks, err := credentialService.CheckSecrets(context.Background())
+	if err != nil {
+		return err
+	}
+
+	missing := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tORG\tNAME\tSTATUS")
+	for _, c := range checks {
+		status := "ok"
+		if c.Error != "" {
+			status = c.Error
+			missing++
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", *c.ID, *c.OrgID, *c.Name, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d of %d credentials have no readable secret", missing, len(checks))
+	}
+	return nil
+}
+
+func runSecretsMigrate(flags *flag.FlagSet, args []string) error {
+	db, err := openDatabase(flags, args, config.SectionSecrets)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	secretsManager, err := secretmanager.NewSecretManager()
+	if err != nil {
+		return err
+	}
+	credentialService := service.NewCredentialService(repository.NewCredentialRepository(db, secretsManager))
+	migrations, err := credentialService.MigrateSecrets(context.Background())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tORG\tNAME\tSTATUS")
+	for _, m := range migrations {
+		status := "migrated"
+		switch {
+		case m.Error != "":
+			status = m.Error
+			failed++
+		case m.Shared:
+			// The legacy secret held whichever of the credentials was written last
+			status = "migrated, secret was shared with another organization, verify it"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", *m.ID, *m.OrgID, *m.Name, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secrets could not be migrated", failed, len(migrations))
+	}
+	return nil
+}
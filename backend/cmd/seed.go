@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/database"
+	"context"
+	"flag"
+	"log/slog"
+)
+
+func runSeedComponents(flags *flag.FlagSet, args []string) error {
+	db, err := openDatabase(flags, args, config.SectionDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := database.SeedComponents(context.Background(), db); err != nil {
+		return err
+	}
+	slog.Info("Seeded built-in components")
+	return nil
+}
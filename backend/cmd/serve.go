@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"clouding/backend/cmd/server"
+	"clouding/backend/internal/config"
+	"flag"
+)
+
+func runServe(flags *flag.FlagSet, args []string) error {
+	if err := setup(flags, args, config.AllSections); err != nil {
+		return err
+	}
+	server.Start()
+	return nil
+}
@@ -3,18 +3,22 @@ package server
 import (
 	"clouding/backend/internal/config"
 	"clouding/backend/internal/database"
-	"clouding/backend/internal/logger"
+	"clouding/backend/internal/lifecycle"
 	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/queue"
+	"clouding/backend/internal/repository"
 	"clouding/backend/internal/router"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/tracing"
+	secretmanager "clouding/backend/internal/utils/secretManager"
 	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
-	"sync"
 	"syscall"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -22,29 +26,30 @@ import (
 
 type Server struct {
 	ctx        context.Context
-	wg         *sync.WaitGroup
+	lifecycle  *lifecycle.Lifecycle
 	ginEngine  *gin.Engine
 	httpServer *http.Server
 	db         *sqlx.DB
 	publisher  *queue.Publisher
+	// shutdownTracing flushes spans that are still buffered
+	shutdownTracing func(context.Context) error
 }
 
+// Start runs the API server until SIGTERM or SIGINT, config.Config and the
+// default logger must already be set up
 func Start() {
-	// Load configuration
-	config.LoadCloudingConfig(".")
-
-	// Initialize logger
-	log := logger.New()
-	slog.SetDefault(log)
-
 	// Initiate database
-	db := database.NewSqlDatabase(
-		config.Config.Sql.Host,
-		config.Config.Sql.Port,
-		config.Config.Sql.User,
-		config.Config.Sql.Password,
-		config.Config.Sql.Db,
-	)
+	db, err := database.NewSqlDatabase(context.Background())
+	if err != nil {
+		slog.Error("Failed to connect to database", "ERR", err)
+		os.Exit(1)
+	}
+	if config.Config.Sql.AutoMigrate {
+		if err := database.Migrate(context.Background(), db); err != nil {
+			slog.Error("Failed to migrate database", "ERR", err)
+			os.Exit(1)
+		}
+	}
 
 	publisher := queue.NewPublisher(
 		config.Config.RabbitMQ.URL,
@@ -54,13 +59,28 @@ func Start() {
 		config.Config.RabbitMQ.QueueName,
 	)
 
+	// One secret manager is shared so the Vault token is only renewed once
+	secretsManager, err := secretmanager.NewSecretManager()
+	if err != nil {
+		slog.Error("Failed to create secret manager", "Backend", config.Config.SecretManager.Backend, "ERR", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		slog.Error("Failed to set up tracing", "ERR", err)
+		os.Exit(1)
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
-	wg := &sync.WaitGroup{}
+	lc := lifecycle.New()
 
 	// Set up Gin
 	ginEngine := gin.New()
+	// Lets a *gin.Context be passed where a context.Context is expected, e.g. to read the request ID
+	ginEngine.ContextWithFallback = true
 
 	// Add root route for banner
 	ginEngine.GET("/", func(c *gin.Context) {
@@ -68,26 +88,49 @@ func Start() {
 	})
 
 	ginEngine.Use(gin.Recovery())
+	ginEngine.Use(middleware.RequestIDMiddleware())
+	ginEngine.Use(middleware.TracingMiddleware())
 	ginEngine.Use(middleware.SlogMiddleware())
-	ginEngine.Use(middleware.JWTAuthMiddleware())
+	ginEngine.Use(middleware.MetricsMiddleware())
+	// Innermost of the global middlewares so the ones above see the rendered status
+	ginEngine.Use(middleware.ErrorMiddleware())
+
+	// Probes must stay reachable without credentials
+	router.SetupHealthRouter(ginEngine.Group(""), db, publisher, secretsManager)
+	router.SetupMetricsRouter(ginEngine.Group(""), db)
+
+	// Built from the routes on first use, so it also covers the ones registered below
+	spec := openapi.NewSpec(ginEngine)
+	// The document is public so that clients can be generated without an account
+	router.SetupOpenAPIRouter(ginEngine.Group("/api/v1"), spec)
 
-	v1RouteGroup := ginEngine.Group("/api/v1")
+	apiTokenService := service.NewApiTokenService(repository.NewApiTokenRepository(db))
+	// Requests are only validated once authenticated, so the document isn't probed anonymously
+	v1RouteGroup := ginEngine.Group("/api/v1", middleware.JWTAuthMiddleware(apiTokenService), middleware.OpenAPIValidationMiddleware(spec))
 
 	//Register routes here
-	router.SetupRouter(v1RouteGroup, db, publisher)
+	router.SetupRouter(v1RouteGroup, db, publisher, secretsManager, lc)
+
+	// Worker callbacks authenticate with signed requests instead of user tokens
+	internalRouteGroup := ginEngine.Group("/internal/v1", middleware.WorkerAuthMiddleware(), middleware.OpenAPIValidationMiddleware(spec))
+	router.SetupInternalRouter(internalRouteGroup, db, publisher)
 
 	httpServer := &http.Server{
 		Addr:    ":" + config.Config.Server.Port,
 		Handler: ginEngine,
 	}
+	// Shutdown waits for handlers to return, SSE handlers only do so once told to drain
+	httpServer.RegisterOnShutdown(lc.Drain)
 
 	server := &Server{
 		ctx:        ctx,
-		wg:         wg,
+		lifecycle:  lc,
 		ginEngine:  ginEngine,
 		httpServer: httpServer,
 		db:         db,
 		publisher:  publisher,
+
+		shutdownTracing: shutdownTracing,
 	}
 
 	// Print banner
@@ -107,21 +150,34 @@ func (s *Server) runServer() {
 	}
 }
 
+// shutdown stops in dependency order: HTTP (which drains the streams), background work,
+// the publisher, the database and finally the tracer, all within server.shutdownTimeout
 func (s *Server) shutdown() {
-	slog.Info("Shutting down the server")
-	// Give server 10s to gracefully shutdown
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	slog.Info("Shutting down the server", "Timeout", config.Config.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Config.Server.ShutdownTimeout)
 	defer cancel()
+
+	// Closes the listeners and waits for in-flight requests, SSE streams send a shutdown
+	// event and end as soon as draining starts
 	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
 		slog.Error("server forced to shutdown", "Error", err)
 	}
-	if err := s.db.Close(); err != nil {
-		slog.Error("DB error on shutdown", "Error", err)
+	if err := s.lifecycle.WaitStreams(shutdownCtx); err != nil {
+		slog.Error("Streams still open on shutdown", "Error", err)
+	}
+	if err := s.lifecycle.WaitBackground(shutdownCtx); err != nil {
+		slog.Error("Background work still running on shutdown", "Error", err)
 	}
 	if err := s.publisher.Close(); err != nil {
 		slog.Error("Publisher error on shutdown", "Error", err)
 	}
-	s.wg.Wait()
+	if err := s.db.Close(); err != nil {
+		slog.Error("DB error on shutdown", "Error", err)
+	}
+	if err := s.shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Tracing error on shutdown", "Error", err)
+	}
+	slog.Info("Server stopped")
 }
 
 func getBanner() string {
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/model/user"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+	"context"
+	"flag"
+	"fmt"
+)
+
+func runUserCreate(flags *flag.FlagSet, args []string) error {
+	id := flags.String("id", "", "User ID, the sub claim of their tokens; generated when empty")
+	email := flags.String("email", "", "Email address")
+	name := flags.String("name", "", "Display name")
+	orgId := flags.Int("org", 0, "Organization to add the user to, besides their personal one")
+	role := flags.String("role", string(organization.RoleViewer), "Role in --org: owner, admin, operator or viewer")
+	db, err := openDatabase(flags, args, config.SectionDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *email == "" {
+		return fmt.Errorf("--email is required")
+	}
+
+	ctx := context.Background()
+	u := &user.User{Email: email}
+	if *id != "" {
+		u.ID = id
+	}
+	if *name != "" {
+		u.Name = name
+	}
+	if err := service.NewUserService(repository.NewUserRepository(db)).ProvisionUser(ctx, u); err != nil {
+		return err
+	}
+
+	orgService := service.NewOrganizationService(repository.NewOrganizationRepository(db))
+	personal, err := orgService.ResolveMembership(ctx, *u.ID, nil)
+	if err != nil {
+		return fmt.Errorf("creating personal organization: %w", err)
+	}
+	fmt.Printf("user %s, personal organization %d\n", *u.ID, *personal.OrganizationID)
+
+	if *orgId != 0 {
+		r := organization.Role(*role)
+		m := &organization.Membership{OrganizationID: orgId, UserID: u.ID, Role: &r}
+		if err := orgService.GrantMembership(ctx, m); err != nil {
+			return fmt.Errorf("adding to organization %d: %w", *orgId, err)
+		}
+		fmt.Printf("%s of organization %d\n", r, *orgId)
+	}
+	return nil
+}
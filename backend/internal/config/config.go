@@ -1,8 +1,11 @@
 package config
 
 import (
-	"log/slog"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,72 +13,194 @@ import (
 type CloudingConfig struct {
 	Sql struct {
 		// SQL Configuration
-		Host     string `mapstructure:"host" default:"0.0.0.0" description:"the sql host address"`
-		Port     string `mapstructure:"port" default:"7653" description:"the sql read port"`
-		User     string `mapstructure:"user" description:"the sql user"`
-		Password string `mapstructure:"password" description:"the sql password"`
-		Db       string `mapstructure:"db" description:"the sql db"`
+		DSN      string `mapstructure:"dsn" env:"SQL.DSN" secret:"true" description:"Full connection string or URL, overrides the individual settings below"`
+		Host     string `mapstructure:"host" env:"SQL.HOST" default:"0.0.0.0" description:"the sql host address"`
+		Port     string `mapstructure:"port" env:"SQL.PORT" default:"7653" description:"the sql read port"`
+		User     string `mapstructure:"user" env:"SQL.USERNAME" description:"the sql user"`
+		Password string `mapstructure:"password" env:"SQL.PASSWORD" secret:"true" description:"the sql password"`
+		Db       string `mapstructure:"db" env:"SQL.DB" description:"the sql db"`
+
+		// TLS Configuration
+		SSLMode     string `mapstructure:"sslMode" env:"SQL.SSL.MODE" default:"disable" description:"disable, allow, prefer, require, verify-ca or verify-full"`
+		SSLRootCert string `mapstructure:"sslRootCert" env:"SQL.SSL.ROOT.CERT" description:"CA certificate used to verify the server"`
+		SSLCert     string `mapstructure:"sslCert" env:"SQL.SSL.CERT" description:"Client certificate"`
+		SSLKey      string `mapstructure:"sslKey" env:"SQL.SSL.KEY" description:"Client certificate key"`
+
+		// Pool Configuration
+		MaxOpenConns     int           `mapstructure:"maxOpenConns" env:"SQL.MAX.OPEN.CONNS" default:"30" description:"Maximum open connections"`
+		MaxIdleConns     int           `mapstructure:"maxIdleConns" env:"SQL.MAX.IDLE.CONNS" default:"5" description:"Maximum idle connections"`
+		ConnMaxLifetime  time.Duration `mapstructure:"connMaxLifetime" env:"SQL.CONN.MAX.LIFETIME" default:"30m" description:"How long a connection is reused"`
+		ConnMaxIdleTime  time.Duration `mapstructure:"connMaxIdleTime" env:"SQL.CONN.MAX.IDLE.TIME" default:"5m" description:"How long a connection may sit idle"`
+		StatementTimeout time.Duration `mapstructure:"statementTimeout" env:"SQL.STATEMENT.TIMEOUT" default:"30s" description:"Server side statement timeout, 0 disables it"`
+
+		// Startup Configuration
+		ConnectTimeout time.Duration `mapstructure:"connectTimeout" env:"SQL.CONNECT.TIMEOUT" default:"5s" description:"Timeout of a single connection attempt"`
+		ConnectRetries int           `mapstructure:"connectRetries" env:"SQL.CONNECT.RETRIES" default:"5" description:"Connection attempts on startup before giving up"`
+		ConnectBackoff time.Duration `mapstructure:"connectBackoff" env:"SQL.CONNECT.BACKOFF" default:"1s" description:"Wait before the first retry, doubled after each attempt"`
+
+		// Schema Configuration
+		AutoMigrate bool `mapstructure:"autoMigrate" env:"SQL.AUTO.MIGRATE" default:"false" description:"Apply pending migrations on server start"`
 	} `mapstructure:"sql" description:"the sql configuration"`
 
 	Server struct {
 		// Server Configuration
-		LogLevel string `mapstructure:"logLevel" default:"info" description:"Log Level"`
-		Port     string `mapstructure:"port" default:"8080" description:"Port to run the server"`
+		LogLevel          string  `mapstructure:"logLevel" env:"SERVER.LOG.LEVEL" default:"info" description:"Log Level, trace, debug, info, warn or error"`
+		LogFormat         string  `mapstructure:"logFormat" env:"SERVER.LOG.FORMAT" default:"console" description:"Log output, console for humans or json for log shippers"`
+		AccessLogSampling float64 `mapstructure:"accessLogSampling" env:"SERVER.LOG.ACCESS.SAMPLING" default:"1" description:"Fraction of successful requests written to the access log, errors are always logged"`
+		Port              string  `mapstructure:"port" env:"SERVER.PORT" default:"8080" required:"true" description:"Port to run the server"`
+
+		ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout" env:"SERVER.SHUTDOWN.TIMEOUT" default:"30s" description:"How long shutdown waits for requests, streams and background work"`
 	} `mapstructure:"server" description:"the server configuration"`
 
 	SupabaseAuth struct {
-		JwtSecret []byte `mapstructure:"jwtSecret" description:"Supabase JWT Secret"`
+		JwtSecret []byte `mapstructure:"jwtSecret" env:"SUPABASE.JWT.SECRET" secret:"true" description:"Supabase JWT Secret"`
 	} `mapstructure:"supabaseAuth" description:"the supabase auth configuration"`
 
+	Auth struct {
+		JwksURL      string        `mapstructure:"jwksUrl" env:"AUTH.JWKS.URL" description:"JWKS URL or file path used to verify RS256/ES256 tokens"`
+		JwksCacheTTL time.Duration `mapstructure:"jwksCacheTtl" env:"AUTH.JWKS.CACHE.TTL" default:"15m" description:"How long JWKS keys are cached"`
+		Issuer       string        `mapstructure:"issuer" env:"AUTH.JWT.ISSUER" description:"Expected iss claim, not checked when empty"`
+		Audience     string        `mapstructure:"audience" env:"AUTH.JWT.AUDIENCE" description:"Expected aud claim, not checked when empty"`
+		Leeway       time.Duration `mapstructure:"leeway" env:"AUTH.JWT.LEEWAY" default:"30s" description:"Clock skew tolerated when validating exp, nbf and iat"`
+	} `mapstructure:"auth" description:"the token verification configuration"`
+
+	SecretManager struct {
+		Backend string `mapstructure:"backend" env:"SECRET.MANAGER.BACKEND" default:"vault" description:"Secret backend used for credentials, vault or aws"`
+	} `mapstructure:"secretManager" description:"the secret manager configuration"`
+
 	Vault struct {
-		VaultSecretEnginePath     string `mapstructure:"vaultSecretEnginePath" description:"Vault Secret Engine Path"`
-		VaultSecretEngineMetaPath string `mapstructure:"vaultSecretEngineMetaPath" description:"Vault Secret Engine Meta Path"`
-	}
+		VaultSecretEnginePath     string `mapstructure:"vaultSecretEnginePath" env:"VAULT_SECRET_ENGINE" description:"Deprecated, KV mount and prefix as a path, e.g. secret/data/clouding/"`
+		VaultSecretEngineMetaPath string `mapstructure:"vaultSecretEngineMetaPath" env:"VAULT_SECRET_ENGINE_METADATA" description:"Deprecated, KV v2 metadata path, e.g. secret/metadata/clouding/"`
+		Mount                     string `mapstructure:"mount" env:"VAULT_KV_MOUNT" description:"KV mount, its version is detected on startup"`
+		Prefix                    string `mapstructure:"prefix" env:"VAULT_KV_PREFIX" description:"Path under the KV mount where secrets are kept"`
+		AuthMethod                string `mapstructure:"authMethod" env:"VAULT_AUTH_METHOD" default:"token" description:"How to log in to Vault, token, approle or kubernetes"`
+		AppRoleMount              string `mapstructure:"appRoleMount" env:"VAULT_APPROLE_MOUNT" default:"approle" description:"Mount of the AppRole auth method"`
+		RoleID                    string `mapstructure:"roleId" env:"VAULT_APPROLE_ROLE_ID" description:"AppRole role ID"`
+		SecretID                  string `mapstructure:"secretId" env:"VAULT_APPROLE_SECRET_ID" secret:"true" description:"AppRole secret ID"`
+		KubernetesMount           string `mapstructure:"kubernetesMount" env:"VAULT_KUBERNETES_MOUNT" default:"kubernetes" description:"Mount of the Kubernetes auth method"`
+		KubernetesRole            string `mapstructure:"kubernetesRole" env:"VAULT_KUBERNETES_ROLE" description:"Vault role bound to the service account"`
+		KubernetesTokenPath       string `mapstructure:"kubernetesTokenPath" env:"VAULT_KUBERNETES_TOKEN_PATH" default:"/var/run/secrets/kubernetes.io/serviceaccount/token" description:"Service account token used to log in"`
+	} `mapstructure:"vault" description:"the vault configuration"`
 
 	RabbitMQ struct {
-		URL       string `mapstructure:"url" description:"RabbitMQ connection URL"`
-		PORT      string `mapstructure:"port" description:"RabbitMQ connection PORT"`
-		Username  string `mapstructure:"username" description:"RabbitMQ connection username"`
-		Password  string `mapstructure:"password" description:"RabbitMQ connection password"`
-		QueueName string `mapstructure:"queueName" description:"RabbitMQ Queue name"`
+		URL       string `mapstructure:"url" env:"RABBITMQ.URL" required:"true" description:"RabbitMQ connection URL"`
+		PORT      string `mapstructure:"port" env:"RABBITMQ.PORT" description:"RabbitMQ connection PORT"`
+		Username  string `mapstructure:"username" env:"RABBITMQ.USERNAME" description:"RabbitMQ connection username"`
+		Password  string `mapstructure:"password" env:"RABBITMQ.PASSWORD" secret:"true" description:"RabbitMQ connection password"`
+		QueueName string `mapstructure:"queueName" env:"RABBITMQ.QUEUE.NAME" required:"true" description:"RabbitMQ Queue name"`
 	} `mapstructure:"rabbitmq"`
 
 	Loki struct {
-		URL string `mapstructure:"url" description:"Loki URL"`
-	}
+		URL string `mapstructure:"url" env:"LOKI.URL" description:"Loki URL"`
+	} `mapstructure:"loki"`
+
+	Metrics struct {
+		Enabled bool   `mapstructure:"enabled" env:"METRICS.ENABLED" default:"true" description:"Expose Prometheus metrics"`
+		Path    string `mapstructure:"path" env:"METRICS.PATH" default:"/metrics" description:"Path metrics are served on"`
+	} `mapstructure:"metrics" description:"the prometheus metrics configuration"`
+
+	OpenAPI struct {
+		Validation string `mapstructure:"validation" env:"OPENAPI.VALIDATION" default:"off" description:"Check traffic against the OpenAPI document, off, request or both"`
+	} `mapstructure:"openapi" description:"the openapi document configuration"`
+
+	Tracing struct {
+		Enabled      bool              `mapstructure:"enabled" env:"TRACING.ENABLED" default:"false" description:"Export OpenTelemetry traces over OTLP"`
+		Endpoint     string            `mapstructure:"endpoint" env:"TRACING.ENDPOINT" default:"localhost:4318" description:"OTLP HTTP collector host:port"`
+		Insecure     bool              `mapstructure:"insecure" env:"TRACING.INSECURE" default:"false" description:"Send traces over plain HTTP"`
+		Headers      map[string]string `mapstructure:"headers" env:"TRACING.HEADERS" secret:"true" description:"Headers sent with every export, as name:value pairs"`
+		ServiceName  string            `mapstructure:"serviceName" env:"TRACING.SERVICE.NAME" default:"clouding-backend" description:"service.name resource attribute"`
+		Sampler      string            `mapstructure:"sampler" env:"TRACING.SAMPLER" default:"parentbased_traceidratio" description:"always_on, always_off, traceidratio or parentbased_traceidratio"`
+		SamplerRatio float64           `mapstructure:"samplerRatio" env:"TRACING.SAMPLER.RATIO" default:"1" description:"Fraction of traces sampled by the ratio samplers"`
+	} `mapstructure:"tracing" description:"the opentelemetry tracing configuration"`
+
+	RateLimit struct {
+		Enabled bool              `mapstructure:"enabled" env:"RATELIMIT.ENABLED" default:"true" description:"Enable rate limiting"`
+		Store   string            `mapstructure:"store" env:"RATELIMIT.STORE" default:"memory" description:"Bucket store, memory or postgres for multi-instance deployments"`
+		Default string            `mapstructure:"default" env:"RATELIMIT.DEFAULT" default:"20/s" description:"Rule for routes without a group, as <requests>/<s|m|h>"`
+		Groups  map[string]string `mapstructure:"groups" env:"RATELIMIT.GROUPS" description:"Per route group rules, as group:rule pairs"`
+	} `mapstructure:"rateLimit" description:"the rate limiting configuration"`
+
+	Idempotency struct {
+		Retention time.Duration `mapstructure:"retention" env:"IDEMPOTENCY.RETENTION" default:"24h" description:"How long responses to requests with an Idempotency-Key are replayed"`
+	} `mapstructure:"idempotency" description:"the idempotency key configuration"`
+
+	Worker struct {
+		Secret       string            `mapstructure:"secret" env:"WORKER.HMAC.SECRET" secret:"true" description:"Shared HMAC secret used by deployment workers"`
+		Secrets      map[string]string `mapstructure:"secrets" env:"WORKER.HMAC.SECRETS" secret:"true" description:"Per-worker HMAC secrets keyed by worker ID, as id:secret pairs"`
+		MaxClockSkew time.Duration     `mapstructure:"maxClockSkew" env:"WORKER.MAX.CLOCK.SKEW" default:"5m" description:"How old a signed worker request may be"`
+	} `mapstructure:"worker" description:"the deployment worker authentication configuration"`
 }
 
 var Config *CloudingConfig
 
-func LoadCloudingConfig(path string) {
-	err := godotenv.Load()
-
-	if err != nil {
-		slog.Error("Error loading .env file")
-		panic(err)
+// LoadCloudingConfig builds the configuration from, in increasing order of precedence,
+// the default struct tags, an optional YAML or TOML file, the environment (including an
+// optional .env file) and command line flags. Every leaf can be set with a flag named after
+// its mapstructure path, e.g. --sql.host or --rateLimit.enabled, the flags are added to
+// flags so commands can define their own next to them.
+// Only the given sections are validated, all of their missing or invalid settings are
+// reported together. Config is still set in that case so the effective configuration can
+// be inspected.
+func LoadCloudingConfig(flags *flag.FlagSet, args []string, sections Section) error {
+	cfg := &CloudingConfig{}
+	fields := collectFields(cfg)
+
+	configFile := flags.String("config", os.Getenv("CLOUDING_CONFIG"), "Path to a YAML or TOML config file")
+	envFile := flags.String("env-file", ".env", "Path to a .env file, skipped when missing")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		flagValues[f.key] = flags.String(f.key, f.defaultValue, f.description)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
 	}
 
-	Config = &CloudingConfig{}
-	Config.Sql.Host = os.Getenv("SQL.HOST")
-	Config.Sql.Port = os.Getenv("SQL.PORT")
-	Config.Sql.User = os.Getenv("SQL.USERNAME")
-	Config.Sql.Password = os.Getenv("SQL.PASSWORD")
-	Config.Sql.Db = os.Getenv("SQL.DB")
-
-	Config.Server.LogLevel = os.Getenv("SERVER.LOG.LEVEL")
-	Config.Server.Port = os.Getenv("SERVER.PORT")
-
-	Config.SupabaseAuth.JwtSecret = []byte(os.Getenv("SUPABASE.JWT.SECRET"))
+	// Variables already in the environment take precedence over the .env file
+	if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("loading %s: %w", *envFile, err)
+	}
 
-	Config.Vault.VaultSecretEnginePath = os.Getenv("VAULT_SECRET_ENGINE")
-	Config.Vault.VaultSecretEngineMetaPath = os.Getenv("VAULT_SECRET_ENGINE_METADATA")
+	var errs []error
+	for _, f := range fields {
+		if f.defaultValue != "" {
+			errs = append(errs, f.set(f.defaultValue, "default"))
+		}
+	}
 
-	Config.RabbitMQ.URL = os.Getenv("RABBITMQ.URL")
-	Config.RabbitMQ.PORT = os.Getenv("RABBITMQ.PORT")
-	Config.RabbitMQ.Username = os.Getenv("RABBITMQ.USERNAME")
-	Config.RabbitMQ.Password = os.Getenv("RABBITMQ.PASSWORD")
-	Config.RabbitMQ.QueueName = os.Getenv("RABBITMQ.QUEUE.NAME")
+	if *configFile != "" {
+		values, err := readConfigFile(*configFile)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if raw, ok := lookupPath(values, f.key); ok {
+				errs = append(errs, f.set(raw, *configFile))
+			}
+		}
+	}
 
-	Config.Loki.URL = os.Getenv("LOKI.URL")
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if raw, ok := os.LookupEnv(f.env); ok && raw != "" {
+			errs = append(errs, f.set(raw, f.env))
+		}
+	}
 
+	// Only flags given explicitly override, their defaults are just for --help
+	flags.Visit(func(fl *flag.Flag) {
+		for _, f := range fields {
+			if f.key == fl.Name {
+				errs = append(errs, f.set(*flagValues[f.key], "--"+fl.Name))
+			}
+		}
+	})
+
+	errs = append(errs, validate(cfg, fields, sections)...)
+	Config = cfg
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
 }
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// field is a single configurable leaf of CloudingConfig
+type field struct {
+	key          string
+	env          string
+	defaultValue string
+	description  string
+	required     bool
+	secret       bool
+	value        reflect.Value
+}
+
+// collectFields walks the config struct and returns its leaves keyed by their
+// dotted mapstructure path, e.g. sql.host
+func collectFields(cfg *CloudingConfig) []*field {
+	var fields []*field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := sf.Tag.Get("mapstructure")
+			if name == "" {
+				name = strings.ToLower(sf.Name[:1]) + sf.Name[1:]
+			}
+			key := prefix + name
+
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			fields = append(fields, &field{
+				key:          key,
+				env:          sf.Tag.Get("env"),
+				defaultValue: sf.Tag.Get("default"),
+				description:  sf.Tag.Get("description"),
+				required:     sf.Tag.Get("required") == "true",
+				secret:       sf.Tag.Get("secret") == "true",
+				value:        v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// set parses raw into the field, source names where the value came from for error messages
+func (f *field) set(raw any, source string) error {
+	if m, ok := raw.(map[string]any); ok && f.value.Kind() == reflect.Map {
+		values := make(map[string]string, len(m))
+		for k, v := range m {
+			values[k] = fmt.Sprint(v)
+		}
+		f.value.Set(reflect.ValueOf(values))
+		return nil
+	}
+
+	s := fmt.Sprint(raw)
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case []byte:
+		f.value.SetBytes([]byte(s))
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: %q from %s is not a boolean", f.key, s, source)
+		}
+		f.value.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%s: %q from %s is not an integer", f.key, s, source)
+		}
+		f.value.SetInt(int64(n))
+	case float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q from %s is not a number", f.key, s, source)
+		}
+		f.value.SetFloat(n)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %q from %s is not a duration", f.key, s, source)
+		}
+		f.value.SetInt(int64(d))
+	case map[string]string:
+		f.value.Set(reflect.ValueOf(parseMap(s)))
+	default:
+		return fmt.Errorf("%s: unsupported type %s", f.key, f.value.Type())
+	}
+	return nil
+}
+
+// parseMap parses comma separated key:value pairs
+func parseMap(s string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || k == "" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func readConfigFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// lookupPath finds a dotted key in nested file values, keys match case-insensitively
+func lookupPath(values map[string]any, key string) (any, bool) {
+	var current any = values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for k, v := range m {
+			if strings.EqualFold(k, part) {
+				current, found = v, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
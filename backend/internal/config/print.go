@@ -0,0 +1,60 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "<redacted>"
+
+// Print writes the effective configuration as YAML, in the same layout the config
+// file takes, with every secret replaced by a placeholder
+func Print(w io.Writer) error {
+	root := map[string]any{}
+	for _, f := range collectFields(Config) {
+		var value any
+		switch v := f.value.Interface().(type) {
+		case []byte:
+			value = string(v)
+		case time.Duration:
+			value = v.String()
+		case map[string]string:
+			m := make(map[string]string, len(v))
+			for k, item := range v {
+				m[k] = item
+				if f.secret {
+					m[k] = redacted
+				}
+			}
+			value = m
+		default:
+			value = v
+		}
+		if f.secret && !f.value.IsZero() && f.value.Kind() != reflect.Map {
+			value = redacted
+		}
+
+		parts := strings.Split(f.key, ".")
+		node := root
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = value
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var rateLimitRulePattern = regexp.MustCompile(`^[1-9][0-9]*/[smh]$`)
+
+// Section is a part of the configuration. Commands only validate the sections they use, so
+// that e.g. migrate doesn't need RabbitMQ settings. Logging is always validated.
+type Section int
+
+const (
+	// SectionDatabase is sql.*
+	SectionDatabase Section = 1 << iota
+	// SectionSecrets is secretManager.* and vault.*
+	SectionSecrets
+	// SectionServer is everything else the API server needs
+	SectionServer
+
+	AllSections = SectionDatabase | SectionSecrets | SectionServer
+)
+
+// sectionOf returns the section a dotted key belongs to
+func sectionOf(key string) Section {
+	top, _, _ := strings.Cut(key, ".")
+	switch top {
+	case "sql":
+		return SectionDatabase
+	case "secretManager", "vault":
+		return SectionSecrets
+	}
+	return SectionServer
+}
+
+// Validate checks sections of the loaded Config, for commands that only learn from their
+// own flags which sections they need
+func Validate(sections Section) error {
+	if err := errors.Join(validate(Config, collectFields(Config), sections)...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// validate reports every missing required setting and every invalid value of sections at once
+func validate(cfg *CloudingConfig, fields []*field, sections Section) []error {
+	var errs []error
+	for _, f := range fields {
+		if f.required && sections&sectionOf(f.key) != 0 && f.value.IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required (env %s or --%s)", f.key, f.env, f.key))
+		}
+	}
+
+	errs = append(errs, validateLogging(cfg)...)
+	if sections&SectionDatabase != 0 {
+		errs = append(errs, validateDatabase(cfg)...)
+	}
+	if sections&SectionSecrets != 0 {
+		errs = append(errs, validateSecrets(cfg)...)
+	}
+	if sections&SectionServer != 0 {
+		errs = append(errs, validateServer(cfg)...)
+	}
+	return errs
+}
+
+// validateLogging checks the settings of the logger every command installs
+func validateLogging(cfg *CloudingConfig) []error {
+	var errs []error
+	if !slices.Contains([]string{"trace", "debug", "info", "warn", "error"}, cfg.Server.LogLevel) {
+		errs = append(errs, fmt.Errorf("server.logLevel must be one of trace, debug, info, warn, error, got %q", cfg.Server.LogLevel))
+	}
+	if cfg.Server.LogFormat != "console" && cfg.Server.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("server.logFormat must be console or json, got %q", cfg.Server.LogFormat))
+	}
+	return errs
+}
+
+func validateDatabase(cfg *CloudingConfig) []error {
+	var errs []error
+	if cfg.Sql.DSN == "" {
+		for _, setting := range [][2]string{
+			{"sql.host", cfg.Sql.Host},
+			{"sql.port", cfg.Sql.Port},
+			{"sql.user", cfg.Sql.User},
+			{"sql.db", cfg.Sql.Db},
+		} {
+			if setting[1] == "" {
+				errs = append(errs, fmt.Errorf("%s is required when sql.dsn (env SQL.DSN) is not set", setting[0]))
+			}
+		}
+		if !slices.Contains([]string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}, cfg.Sql.SSLMode) {
+			errs = append(errs, fmt.Errorf("sql.sslMode must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", cfg.Sql.SSLMode))
+		}
+		if (cfg.Sql.SSLMode == "verify-ca" || cfg.Sql.SSLMode == "verify-full") && cfg.Sql.SSLRootCert == "" {
+			errs = append(errs, fmt.Errorf("sql.sslRootCert (env SQL.SSL.ROOT.CERT) is required with sslMode %s", cfg.Sql.SSLMode))
+		}
+		if (cfg.Sql.SSLCert == "") != (cfg.Sql.SSLKey == "") {
+			errs = append(errs, fmt.Errorf("sql.sslCert and sql.sslKey must be set together"))
+		}
+	}
+	if cfg.Sql.MaxOpenConns < 1 || cfg.Sql.MaxIdleConns < 0 || cfg.Sql.MaxIdleConns > cfg.Sql.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("sql.maxOpenConns must be at least 1 and sql.maxIdleConns between 0 and maxOpenConns"))
+	}
+	if cfg.Sql.ConnectRetries < 0 {
+		errs = append(errs, fmt.Errorf("sql.connectRetries must not be negative"))
+	}
+	for key, d := range map[string]time.Duration{
+		"sql.connMaxLifetime":  cfg.Sql.ConnMaxLifetime,
+		"sql.connMaxIdleTime":  cfg.Sql.ConnMaxIdleTime,
+		"sql.statementTimeout": cfg.Sql.StatementTimeout,
+		"sql.connectTimeout":   cfg.Sql.ConnectTimeout,
+		"sql.connectBackoff":   cfg.Sql.ConnectBackoff,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", key))
+		}
+	}
+	return errs
+}
+
+func validateSecrets(cfg *CloudingConfig) []error {
+	var errs []error
+	if cfg.SecretManager.Backend == "vault" {
+		switch cfg.Vault.AuthMethod {
+		case "token":
+		case "approle":
+			if cfg.Vault.RoleID == "" || cfg.Vault.SecretID == "" {
+				errs = append(errs, fmt.Errorf("vault.roleId (env VAULT_APPROLE_ROLE_ID) and vault.secretId (env VAULT_APPROLE_SECRET_ID) are required for approle auth"))
+			}
+		case "kubernetes":
+			if cfg.Vault.KubernetesRole == "" {
+				errs = append(errs, fmt.Errorf("vault.kubernetesRole (env VAULT_KUBERNETES_ROLE) is required for kubernetes auth"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("vault.authMethod must be one of token, approle, kubernetes, got %q", cfg.Vault.AuthMethod))
+		}
+		if cfg.Vault.Mount == "" && cfg.Vault.VaultSecretEnginePath == "" {
+			errs = append(errs, fmt.Errorf("vault.mount (env VAULT_KV_MOUNT) is required"))
+		}
+	}
+	return errs
+}
+
+func validateServer(cfg *CloudingConfig) []error {
+	var errs []error
+	if len(cfg.SupabaseAuth.JwtSecret) == 0 && cfg.Auth.JwksURL == "" {
+		errs = append(errs, fmt.Errorf("one of supabaseAuth.jwtSecret (env SUPABASE.JWT.SECRET) or auth.jwksUrl (env AUTH.JWKS.URL) is required"))
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server.shutdownTimeout must be positive"))
+	}
+	if cfg.Server.AccessLogSampling < 0 || cfg.Server.AccessLogSampling > 1 {
+		errs = append(errs, fmt.Errorf("server.accessLogSampling must be between 0 and 1, got %v", cfg.Server.AccessLogSampling))
+	}
+	if cfg.Tracing.Enabled {
+		if !slices.Contains([]string{"always_on", "always_off", "traceidratio", "parentbased_traceidratio"}, cfg.Tracing.Sampler) {
+			errs = append(errs, fmt.Errorf("tracing.sampler must be one of always_on, always_off, traceidratio, parentbased_traceidratio, got %q", cfg.Tracing.Sampler))
+		}
+		if cfg.Tracing.SamplerRatio < 0 || cfg.Tracing.SamplerRatio > 1 {
+			errs = append(errs, fmt.Errorf("tracing.samplerRatio must be between 0 and 1, got %v", cfg.Tracing.SamplerRatio))
+		}
+	}
+	if !slices.Contains([]string{"off", "request", "both"}, cfg.OpenAPI.Validation) {
+		errs = append(errs, fmt.Errorf("openapi.validation must be one of off, request, both, got %q", cfg.OpenAPI.Validation))
+	}
+	if cfg.RateLimit.Store != "memory" && cfg.RateLimit.Store != "postgres" {
+		errs = append(errs, fmt.Errorf("rateLimit.store must be memory or postgres, got %q", cfg.RateLimit.Store))
+	}
+	if !rateLimitRulePattern.MatchString(strings.TrimSpace(cfg.RateLimit.Default)) {
+		errs = append(errs, fmt.Errorf("rateLimit.default must look like <requests>/<s|m|h>, got %q", cfg.RateLimit.Default))
+	}
+	for group, rule := range cfg.RateLimit.Groups {
+		if !rateLimitRulePattern.MatchString(strings.TrimSpace(rule)) {
+			errs = append(errs, fmt.Errorf("rateLimit.groups.%s must look like <requests>/<s|m|h>, got %q", group, rule))
+		}
+	}
+	if cfg.Idempotency.Retention <= 0 {
+		errs = append(errs, fmt.Errorf("idempotency.retention must be positive, got %s", cfg.Idempotency.Retention))
+	}
+	for key, d := range map[string]time.Duration{
+		"auth.jwksCacheTtl":   cfg.Auth.JwksCacheTTL,
+		"auth.leeway":         cfg.Auth.Leeway,
+		"worker.maxClockSkew": cfg.Worker.MaxClockSkew,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", key))
+		}
+	}
+	return errs
+}
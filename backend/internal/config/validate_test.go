@@ -0,0 +1,52 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLoadValidatesSections(t *testing.T) {
+	database := []string{"--sql.host=db", "--sql.port=5432", "--sql.user=clouding", "--sql.db=clouding"}
+	server := []string{"--rabbitmq.url=mq", "--rabbitmq.queueName=jobs", "--supabaseAuth.jwtSecret=secret"}
+
+	tests := []struct {
+		name     string
+		sections Section
+		args     []string
+		wantErrs []string
+	}{
+		{"database only", SectionDatabase, database, nil},
+		{"database missing", SectionDatabase, nil, []string{"sql.user is required", "sql.db is required"}},
+		{"secrets with token auth", SectionDatabase | SectionSecrets, append(database, "--vault.mount=secret"), nil},
+		{"secrets without a mount", SectionDatabase | SectionSecrets, database, []string{"vault.mount"}},
+		{"secrets ignored by database commands", SectionDatabase, append(database, "--vault.authMethod=bogus"), nil},
+		{"server without rabbitmq", AllSections, append(database, "--vault.mount=secret"), []string{"rabbitmq.url is required", "supabaseAuth.jwtSecret"}},
+		{"server", AllSections, append(append(database, server...), "--vault.mount=secret"), nil},
+		{"logging is always checked", 0, []string{"--server.logLevel=loud"}, []string{"server.logLevel"}},
+		{"server settings ignored by database commands", SectionDatabase, append(database, "--rateLimit.default=fast"), nil},
+		{"unparseable values are always reported", 0, []string{"--sql.maxOpenConns=many"}, []string{"sql.maxOpenConns"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			args := append([]string{"--env-file=" + t.TempDir() + "/.env"}, tt.args...)
+			err := LoadCloudingConfig(flags, args, tt.sections)
+
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors mentioning %v", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
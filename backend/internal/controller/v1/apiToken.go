@@ -0,0 +1,84 @@
+package v1
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/apiToken"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiTokenController struct {
+	Service service.ApiTokenService
+}
+
+func NewApiTokenController(s service.ApiTokenService) *ApiTokenController {
+	return &ApiTokenController{Service: s}
+}
+
+func (c *ApiTokenController) GetAll(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
+	tokens, err := c.Service.GetAllByUserId(ctx.Request.Context(), userId, orgId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, tokens))
+}
+
+func (c *ApiTokenController) Create(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
+	role, _ := ctx.MustGet("orgRole").(organization.Role)
+
+	var req apiToken.ApiToken
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if req.Name == nil || *req.Name == "" {
+		ctx.Error(apperrors.InvalidParameter("name is required"))
+		return
+	}
+
+	req.UserID = &userId
+	req.OrgID = &orgId
+	token, err := c.Service.Create(ctx.Request.Context(), &req, role)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	resp := &apiToken.CreateApiTokenResponse{
+		ID:        req.ID,
+		Token:     token,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, resp))
+}
+
+func (c *ApiTokenController) Revoke(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+
+	if err := c.Service.Revoke(ctx.Request.Context(), id, userId); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrApiTokenNotFound))
+		return
+	}
+
+	resp := &apiToken.RevokeApiTokenResponse{
+		ID:        &id,
+		IsRevoked: true,
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
+}
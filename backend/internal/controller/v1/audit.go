@@ -0,0 +1,69 @@
+package v1
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/audit"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	Service service.AuditService
+}
+
+func NewAuditController(s service.AuditService) *AuditController {
+	return &AuditController{Service: s}
+}
+
+// GetAll lists the organization's audit entries, newest first.
+// Supports actorId, action, resourceType, resourceId, from, to (RFC 3339), limit and cursor.
+func (c *AuditController) GetAll(ctx *gin.Context) {
+	filter := audit.Filter{
+		OrgID:        ctx.GetInt("orgId"),
+		ActorID:      ctx.Query("actorId"),
+		Action:       ctx.Query("action"),
+		ResourceType: ctx.Query("resourceType"),
+		ResourceID:   ctx.Query("resourceId"),
+	}
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := ctx.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ctx.Error(apperrors.InvalidParameter(name + " must be an RFC 3339 timestamp"))
+				return
+			}
+			*target = &t
+		}
+	}
+
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			ctx.Error(apperrors.InvalidParameter("limit must be a positive number"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if value := ctx.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			ctx.Error(apperrors.InvalidParameter("invalid cursor"))
+			return
+		}
+		filter.Cursor = &cursor
+	}
+
+	resp, err := c.Service.GetEntries(ctx.Request.Context(), &filter)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
+}
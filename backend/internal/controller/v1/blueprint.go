@@ -1,10 +1,14 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/middleware"
 	"clouding/backend/internal/model/blueprint"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
+	"clouding/backend/internal/validation"
 	"net/http"
 	"strconv"
 	"strings"
@@ -20,64 +24,97 @@ func NewBlueprintController(s service.BlueprintService) *BlueprintController {
 	return &BlueprintController{Service: s}
 }
 
+// GetAll lists the organization's blueprints a page at a time.
+// Supports status, namePrefix, sort (id, name, createdAt, updatedAt), limit and cursor.
 func (c *BlueprintController) GetAll(ctx *gin.Context) {
-	userId := ctx.GetString("userId")
-	blueprints, err := c.Service.GetAllByUserID(ctx.Request.Context(), userId)
+	page, err := pagination.FromQuery(ctx.Request.URL.Query())
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(blueprints))
+	filter := blueprint.Filter{
+		OrgID:      ctx.GetInt("orgId"),
+		Status:     ctx.Query("status"),
+		NamePrefix: ctx.Query("namePrefix"),
+	}
+
+	blueprints, meta, err := c.Service.GetAllByOrgID(ctx.Request.Context(), &filter, page)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewListResponse(ctx, blueprints, meta))
 }
 
 func (c *BlueprintController) GetById(ctx *gin.Context) {
 	idStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
-	bp, err := c.Service.GetByID(ctx.Request.Context(), id)
+	bp, err := c.Service.GetByID(ctx.Request.Context(), id, orgId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
+		return
+	}
+	if bp == nil {
+		ctx.Error(apperrors.ErrBlueprintNotFound)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(bp))
+	utils.SetETag(ctx, bp.UpdatedAt)
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, bp))
 }
 
 func (c *BlueprintController) GetComponents(ctx *gin.Context) {
 	blueprintIdStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	blueprintId, err := strconv.Atoi(blueprintIdStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	// The components share the blueprint's version. It is read first, so a concurrent
+	// change can only make the ETag older than the components and fail the next If-Match.
+	bp, err := c.Service.GetByID(ctx.Request.Context(), blueprintId, orgId)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
-	comps, err := c.Service.GetComponentsByBlueprintID(ctx.Request.Context(), blueprintId)
+	if bp == nil {
+		ctx.Error(apperrors.ErrBlueprintNotFound)
+		return
+	}
+	comps, err := c.Service.GetComponentsByBlueprintID(ctx.Request.Context(), blueprintId, orgId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrBlueprintNotFound))
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(comps))
+	utils.SetETag(ctx, bp.UpdatedAt)
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, comps))
 }
 
 func (c *BlueprintController) Create(ctx *gin.Context) {
 	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
 	var bp blueprint.Blueprint
 	if err := ctx.ShouldBindJSON(&bp); err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Error(err.Error())
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.Check(&bp); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	bp.UserID = &userId
+	bp.OrgID = &orgId
 	if err := c.Service.Create(ctx.Request.Context(), &bp); err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
@@ -86,34 +123,44 @@ func (c *BlueprintController) Create(ctx *gin.Context) {
 		ID: bp.ID,
 	}
 
-	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(response))
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, response))
 }
 
 func (c *BlueprintController) Update(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
 
-	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 	var bp blueprint.Blueprint
 	if err := ctx.ShouldBindJSON(&bp); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.CheckPartial(&bp); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	// Set the ID from URL parameter and user ID
+	// Set the ID from URL parameter and organization ID
 	bp.ID = &id
-	bp.UserID = &userId
+	bp.OrgID = &orgId
 
-	if err := c.Service.Update(ctx.Request.Context(), &bp); err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	c.auditBefore(ctx, id, orgId)
+	if err := c.Service.Update(ctx.Request.Context(), &bp, versions); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrBlueprintNotFound))
 		return
 	}
+	utils.SetETag(ctx, bp.UpdatedAt)
 
 	// Build response with component details
 	response := &blueprint.UpdateBlueprintResponse{
@@ -121,30 +168,44 @@ func (c *BlueprintController) Update(ctx *gin.Context) {
 		UpdatedAt: bp.UpdatedAt,
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(response))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, response))
 }
 
 func (c *BlueprintController) UpdateBlueprintComponents(ctx *gin.Context) {
 	blueprintIdStr := ctx.Param("id")
 	blueprintId, err := strconv.Atoi(blueprintIdStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
 
-	// userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 	var components []*blueprint.BlueprintComponent
 	if err := ctx.ShouldBindJSON(&components); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.Check(components); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	if err := c.Service.UpdateBlueprintComponents(ctx.Request.Context(), blueprintId, components); err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	if existing, err := c.Service.GetComponentsByBlueprintID(ctx.Request.Context(), blueprintId, orgId); err == nil {
+		middleware.AuditBefore(ctx, existing)
+	}
+
+	updatedAt, err := c.Service.UpdateBlueprintComponents(ctx.Request.Context(), blueprintId, orgId, components, versions)
+	if err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrBlueprintNotFound))
 		return
 	}
+	utils.SetETag(ctx, updatedAt)
 
 	// Build response with component details
 	response := make([]*blueprint.UpdateBlueprintComponentResponse, len(components))
@@ -157,15 +218,16 @@ func (c *BlueprintController) UpdateBlueprintComponents(ctx *gin.Context) {
 		}
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(response))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, response))
 }
 
 func (c *BlueprintController) GetDeployments(ctx *gin.Context) {
 	idStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
 
@@ -175,34 +237,39 @@ func (c *BlueprintController) GetDeployments(ctx *gin.Context) {
 		parsedLimit, err := strconv.Atoi(limitStr)
 		if err == nil {
 			if parsedLimit <= 0 {
-				ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("limit must be a positive integer"))
+				ctx.Error(apperrors.InvalidParameter("limit must be a positive integer"))
 				return
 			}
 			limit = parsedLimit
 		}
 	}
 
-	deployments, err := c.Service.GetDeployments(ctx.Request.Context(), id, limit)
+	deployments, err := c.Service.GetDeployments(ctx.Request.Context(), id, orgId, limit)
 	if err != nil {
-		slog.Error("failed to fetch deployments for blueprint", "blueprintId", id, "error", err)
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrBlueprintNotFound))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(deployments))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, deployments))
 }
 
 func (c *BlueprintController) Delete(ctx *gin.Context) {
 	idStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
-	if err := c.Service.Delete(ctx.Request.Context(), id); err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	c.auditBefore(ctx, id, orgId)
+	if err := c.Service.Delete(ctx.Request.Context(), id, orgId, versions); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrBlueprintNotFound))
 		return
 	}
 
@@ -210,5 +277,13 @@ func (c *BlueprintController) Delete(ctx *gin.Context) {
 		ID:        &id,
 		IsDeleted: true,
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
+}
+
+// auditBefore records the blueprint as it was before the current request changes it
+func (c *BlueprintController) auditBefore(ctx *gin.Context, id int, orgId int) {
+	bp, err := c.Service.GetByID(ctx.Request.Context(), id, orgId)
+	if err == nil && bp != nil {
+		middleware.AuditBefore(ctx, bp)
+	}
 }
@@ -1,9 +1,10 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -28,8 +29,8 @@ func (c *ComponentController) GetComponentByIds(ctx *gin.Context) {
 	for _, idStr := range idsStrArr {
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			slog.Debug("ComponentId not correct", "ERR", err)
-			ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+			logger.FromContext(ctx).Debug("ComponentId not correct", "ERR", err)
+			ctx.Error(apperrors.InvalidParameter(err.Error()))
 			return
 		}
 		ids = append(ids, id)
@@ -37,19 +38,17 @@ func (c *ComponentController) GetComponentByIds(ctx *gin.Context) {
 
 	comps, err := c.Service.GetComponentByIds(ctx.Request.Context(), ids)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(comps))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, comps))
 }
 
 func (c *ComponentController) GetAllComponents(ctx *gin.Context) {
 	comps, err := c.Service.GetAllComponents(ctx.Request.Context())
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(comps))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, comps))
 }
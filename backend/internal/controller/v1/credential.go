@@ -1,10 +1,15 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/middleware"
 	"clouding/backend/internal/model/credential"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
+	"clouding/backend/internal/validation"
 	"net/http"
 	"strconv"
 
@@ -19,45 +24,75 @@ func NewCredentialController(s service.CredentialService) *CredentialController
 	return &CredentialController{Service: s}
 }
 
-func (c *CredentialController) GetAllByUserId(ctx *gin.Context) {
-	userId := ctx.GetString("userId")
-	creds, err := c.Service.GetAllByUserId(ctx.Request.Context(), userId)
+// GetAllByOrgId lists the organization's credentials a page at a time.
+// Supports type, namePrefix, sort (id, name, createdAt, updatedAt), limit and cursor.
+func (c *CredentialController) GetAllByOrgId(ctx *gin.Context) {
+	page, err := pagination.FromQuery(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	filter := credential.Filter{
+		OrgID:      ctx.GetInt("orgId"),
+		Type:       ctx.Query("type"),
+		NamePrefix: ctx.Query("namePrefix"),
+	}
+
+	withSecrets := middleware.HasPermission(ctx, organization.PermCredentialsSecret)
+	if withSecrets {
+		middleware.AuditAction(ctx, "credentials.secrets.read")
+	}
+	creds, meta, err := c.Service.GetAllByOrgId(ctx.Request.Context(), &filter, page, withSecrets)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(creds))
+	ctx.JSON(http.StatusOK, utils.NewListResponse(ctx, creds, meta))
 }
 
 func (c *CredentialController) GetById(ctx *gin.Context) {
 	idStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug("ID not correct", "ERR", err)
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		logger.FromContext(ctx).Debug("ID not correct", "ERR", err)
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
-	cred, err := c.Service.GetById(ctx.Request.Context(), id)
+	withSecret := middleware.HasPermission(ctx, organization.PermCredentialsSecret)
+	if withSecret {
+		middleware.AuditAction(ctx, "credentials.secrets.read")
+	}
+	cred, err := c.Service.GetById(ctx.Request.Context(), id, orgId, withSecret)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
+		return
+	}
+	if cred == nil {
+		ctx.Error(apperrors.ErrCredentialNotFound)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(cred))
+	utils.SetETag(ctx, cred.UpdatedAt)
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, cred))
 }
 
 func (c *CredentialController) Create(ctx *gin.Context) {
 	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
 	req := credential.Credential{}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.Check(&req); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	req.UserID = &userId
+	req.OrgID = &orgId
 	if err := c.Service.Create(ctx.Request.Context(), &req); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
@@ -65,52 +100,78 @@ func (c *CredentialController) Create(ctx *gin.Context) {
 		ID: req.ID,
 	}
 
-	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, resp))
 }
 
 func (c *CredentialController) Update(ctx *gin.Context) {
 	idStr := ctx.Param("id")
-	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	var cred credential.Credential
 	if err := ctx.ShouldBindJSON(&cred); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.CheckPartial(&cred); err != nil {
+		ctx.Error(err)
 		return
 	}
 	cred.ID = &id
-	cred.UserID = &userId
-	if err := c.Service.Update(ctx.Request.Context(), &cred); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	cred.OrgID = &orgId
+	c.auditBefore(ctx, id, orgId)
+	if err := c.Service.Update(ctx.Request.Context(), &cred, versions); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrCredentialNotFound))
 		return
 	}
+	utils.SetETag(ctx, cred.UpdatedAt)
 
 	resp := &credential.UpdateCredentialResponse{
 		ID:        &id,
 		UpdatedAt: cred.UpdatedAt,
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
 }
 
 func (c *CredentialController) Delete(ctx *gin.Context) {
 	idStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
-	if err := c.Service.Delete(ctx.Request.Context(), id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	c.auditBefore(ctx, id, orgId)
+	if err := c.Service.Delete(ctx.Request.Context(), id, orgId, versions); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrCredentialNotFound))
 		return
 	}
 	resp := &credential.DeleteCredentialResponse{
 		ID:        &id,
 		IsDeleted: true,
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
+}
+
+// auditBefore records the credential, without its secret, as it was before the current request changes it
+func (c *CredentialController) auditBefore(ctx *gin.Context, id int, orgId int) {
+	cred, err := c.Service.GetById(ctx.Request.Context(), id, orgId, false)
+	if err == nil && cred != nil {
+		middleware.AuditBefore(ctx, cred)
+	}
 }
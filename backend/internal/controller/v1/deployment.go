@@ -1,116 +1,200 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/metrics"
 	"clouding/backend/internal/model/deployment"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
 	"clouding/backend/internal/utils/logStreamer"
-	"log/slog"
+	"clouding/backend/internal/validation"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// shutdownRetry is the reconnect delay in milliseconds sent with the shutdown event
+const shutdownRetry = 1000
+
 type DeploymentController struct {
 	Service     service.DeploymentService
 	LogStreamer logStreamer.LogStreamer
+	Lifecycle   *lifecycle.Lifecycle
 }
 
-func NewDeploymentController(s service.DeploymentService, ls logStreamer.LogStreamer) *DeploymentController {
-	return &DeploymentController{Service: s, LogStreamer: ls}
+func NewDeploymentController(s service.DeploymentService, ls logStreamer.LogStreamer, lc *lifecycle.Lifecycle) *DeploymentController {
+	return &DeploymentController{Service: s, LogStreamer: ls, Lifecycle: lc}
 }
 
+// Create queues a deployment and answers with it, its id is generated by the server and an
+// id in the body is ignored
 func (c *DeploymentController) Create(ctx *gin.Context) {
 	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
 	deploymentType := ctx.Param("type")
 
 	var req deployment.Deployment
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
-
+	// The type comes from the path, it is validated along with the body
 	req.Type = deployment.DeploymentType(deploymentType)
+	if err := validation.Check(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
 	req.UserID = &userId
+	req.OrgID = &orgId
 
 	if err := c.Service.Create(ctx.Request.Context(), &req); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(nil))
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, req))
 }
 
+// UpdateStatus is called by deployment workers on the internal route group
 func (c *DeploymentController) UpdateStatus(ctx *gin.Context) {
-	id := ctx.Param("id")
+	id, ok := deploymentID(ctx, "id")
+	if !ok {
+		return
+	}
 	var body deployment.UpdateDeploymentStatusPayload
 	if err := ctx.ShouldBindJSON(&body); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if !body.Status.IsValid() {
+		ctx.Error(apperrors.InvalidParameter("status must be one of pending, started, completed, failed"))
 		return
 	}
 
 	if err := c.Service.UpdateStatus(ctx.Request.Context(), id, &body); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrDeploymentNotFound))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(body))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, body))
 }
 
 func (c *DeploymentController) GetByID(ctx *gin.Context) {
-	id := ctx.Param("id")
+	id, ok := deploymentID(ctx, "id")
+	if !ok {
+		return
+	}
+	orgId := ctx.GetInt("orgId")
 
-	result, err := c.Service.GetByID(ctx.Request.Context(), id)
+	result, err := c.Service.GetByID(ctx.Request.Context(), id, orgId)
 	if err != nil {
-		slog.Error("Error fetching deployment", "err", err)
-		ctx.JSON(http.StatusNotFound, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
+		return
+	}
+	if result == nil {
+		ctx.Error(apperrors.ErrDeploymentNotFound)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(result))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, result))
 }
 
-func (c *DeploymentController) GetByUserAndType(ctx *gin.Context) {
-	userId := ctx.GetString("userId")
+// GetByOrgAndType lists the organization's deployments of a type a page at a time, newest first.
+// Supports status, blueprintId, from, to (RFC 3339), sort (createdAt, updatedAt), limit and cursor.
+func (c *DeploymentController) GetByOrgAndType(ctx *gin.Context) {
 	dType := ctx.Param("type")
 
 	if dType == "" {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("Type required"))
+		ctx.Error(apperrors.InvalidParameter("Type required"))
+		return
+	}
+
+	page, err := pagination.FromQuery(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	filter := deployment.Filter{
+		OrgID:  ctx.GetInt("orgId"),
+		Type:   deployment.DeploymentType(dType),
+		Status: deployment.DeploymentStatus(ctx.Query("status")),
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		ctx.Error(apperrors.InvalidParameter("status must be one of pending, started, completed, failed"))
 		return
 	}
+	if value := ctx.Query("blueprintId"); value != "" {
+		blueprintId, err := strconv.Atoi(value)
+		if err != nil {
+			ctx.Error(apperrors.InvalidParameter("blueprintId must be a number"))
+			return
+		}
+		filter.BlueprintID = &blueprintId
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := ctx.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ctx.Error(apperrors.InvalidParameter(name + " must be an RFC 3339 timestamp"))
+				return
+			}
+			*target = &t
+		}
+	}
 
-	results, err := c.Service.GetByUserAndType(ctx.Request.Context(), userId, dType)
+	results, meta, err := c.Service.GetByOrgAndType(ctx.Request.Context(), &filter, page)
 	if err != nil {
-		slog.Error("Error fetching deployments", "err", err)
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(results))
+	ctx.JSON(http.StatusOK, utils.NewListResponse(ctx, results, meta))
 }
 
 func (c *DeploymentController) GetDeploymentHostMappingByIds(ctx *gin.Context) {
-	idsStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	// @ TODO fetch unique values here
-	idsStrArr := strings.Split(idsStr, ",")
-	result, err := c.Service.GetDeploymentHostMappingByIds(ctx.Request.Context(), idsStrArr)
+	var ids []string
+	for _, value := range strings.Split(ctx.Param("id"), ",") {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			ctx.Error(apperrors.InvalidParameter("id must be a comma separated list of deployment ids"))
+			return
+		}
+		ids = append(ids, id.String())
+	}
+	result, err := c.Service.GetDeploymentHostMappingByIds(ctx.Request.Context(), ids, orgId)
 	if err != nil {
-		slog.Error("Error fetching deployments hosts mapping", "err", err)
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(result))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, result))
 }
 
 func (c *DeploymentController) StreamJobProgress(ctx *gin.Context) {
-	jobId := ctx.Param("jobId")
-	job, err := c.Service.GetByID(ctx.Request.Context(), jobId)
-	if err != nil || job == nil {
-		slog.Error("Error fetching job", "err", err)
-		ctx.JSON(http.StatusNotFound, utils.NewInternalErrorResponse(err.Error()))
+	jobId, ok := deploymentID(ctx, "jobId")
+	if !ok {
+		return
+	}
+	orgId := ctx.GetInt("orgId")
+	job, err := c.Service.GetByID(ctx.Request.Context(), jobId, orgId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if job == nil {
+		ctx.Error(apperrors.ErrDeploymentNotFound)
 		return
 	}
 
@@ -122,19 +206,35 @@ func (c *DeploymentController) StreamJobProgress(ctx *gin.Context) {
 
 	flusher, ok := ctx.Writer.(http.Flusher)
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse("streaming unsupported"))
+		ctx.Error(errors.New("streaming unsupported"))
+		return
+	}
+
+	streamDone, ok := c.Lifecycle.StartStream()
+	if !ok {
+		ctx.Error(apperrors.ErrShuttingDown)
 		return
 	}
+	defer streamDone()
 
-	reqCtx := ctx.Request.Context()
+	reqCtx, cancel := context.WithCancel(ctx.Request.Context())
 	logChan, err := c.LogStreamer.StreamLogs(reqCtx, jobId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		cancel()
+		ctx.Error(err)
 		return
 	}
+	// Stop the Loki poller and wait for it before the stream counts as ended
+	defer func() {
+		cancel()
+		for range logChan {
+		}
+	}()
 
 	ctx.Status(http.StatusOK)
 	flusher.Flush()
+	metrics.SSEStreams.Inc()
+	defer metrics.SSEStreams.Dec()
 
 	// Heartbeat to keep connection alive
 	heartbeat := time.NewTicker(15 * time.Second)
@@ -144,22 +244,42 @@ func (c *DeploymentController) StreamJobProgress(ctx *gin.Context) {
 		select {
 		case <-reqCtx.Done():
 			return
+		case <-c.Lifecycle.Draining():
+			// Browsers' EventSource reconnects on its own after retry, to another instance
+			ctx.Render(-1, sse.Event{
+				Event: "shutdown",
+				Retry: shutdownRetry,
+				Data:  utils.NewSuccessResponse(ctx, "server is shutting down, reconnect"),
+			})
+			flusher.Flush()
+			return
 		case log, ok := <-logChan:
 			if !ok {
-				ctx.SSEvent("end", utils.NewSuccessResponse("complete"))
+				ctx.SSEvent("end", utils.NewSuccessResponse(ctx, "complete"))
 				flusher.Flush()
 				return
 			}
 			if log.Error != "" {
-				ctx.SSEvent("error", utils.NewInternalErrorResponse(log.Error))
+				ctx.SSEvent("error", utils.NewErrorResponse(ctx, apperrors.CodeLokiQuery, log.Error))
 				flusher.Flush()
 				return
 			}
-			ctx.SSEvent("logs", utils.NewSuccessResponse(log))
+			ctx.SSEvent("logs", utils.NewSuccessResponse(ctx, log))
 			flusher.Flush()
 		case <-heartbeat.C:
-			ctx.SSEvent("heartbeat", utils.NewSuccessResponse("heartbeat"))
+			ctx.SSEvent("heartbeat", utils.NewSuccessResponse(ctx, "heartbeat"))
 			flusher.Flush()
 		}
 	}
 }
+
+// deploymentID reads a deployment id from the path. Deployment ids are UUIDs, anything
+// else can't name one and is not found rather than passed on to a uuid column.
+func deploymentID(ctx *gin.Context, param string) (string, bool) {
+	id, err := uuid.Parse(ctx.Param(param))
+	if err != nil {
+		ctx.Error(apperrors.ErrDeploymentNotFound)
+		return "", false
+	}
+	return id.String(), true
+}
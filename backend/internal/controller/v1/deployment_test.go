@@ -0,0 +1,39 @@
+package v1
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeploymentID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		value  string
+		wantID string
+	}{
+		{"uuid", "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11", "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11"},
+		{"upper case", "6F1C1F54-8A7E-4A35-9A57-3B7A2A6E0C11", "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11"},
+		{"not a uuid", "abc", ""},
+		{"number", "42", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Params = gin.Params{{Key: "id", Value: tt.value}}
+
+			id, ok := deploymentID(ctx, "id")
+			if ok != (tt.wantID != "") || id != tt.wantID {
+				t.Fatalf("deploymentID() = %q, %v, want %q", id, ok, tt.wantID)
+			}
+			if !ok && !errors.Is(ctx.Errors.Last(), apperrors.ErrDeploymentNotFound) {
+				t.Fatalf("error = %v, want %v", ctx.Errors.Last(), apperrors.ErrDeploymentNotFound)
+			}
+		})
+	}
+}
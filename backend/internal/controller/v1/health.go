@@ -0,0 +1,42 @@
+package v1
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/health"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	Checker *health.Checker
+}
+
+func NewHealthController(checker *health.Checker) *HealthController {
+	return &HealthController{Checker: checker}
+}
+
+// Liveness only tells that the process is serving requests
+func (h *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, gin.H{"status": health.StatusUp}))
+}
+
+// Readiness checks every dependency and answers 503 if any of them is down
+func (h *HealthController) Readiness(c *gin.Context) {
+	report := h.Checker.Run(c.Request.Context())
+	if report.Status != health.StatusUp {
+		for _, dep := range report.Dependencies {
+			if dep.Status != health.StatusUp {
+				logger.FromContext(c).Warn("Readiness check failed", "Dependency", dep.Name, "ERR", dep.Error)
+			}
+		}
+		// The report is still returned so the failing dependency is visible to whoever probes
+		resp := utils.NewErrorResponse(c, apperrors.CodeUnavailable, "one or more dependencies are unavailable")
+		resp["data"] = report
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, report))
+}
@@ -1,10 +1,14 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/middleware"
 	"clouding/backend/internal/model/host"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
+	"clouding/backend/internal/validation"
 	"net/http"
 	"strconv"
 	"strings"
@@ -21,58 +25,89 @@ func NewHostController(s service.HostService) *HostController {
 }
 
 func (c *HostController) GetHost(ctx *gin.Context) {
+	orgId := ctx.GetInt("orgId")
 
 	idsStr := ctx.Param("id")
 	idsStrArr := strings.Split(idsStr, ",")
 	var ids []int
-	// @ TODO fetch unique values here
+	uniqueIDs := make(map[int]struct{})
+
 	for _, idStr := range idsStrArr {
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			slog.Debug("Host ID not correct", "ERR", err)
-			ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+			logger.FromContext(ctx).Debug("Host ID not correct", "ERR", err)
+			ctx.Error(apperrors.InvalidParameter(err.Error()))
 			return
 		}
-		ids = append(ids, id)
+		if _, exists := uniqueIDs[id]; !exists {
+			uniqueIDs[id] = struct{}{}
+			ids = append(ids, id)
+		}
 	}
 
-	host, err := c.Service.GetHosts(ctx.Request.Context(), ids)
+	host, err := c.Service.GetHosts(ctx.Request.Context(), ids, orgId)
 
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrHostNotFound))
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(host))
+	// Only a single host has a version that If-Match can refer to
+	if len(ids) == 1 && len(host) == 1 {
+		utils.SetETag(ctx, host[0].UpdatedAt)
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, host))
 }
 
+// GetAllHosts lists the organization's hosts a page at a time.
+// Supports os, namePrefix, credentialId, sort (id, name, createdAt, updatedAt), limit and cursor.
 func (c *HostController) GetAllHosts(ctx *gin.Context) {
-	userId := ctx.GetString("userId")
-
-	hosts, err := c.Service.GetAllHostsByUserId(ctx.Request.Context(), userId)
+	page, err := pagination.FromQuery(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	filter := host.Filter{
+		OrgID:      ctx.GetInt("orgId"),
+		Os:         ctx.Query("os"),
+		NamePrefix: ctx.Query("namePrefix"),
+	}
+	if value := ctx.Query("credentialId"); value != "" {
+		credentialId, err := strconv.Atoi(value)
+		if err != nil {
+			ctx.Error(apperrors.InvalidParameter("credentialId must be a number"))
+			return
+		}
+		filter.CredentialID = &credentialId
+	}
 
+	hosts, meta, err := c.Service.GetAllHostsByOrgId(ctx.Request.Context(), &filter, page)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(hosts))
+	ctx.JSON(http.StatusOK, utils.NewListResponse(ctx, hosts, meta))
 }
 
 func (c *HostController) CreateHost(ctx *gin.Context) {
 	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
 
 	var hostObj host.Host
 	if err := ctx.ShouldBindJSON(&hostObj); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.Check(&hostObj); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	hostObj.UserID = &userId
+	hostObj.OrgID = &orgId
 
 	if err := c.Service.CreateHost(ctx.Request.Context(), &hostObj); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
@@ -80,49 +115,67 @@ func (c *HostController) CreateHost(ctx *gin.Context) {
 		ID: hostObj.ID,
 	}
 
-	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, resp))
 }
 
 func (c *HostController) UpdateHost(ctx *gin.Context) {
 	idStr := ctx.Param("id")
-	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	var hostObj host.Host
 	if err := ctx.ShouldBindJSON(&hostObj); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.CheckPartial(&hostObj); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	hostObj.ID = &id
-	hostObj.UserID = &userId
+	hostObj.OrgID = &orgId
 
-	if err := c.Service.UpdateHost(ctx.Request.Context(), &hostObj); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	c.auditBefore(ctx, id, orgId)
+	if err := c.Service.UpdateHost(ctx.Request.Context(), &hostObj, versions); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrHostNotFound))
 		return
 	}
+	utils.SetETag(ctx, hostObj.UpdatedAt)
 
 	resp := &host.UpdateHostResponse{
 		ID:        hostObj.ID,
 		UpdatedAt: hostObj.UpdatedAt,
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
 }
 
 func (c *HostController) DeleteHost(ctx *gin.Context) {
 	idStr := ctx.Param("id")
+	orgId := ctx.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	versions, err := utils.IfMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	if err := c.Service.DeleteHost(ctx.Request.Context(), id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	c.auditBefore(ctx, id, orgId)
+	if err := c.Service.DeleteHost(ctx.Request.Context(), id, orgId, versions); err != nil {
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrHostNotFound))
 		return
 	}
 
@@ -130,10 +183,11 @@ func (c *HostController) DeleteHost(ctx *gin.Context) {
 		ID:        &id,
 		IsDeleted: true,
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
 }
 
 func (c *HostController) GetHostsHealth(ctx *gin.Context) {
+	orgId := ctx.GetInt("orgId")
 	idsStr := ctx.Param("id")
 	idsStrArr := strings.Split(idsStr, ",")
 	var ids []int
@@ -142,8 +196,8 @@ func (c *HostController) GetHostsHealth(ctx *gin.Context) {
 	for _, idStr := range idsStrArr {
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			slog.Debug("Host ID not correct", "ERR", err)
-			ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+			logger.FromContext(ctx).Debug("Host ID not correct", "ERR", err)
+			ctx.Error(apperrors.InvalidParameter(err.Error()))
 			return
 		}
 		if _, exists := uniqueIDs[id]; !exists {
@@ -152,12 +206,19 @@ func (c *HostController) GetHostsHealth(ctx *gin.Context) {
 		}
 	}
 
-	healthData, err := c.Service.GetHostsHealth(ctx.Request.Context(), ids)
+	healthData, err := c.Service.GetHostsHealth(ctx.Request.Context(), ids, orgId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrHostNotFound))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(healthData))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, healthData))
+}
+
+// auditBefore records the host as it was before the current request changes it
+func (c *HostController) auditBefore(ctx *gin.Context, id int, orgId int) {
+	hosts, err := c.Service.GetHosts(ctx.Request.Context(), []int{id}, orgId)
+	if err == nil && len(hosts) == 1 {
+		middleware.AuditBefore(ctx, hosts[0])
+	}
 }
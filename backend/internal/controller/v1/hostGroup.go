@@ -1,10 +1,14 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/middleware"
 	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
+	"clouding/backend/internal/validation"
 	"net/http"
 	"strconv"
 
@@ -19,45 +23,63 @@ func NewHostGroupController(s service.HostGroupService) *HostGroupController {
 	return &HostGroupController{Service: s}
 }
 
+// GetAllHostGroups lists the organization's host groups a page at a time.
+// Supports namePrefix, sort (id, name, createdAt, updatedAt), limit and cursor.
 func (h *HostGroupController) GetAllHostGroups(c *gin.Context) {
-	userId := c.GetString("userId")
-	groups, err := h.Service.GetAllHostGroups(c.Request.Context(), userId)
+	page, err := pagination.FromQuery(c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter := hostgroup.Filter{
+		OrgID:      c.GetInt("orgId"),
+		NamePrefix: c.Query("namePrefix"),
+	}
+
+	groups, meta, err := h.Service.GetAllHostGroups(c.Request.Context(), &filter, page)
 	if err != nil {
-		slog.Debug("Error while fetching all hosts for", "User", userId, "ERR", err)
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		logger.FromContext(c).Debug("Error while fetching all hosts for", "Org", filter.OrgID, "ERR", err)
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse(groups))
+	c.JSON(http.StatusOK, utils.NewListResponse(c, groups, meta))
 }
 
 func (h *HostGroupController) GetHostGroupByID(c *gin.Context) {
 	idStr := c.Param("id")
+	orgId := c.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("ID must be a number"))
+		c.Error(apperrors.InvalidParameter("ID must be a number"))
 		return
 	}
 
-	group, err := h.Service.GetHostGroupByID(c.Request.Context(), id)
+	group, err := h.Service.GetHostGroupByID(c.Request.Context(), id, orgId)
 	if err != nil {
-		slog.Debug("HostGroupId not correct", "ERR", err)
-		c.JSON(http.StatusNotFound, utils.NewApiErrorResponse("Host group not found"))
+		c.Error(apperrors.OrNotFound(err, apperrors.ErrHostGroupNotFound))
 		return
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse(group))
+	utils.SetETag(c, group.UpdatedAt)
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, group))
 }
 
 func (h *HostGroupController) CreateHostGroup(c *gin.Context) {
 	userId := c.GetString("userId")
+	orgId := c.GetInt("orgId")
 	var group hostgroup.HostGroup
 	if err := c.ShouldBindJSON(&group); err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		c.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.Check(&group); err != nil {
+		c.Error(err)
 		return
 	}
 
 	group.UserID = &userId
+	group.OrgID = &orgId
 	if err := h.Service.CreateHostGroup(c.Request.Context(), &group); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		c.Error(err)
 		return
 	}
 
@@ -65,86 +87,112 @@ func (h *HostGroupController) CreateHostGroup(c *gin.Context) {
 		ID:        group.ID,
 		CreatedAt: group.CreatedAt,
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, resp))
 }
 
 func (h *HostGroupController) UpdateHostGroup(c *gin.Context) {
 	idStr := c.Param("id")
+	orgId := c.GetInt("orgId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("Invalid ID"))
+		c.Error(apperrors.InvalidParameter("Invalid ID"))
+		return
+	}
+	versions, err := utils.IfMatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 	var group hostgroup.HostGroup
 	if err := c.ShouldBindJSON(&group); err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		c.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.CheckPartial(&group); err != nil {
+		c.Error(err)
 		return
 	}
 
 	group.ID = &id
-	if err := h.Service.UpdateHostGroup(c.Request.Context(), &group); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	group.OrgID = &orgId
+	h.auditBefore(c, id, orgId)
+	if err := h.Service.UpdateHostGroup(c.Request.Context(), &group, versions); err != nil {
+		c.Error(apperrors.OrNotFound(err, apperrors.ErrHostGroupNotFound))
 		return
 	}
+	utils.SetETag(c, group.UpdatedAt)
 
 	resp := hostgroup.HostGroupUpdateResponse{
 		UpdatedAt: group.UpdatedAt,
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, resp))
 }
 
 func (h *HostGroupController) AddHostsToGroup(c *gin.Context) {
 	groupIDStr := c.Param("id")
+	orgId := c.GetInt("orgId")
 	groupID, err := strconv.Atoi(groupIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("ID must be a number"))
+		c.Error(apperrors.InvalidParameter("ID must be a number"))
 		return
 	}
 
 	var body hostgroup.AddHostToHostgroupRequest
 	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		c.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
-	if err := h.Service.AddHostsToGroup(c.Request.Context(), groupID, body.HostIDs); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	if err := validation.Check(&body); err != nil {
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse("Hosts added successfully"))
+	if err := h.Service.AddHostsToGroup(c.Request.Context(), groupID, orgId, body.HostIDs); err != nil {
+		c.Error(apperrors.OrNotFound(err, apperrors.ErrHostGroupNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, "Hosts added successfully"))
 }
 
 func (h *HostGroupController) RemoveHostFromGroup(c *gin.Context) {
 	groupIDStr := c.Param("id")
+	orgId := c.GetInt("orgId")
 	hostIDStr := c.Param("hostId")
 
 	groupID, err := strconv.Atoi(groupIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("Invalid group ID"))
+		c.Error(apperrors.InvalidParameter("Invalid group ID"))
 		return
 	}
 
 	hostID, err := strconv.Atoi(hostIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("Invalid host ID"))
+		c.Error(apperrors.InvalidParameter("Invalid host ID"))
 		return
 	}
-	if err := h.Service.RemoveHostFromGroup(c.Request.Context(), groupID, hostID); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	if err := h.Service.RemoveHostFromGroup(c.Request.Context(), groupID, hostID, orgId); err != nil {
+		c.Error(apperrors.OrNotFound(err, apperrors.ErrHostGroupNotFound))
 		return
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse("Host removed successfully"))
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, "Host removed successfully"))
 }
 
 func (h *HostGroupController) DeleteHostGroup(c *gin.Context) {
 	groupIDStr := c.Param("id")
+	orgId := c.GetInt("orgId")
 	groupID, err := strconv.Atoi(groupIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.NewWrongParamResponse("Invalid group ID"))
+		c.Error(apperrors.InvalidParameter("Invalid group ID"))
+		return
+	}
+	versions, err := utils.IfMatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := h.Service.DeleteHostGroup(c.Request.Context(), groupID); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+	h.auditBefore(c, groupID, orgId)
+	if err := h.Service.DeleteHostGroup(c.Request.Context(), groupID, orgId, versions); err != nil {
+		c.Error(apperrors.OrNotFound(err, apperrors.ErrHostGroupNotFound))
 		return
 	}
 
@@ -152,5 +200,13 @@ func (h *HostGroupController) DeleteHostGroup(c *gin.Context) {
 		ID:        &groupID,
 		IsDeleted: true,
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, resp))
+}
+
+// auditBefore records the host group as it was before the current request changes it
+func (h *HostGroupController) auditBefore(c *gin.Context, id int, orgId int) {
+	group, err := h.Service.GetHostGroupByID(c.Request.Context(), id, orgId)
+	if err == nil && group != nil {
+		middleware.AuditBefore(c, group)
+	}
 }
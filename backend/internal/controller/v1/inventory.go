@@ -0,0 +1,63 @@
+package v1
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/inventory"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/utils"
+	"clouding/backend/internal/utils/inventoryFile"
+	"clouding/backend/internal/validation"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryController struct {
+	Service service.InventoryService
+}
+
+func NewInventoryController(s service.InventoryService) *InventoryController {
+	return &InventoryController{Service: s}
+}
+
+// Import creates hosts and host groups from an inventory file, or previews it on a dry run.
+// Hosts that fail are listed with their line, the others are imported regardless.
+func (c *InventoryController) Import(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	orgId := ctx.GetInt("orgId")
+
+	var req inventory.ImportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if err := validation.Check(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result, err := c.Service.Import(ctx.Request.Context(), orgId, userId, &req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, result))
+}
+
+// Export downloads the organization's hosts and host groups as an inventory file
+func (c *InventoryController) Export(ctx *gin.Context) {
+	format := inventory.Format(ctx.Query("format"))
+	if !slices.Contains(inventory.Formats, format) {
+		ctx.Error(apperrors.InvalidParameter("format must be one of csv, ansible_ini, ansible_yaml, ssh_config"))
+		return
+	}
+
+	file, err := c.Service.Export(ctx.Request.Context(), ctx.GetInt("orgId"), format)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Header("Content-Disposition", `attachment; filename="`+inventoryFile.FileName(format)+`"`)
+	ctx.Data(http.StatusOK, inventoryFile.ContentType(format), file)
+}
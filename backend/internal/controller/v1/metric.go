@@ -1,9 +1,9 @@
 package v1
 
 import (
+	"clouding/backend/internal/logger"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,12 +18,12 @@ func NewMetricController(s service.MetricService) *MetricController {
 }
 
 func (m *MetricController) GetOverview(c *gin.Context) {
-	userId := c.GetString("userId")
-	groups, err := m.Service.GetOverview(c.Request.Context(), userId)
+	orgId := c.GetInt("orgId")
+	groups, err := m.Service.GetOverview(c.Request.Context(), orgId)
 	if err != nil {
-		slog.Debug("Error while fetching overview", "User", userId, "ERR", err)
-		c.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		logger.FromContext(c).Debug("Error while fetching overview", "Org", orgId, "ERR", err)
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, utils.NewSuccessResponse(groups))
+	c.JSON(http.StatusOK, utils.NewSuccessResponse(c, groups))
 }
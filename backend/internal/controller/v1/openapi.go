@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"clouding/backend/internal/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OpenAPIController struct {
+	Spec *openapi.Spec
+}
+
+func NewOpenAPIController(spec *openapi.Spec) *OpenAPIController {
+	return &OpenAPIController{Spec: spec}
+}
+
+// GetDocument serves the OpenAPI document as is, tools expect it without the response envelope
+func (o *OpenAPIController) GetDocument(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", o.Spec.JSON())
+}
@@ -0,0 +1,131 @@
+package v1
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationController struct {
+	Service service.OrganizationService
+}
+
+func NewOrganizationController(s service.OrganizationService) *OrganizationController {
+	return &OrganizationController{Service: s}
+}
+
+func (c *OrganizationController) GetOrganizations(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	orgs, err := c.Service.GetOrganizations(ctx.Request.Context(), userId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, orgs))
+}
+
+func (c *OrganizationController) Create(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	var org organization.Organization
+	if err := ctx.ShouldBindJSON(&org); err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if org.Name == nil || *org.Name == "" {
+		ctx.Error(apperrors.InvalidParameter("name is required"))
+		return
+	}
+
+	org.CreatedBy = &userId
+	if err := c.Service.Create(ctx.Request.Context(), &org); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	resp := &organization.CreateOrganizationResponse{
+		ID: org.ID,
+	}
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, resp))
+}
+
+func (c *OrganizationController) GetMembers(ctx *gin.Context) {
+	userId := ctx.GetString("userId")
+	orgId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+
+	members, err := c.Service.GetMembers(ctx.Request.Context(), orgId, userId)
+	if err != nil {
+		writeOrganizationError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, members))
+}
+
+func (c *OrganizationController) SetMember(ctx *gin.Context) {
+	callerId := ctx.GetString("userId")
+	memberId := ctx.Param("userId")
+	orgId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+
+	var m organization.Membership
+	if err := ctx.ShouldBindJSON(&m); err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+	if m.Role == nil || !m.Role.IsValid() {
+		ctx.Error(apperrors.InvalidParameter("role must be one of owner, admin, operator, viewer"))
+		return
+	}
+
+	m.OrganizationID = &orgId
+	m.UserID = &memberId
+	if err := c.Service.SetMember(ctx.Request.Context(), callerId, &m); err != nil {
+		writeOrganizationError(ctx, err)
+		return
+	}
+
+	resp := &organization.UpdateMembershipResponse{
+		OrganizationID: m.OrganizationID,
+		UserID:         m.UserID,
+		Role:           m.Role,
+		UpdatedAt:      m.UpdatedAt,
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
+}
+
+func (c *OrganizationController) RemoveMember(ctx *gin.Context) {
+	callerId := ctx.GetString("userId")
+	memberId := ctx.Param("userId")
+	orgId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
+		return
+	}
+
+	if err := c.Service.RemoveMember(ctx.Request.Context(), orgId, callerId, memberId); err != nil {
+		writeOrganizationError(ctx, err)
+		return
+	}
+
+	resp := &organization.DeleteMembershipResponse{
+		OrganizationID: &orgId,
+		UserID:         &memberId,
+		IsDeleted:      true,
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
+}
+
+func writeOrganizationError(ctx *gin.Context, err error) {
+	ctx.Error(apperrors.OrNotFound(err, apperrors.ErrMemberNotFound))
+}
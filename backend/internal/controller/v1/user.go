@@ -1,10 +1,10 @@
 package v1
 
 import (
+	apperrors "clouding/backend/internal/errors"
 	"clouding/backend/internal/model/user"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils"
-	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -24,29 +24,32 @@ func (c *UserController) GetUser(ctx *gin.Context) {
 	userObj, err := c.Service.GetUser(ctx.Request.Context(), id)
 
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.JSON(http.StatusBadRequest, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(userObj))
+	if userObj == nil {
+		ctx.Error(apperrors.ErrUserNotFound)
+		return
+	}
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, userObj))
 }
 
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var userObj user.User
 	if err := ctx.ShouldBindJSON(&userObj); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
 
 	if err := c.Service.CreateUser(ctx.Request.Context(), &userObj); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
 	res := &user.CreateUserResponse{
 		ID: userObj.ID,
 	}
-	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(res))
+	ctx.JSON(http.StatusCreated, utils.NewSuccessResponse(ctx, res))
 }
 
 func (c *UserController) UpdateUser(ctx *gin.Context) {
@@ -54,13 +57,13 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 
 	var userObj user.User
 	if err := ctx.ShouldBindJSON(&userObj); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.NewWrongParamResponse(err.Error()))
+		ctx.Error(apperrors.InvalidParameter(err.Error()))
 		return
 	}
 	userObj.ID = &id
 
 	if err := c.Service.UpdateUser(ctx.Request.Context(), &userObj); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(err)
 		return
 	}
 
@@ -68,14 +71,14 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		ID:        userObj.ID,
 		UpdatedAt: userObj.UpdatedAt,
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
 }
 
 func (c *UserController) DeleteUser(ctx *gin.Context) {
 	id := ctx.GetString("userId")
 
 	if err := c.Service.DeleteUser(ctx.Request.Context(), id); err != nil {
-		ctx.JSON(http.StatusNotFound, utils.NewInternalErrorResponse(err.Error()))
+		ctx.Error(apperrors.OrNotFound(err, apperrors.ErrUserNotFound))
 		return
 	}
 
@@ -83,5 +86,5 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 		ID:        &id,
 		IsDeleted: true,
 	}
-	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(resp))
+	ctx.JSON(http.StatusOK, utils.NewSuccessResponse(ctx, resp))
 }
@@ -1,7 +1,13 @@
 package database
 
 import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/tracing"
+	"context"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -9,22 +15,92 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func NewSqlDatabase(host string, port string, user string, password string, dbName string) (db *sqlx.DB) {
-	connString := fmt.Sprintf("host=%s port=%s user=%s "+"password=%s dbname=%s sslmode=disable", host, port, user, password, dbName)
+// maxConnectBackoff caps the wait between startup connection attempts
+const maxConnectBackoff = 30 * time.Second
+
+// NewSqlDatabase opens the pool described by config.Config.Sql and waits until
+// Postgres accepts connections, retrying with exponential backoff
+func NewSqlDatabase(ctx context.Context) (*sqlx.DB, error) {
+	cfg := config.Config.Sql
 
 	// Parse pgx config
-	pgxConfig, err := pgx.ParseConfig(connString)
+	pgxConfig, err := pgx.ParseConfig(connString())
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("parsing database config: %w", err)
 	}
 	// Disable statement cache & prefer simple protocol
 	// @ TODO check the performance impact due to this
 	pgxConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	pgxConfig.Tracer = tracing.QueryTracer{}
+	if cfg.ConnectTimeout > 0 {
+		pgxConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	if cfg.StatementTimeout > 0 {
+		pgxConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	db := sqlx.NewDb(stdlib.OpenDB(*pgxConfig), "pgx")
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := waitForDatabase(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func waitForDatabase(ctx context.Context, db *sqlx.DB) error {
+	cfg := config.Config.Sql
+	backoff := cfg.ConnectBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("connecting to database after %d attempts: %w", attempt+1, err)
+		}
 
-	db = sqlx.NewDb(stdlib.OpenDB(*pgxConfig), "pgx")
-	db.SetMaxOpenConns(30)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(30 * time.Minute)
+		slog.Warn("Database not reachable, retrying", "Attempt", attempt+1, "In", backoff, "ERR", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// connString returns sql.dsn when set, otherwise builds a keyword/value string from the
+// individual settings
+func connString() string {
+	cfg := config.Config.Sql
+	if cfg.DSN != "" {
+		return cfg.DSN
+	}
+
+	params := []string{
+		"host=" + quote(cfg.Host),
+		"port=" + quote(cfg.Port),
+		"user=" + quote(cfg.User),
+		"password=" + quote(cfg.Password),
+		"dbname=" + quote(cfg.Db),
+		"sslmode=" + quote(cfg.SSLMode),
+	}
+	if cfg.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+quote(cfg.SSLRootCert))
+	}
+	if cfg.SSLCert != "" {
+		params = append(params, "sslcert="+quote(cfg.SSLCert), "sslkey="+quote(cfg.SSLKey))
+	}
+	return strings.Join(params, " ")
+}
 
-	return db
+// quote escapes a value for a keyword/value connection string
+func quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
 }
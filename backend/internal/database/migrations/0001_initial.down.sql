@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS deployment_host_mappings;
+DROP TABLE IF EXISTS deployments;
+DROP TYPE IF EXISTS deployment_status;
+DROP TYPE IF EXISTS deployment_type;
+DROP TABLE IF EXISTS blueprint_components;
+DROP TABLE IF EXISTS blueprints;
+DROP TYPE IF EXISTS blueprint_status;
+DROP TABLE IF EXISTS components;
+DROP TABLE IF EXISTS host_groups_to_host_mapping;
+DROP TABLE IF EXISTS host_groups;
+DROP TABLE IF EXISTS hosts;
+DROP TABLE IF EXISTS credentials;
+DROP TYPE IF EXISTS credential_type;
+DROP TABLE IF EXISTS users;
+-- auth.users is left alone, on Supabase it holds the real accounts
@@ -1,3 +1,12 @@
+-- Baseline schema. Every statement is idempotent so databases created by hand from the
+-- old init.sql can adopt migrations without changes.
+
+-- Users are managed by Supabase auth, this stub only lets the schema apply on plain Postgres
+CREATE SCHEMA IF NOT EXISTS auth;
+CREATE TABLE IF NOT EXISTS auth.users (
+    id UUID PRIMARY KEY
+);
+
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
@@ -7,12 +16,10 @@ CREATE TABLE IF NOT EXISTS users (
     UNIQUE(email)
 );
 
-CREATE TYPE credential_type AS ENUM (
-  'ssh_key',
-  'ssl_cert',
-  'password',
-  'api_key'
-);
+DO $$ BEGIN
+    CREATE TYPE credential_type AS ENUM ('ssh_key', 'ssl_cert', 'password', 'api_key');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
 
 CREATE TABLE IF NOT EXISTS credentials (
     id SERIAL PRIMARY KEY,
@@ -50,7 +57,7 @@ CREATE TABLE IF NOT EXISTS host_groups_to_host_mapping (
     host_group_id INT NOT NULL REFERENCES host_groups(id) ON DELETE CASCADE,
     host_id INT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
     created_at TIMESTAMPTZ DEFAULT NOW(),
-    updated_at TIMESTAMPTZ DEFAULT NOW()
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
     UNIQUE(host_id)
 );
 
@@ -66,8 +73,10 @@ CREATE TABLE IF NOT EXISTS components (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
--- define the ENUM type
-CREATE TYPE blueprint_status AS ENUM ('draft', 'deployed', 'archived');
+DO $$ BEGIN
+    CREATE TYPE blueprint_status AS ENUM ('draft', 'deployed', 'archived');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
 
 CREATE TABLE IF NOT EXISTS blueprints (
     id SERIAL PRIMARY KEY,
@@ -80,7 +89,6 @@ CREATE TABLE IF NOT EXISTS blueprints (
     UNIQUE(user_id, name)
 );
 
-
 CREATE TABLE IF NOT EXISTS blueprint_components (
     id SERIAL PRIMARY KEY,
     blueprint_id INT NOT NULL REFERENCES blueprints(id) ON DELETE CASCADE,
@@ -93,18 +101,24 @@ CREATE TABLE IF NOT EXISTS blueprint_components (
     UNIQUE(blueprint_id, position)
 );
 
-CREATE TYPE deployment_type AS ENUM ('plan', 'deploy');
+DO $$ BEGIN
+    CREATE TYPE deployment_type AS ENUM ('plan', 'deploy');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
 
-CREATE TYPE deployment_status AS ENUM ('pending', 'started', 'completed', 'failed');
+DO $$ BEGIN
+    CREATE TYPE deployment_status AS ENUM ('pending', 'started', 'completed', 'failed');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
 
 CREATE TABLE IF NOT EXISTS deployments (
-  id UUID PRIMARY KEY,
-  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
-  blueprint_id INT NOT NULL REFERENCES blueprints(id) ON DELETE CASCADE,
-  type deployment_type NOT NULL,
-  status deployment_status NOT NULL DEFAULT 'pending',
-  created_at TIMESTAMPTZ DEFAULT NOW(),
-  updated_at TIMESTAMPTZ DEFAULT NOW()
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    blueprint_id INT NOT NULL REFERENCES blueprints(id) ON DELETE CASCADE,
+    type deployment_type NOT NULL,
+    status deployment_status NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
 CREATE TABLE IF NOT EXISTS deployment_host_mappings (
@@ -114,4 +128,4 @@ CREATE TABLE IF NOT EXISTS deployment_host_mappings (
     UNIQUE(deployment_id, host_id, status),
     FOREIGN KEY (deployment_id) REFERENCES deployments(id) ON DELETE CASCADE,
     FOREIGN KEY (host_id) REFERENCES hosts(id) ON DELETE CASCADE
-)
\ No newline at end of file
+);
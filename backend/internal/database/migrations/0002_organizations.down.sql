@@ -0,0 +1,16 @@
+-- Moves back to a single-user schema, organization memberships are lost.
+
+ALTER TABLE credentials DROP CONSTRAINT IF EXISTS credentials_org_id_name_key;
+ALTER TABLE credentials ADD CONSTRAINT credentials_user_id_name_key UNIQUE (user_id, name);
+ALTER TABLE blueprints DROP CONSTRAINT IF EXISTS blueprints_org_id_name_key;
+ALTER TABLE blueprints ADD CONSTRAINT blueprints_user_id_name_key UNIQUE (user_id, name);
+
+ALTER TABLE credentials DROP COLUMN IF EXISTS org_id;
+ALTER TABLE hosts DROP COLUMN IF EXISTS org_id;
+ALTER TABLE host_groups DROP COLUMN IF EXISTS org_id;
+ALTER TABLE blueprints DROP COLUMN IF EXISTS org_id;
+ALTER TABLE deployments DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TYPE IF EXISTS organization_role;
@@ -0,0 +1,67 @@
+-- Moves an existing single-user schema to organizations.
+-- Every user that owns data gets a personal organization and all their rows are attached to it.
+
+DO $$ BEGIN
+    CREATE TYPE organization_role AS ENUM ('owner', 'admin', 'operator', 'viewer');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    personal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS organizations_personal_idx ON organizations (created_by) WHERE personal;
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    role organization_role NOT NULL DEFAULT 'viewer',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+INSERT INTO organizations (name, personal, created_by)
+SELECT 'Personal', TRUE, owners.user_id
+FROM (
+    SELECT user_id FROM credentials
+    UNION SELECT user_id FROM hosts
+    UNION SELECT user_id FROM host_groups
+    UNION SELECT user_id FROM blueprints
+    UNION SELECT user_id FROM deployments
+) AS owners
+ON CONFLICT DO NOTHING;
+
+INSERT INTO organization_members (organization_id, user_id, role)
+SELECT id, created_by, 'owner' FROM organizations WHERE personal
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE hosts ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE host_groups ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE blueprints ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id) ON DELETE CASCADE;
+
+UPDATE credentials t SET org_id = o.id FROM organizations o WHERE o.personal AND o.created_by = t.user_id AND t.org_id IS NULL;
+UPDATE hosts t SET org_id = o.id FROM organizations o WHERE o.personal AND o.created_by = t.user_id AND t.org_id IS NULL;
+UPDATE host_groups t SET org_id = o.id FROM organizations o WHERE o.personal AND o.created_by = t.user_id AND t.org_id IS NULL;
+UPDATE blueprints t SET org_id = o.id FROM organizations o WHERE o.personal AND o.created_by = t.user_id AND t.org_id IS NULL;
+UPDATE deployments t SET org_id = o.id FROM organizations o WHERE o.personal AND o.created_by = t.user_id AND t.org_id IS NULL;
+
+ALTER TABLE credentials ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE hosts ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE host_groups ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE blueprints ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE deployments ALTER COLUMN org_id SET NOT NULL;
+
+ALTER TABLE credentials DROP CONSTRAINT IF EXISTS credentials_user_id_name_key;
+ALTER TABLE credentials DROP CONSTRAINT IF EXISTS credentials_org_id_name_key;
+ALTER TABLE credentials ADD CONSTRAINT credentials_org_id_name_key UNIQUE (org_id, name);
+ALTER TABLE blueprints DROP CONSTRAINT IF EXISTS blueprints_user_id_name_key;
+ALTER TABLE blueprints DROP CONSTRAINT IF EXISTS blueprints_org_id_name_key;
+ALTER TABLE blueprints ADD CONSTRAINT blueprints_org_id_name_key UNIQUE (org_id, name);
@@ -0,0 +1,2 @@
+-- Nothing to revert, the columns can not be told apart from the ones Supabase owns
+SELECT 1;
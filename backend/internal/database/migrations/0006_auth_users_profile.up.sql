@@ -0,0 +1,14 @@
+-- The auth.users stub from 0001 gets the profile columns the user queries read.
+-- Supabase already has them and does not let us alter its table, so it is left untouched.
+
+DO $$ BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'auth' AND table_name = 'users' AND column_name = 'raw_user_meta_data'
+    ) THEN
+        ALTER TABLE auth.users
+            ADD COLUMN raw_user_meta_data JSONB,
+            ADD COLUMN created_at TIMESTAMPTZ DEFAULT NOW(),
+            ADD COLUMN updated_at TIMESTAMPTZ DEFAULT NOW();
+    END IF;
+END $$;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS deployments_org_type_created_idx;
+DROP INDEX IF EXISTS blueprints_org_idx;
+DROP INDEX IF EXISTS host_groups_org_idx;
+DROP INDEX IF EXISTS hosts_org_name_idx;
+DROP INDEX IF EXISTS hosts_org_idx;
+DROP INDEX IF EXISTS credentials_org_idx;
+
+ALTER TABLE deployments ALTER COLUMN created_at DROP NOT NULL, ALTER COLUMN updated_at DROP NOT NULL;
+ALTER TABLE blueprints ALTER COLUMN created_at DROP NOT NULL, ALTER COLUMN updated_at DROP NOT NULL;
+ALTER TABLE host_groups ALTER COLUMN created_at DROP NOT NULL, ALTER COLUMN updated_at DROP NOT NULL;
+ALTER TABLE hosts ALTER COLUMN created_at DROP NOT NULL, ALTER COLUMN updated_at DROP NOT NULL;
+ALTER TABLE credentials ALTER COLUMN created_at DROP NOT NULL, ALTER COLUMN updated_at DROP NOT NULL;
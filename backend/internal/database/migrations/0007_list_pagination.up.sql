@@ -0,0 +1,27 @@
+-- Prepares list endpoints for keyset pagination.
+-- The sort columns must not be NULL for (column, id) comparisons to page correctly.
+
+UPDATE credentials SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE hosts SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE host_groups SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE blueprints SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE deployments SET created_at = NOW() WHERE created_at IS NULL;
+
+UPDATE credentials SET updated_at = created_at WHERE updated_at IS NULL;
+UPDATE hosts SET updated_at = created_at WHERE updated_at IS NULL;
+UPDATE host_groups SET updated_at = created_at WHERE updated_at IS NULL;
+UPDATE blueprints SET updated_at = created_at WHERE updated_at IS NULL;
+UPDATE deployments SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE credentials ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE hosts ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE host_groups ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE blueprints ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE deployments ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS credentials_org_idx ON credentials (org_id, id);
+CREATE INDEX IF NOT EXISTS hosts_org_idx ON hosts (org_id, id);
+CREATE INDEX IF NOT EXISTS hosts_org_name_idx ON hosts (org_id, name, id);
+CREATE INDEX IF NOT EXISTS host_groups_org_idx ON host_groups (org_id, id);
+CREATE INDEX IF NOT EXISTS blueprints_org_idx ON blueprints (org_id, id);
+CREATE INDEX IF NOT EXISTS deployments_org_type_created_idx ON deployments (org_id, type, created_at DESC, id DESC);
@@ -0,0 +1,15 @@
+-- Adds the store for Idempotency-Key requests. A row is claimed before the request runs and
+-- holds the response once it succeeded, so a retry within the retention window replays it.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    org_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INT,
+    response BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_idx ON idempotency_keys (created_at);
@@ -0,0 +1,4 @@
+-- Secrets already moved to their new name are not renamed back, run this only before
+-- `clouding secrets migrate`
+DROP INDEX IF EXISTS credentials_legacy_secret_name_idx;
+ALTER TABLE credentials DROP COLUMN IF EXISTS legacy_secret_name;
@@ -0,0 +1,10 @@
+-- Secrets were named <credential name>-<creator user id>, so a user's credentials of the same
+-- name in two organizations shared one secret. Secrets are now named by organization and
+-- credential ID, legacy_secret_name keeps the old name of existing credentials until
+-- `clouding secrets migrate`, or the next update of the credential, moves the secret.
+
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS legacy_secret_name TEXT;
+
+UPDATE credentials SET legacy_secret_name = name || '-' || user_id::text WHERE legacy_secret_name IS NULL;
+
+CREATE INDEX IF NOT EXISTS credentials_legacy_secret_name_idx ON credentials (legacy_secret_name) WHERE legacy_secret_name IS NOT NULL;
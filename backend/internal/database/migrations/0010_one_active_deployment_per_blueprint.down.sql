@@ -0,0 +1 @@
+DROP INDEX IF EXISTS deployments_one_active_per_blueprint;
@@ -0,0 +1,16 @@
+-- A blueprint may only have one pending or started deployment. The API used to check this
+-- before inserting, which two concurrent requests could both pass. Deployments that already
+-- overlap are settled first: all but the newest active one of a blueprint are marked failed.
+
+UPDATE deployments d
+SET status = 'failed', updated_at = NOW()
+WHERE d.status IN ('pending', 'started')
+  AND EXISTS (
+    SELECT 1 FROM deployments newer
+    WHERE newer.blueprint_id = d.blueprint_id
+      AND newer.status IN ('pending', 'started')
+      AND (newer.created_at, newer.id) > (d.created_at, d.id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS deployments_one_active_per_blueprint
+    ON deployments (blueprint_id) WHERE status IN ('pending', 'started');
@@ -0,0 +1,39 @@
+-- Rows whose creator was deleted can't be given one back, they are removed as the
+-- cascade would have done.
+
+DELETE FROM deployments WHERE user_id IS NULL;
+DELETE FROM blueprints WHERE user_id IS NULL;
+DELETE FROM host_groups WHERE user_id IS NULL;
+DELETE FROM hosts WHERE user_id IS NULL;
+DELETE FROM credentials WHERE user_id IS NULL;
+DELETE FROM organizations WHERE created_by IS NULL;
+
+ALTER TABLE deployments DROP CONSTRAINT IF EXISTS deployments_user_id_fkey;
+ALTER TABLE deployments ADD CONSTRAINT deployments_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE CASCADE;
+ALTER TABLE deployments ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE blueprints DROP CONSTRAINT IF EXISTS blueprints_user_id_fkey;
+ALTER TABLE blueprints ADD CONSTRAINT blueprints_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE CASCADE;
+ALTER TABLE blueprints ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE host_groups DROP CONSTRAINT IF EXISTS host_groups_user_id_fkey;
+ALTER TABLE host_groups ADD CONSTRAINT host_groups_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE CASCADE;
+ALTER TABLE host_groups ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE hosts DROP CONSTRAINT IF EXISTS hosts_user_id_fkey;
+ALTER TABLE hosts ADD CONSTRAINT hosts_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE CASCADE;
+ALTER TABLE hosts ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE credentials DROP CONSTRAINT IF EXISTS credentials_user_id_fkey;
+ALTER TABLE credentials ADD CONSTRAINT credentials_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE CASCADE;
+ALTER TABLE credentials ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_created_by_fkey;
+ALTER TABLE organizations ADD CONSTRAINT organizations_created_by_fkey
+    FOREIGN KEY (created_by) REFERENCES auth.users(id) ON DELETE CASCADE;
+ALTER TABLE organizations ALTER COLUMN created_by SET NOT NULL;
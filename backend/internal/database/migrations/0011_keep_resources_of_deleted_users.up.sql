@@ -0,0 +1,33 @@
+-- Resources belong to an organization, deleting the user who created one of them, or the
+-- organization, must not take the team's resources with them. The creator is forgotten
+-- instead. A personal organization is kept with its resources as well, it has no member left.
+
+ALTER TABLE organizations ALTER COLUMN created_by DROP NOT NULL;
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_created_by_fkey;
+ALTER TABLE organizations ADD CONSTRAINT organizations_created_by_fkey
+    FOREIGN KEY (created_by) REFERENCES auth.users(id) ON DELETE SET NULL;
+
+ALTER TABLE credentials ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE credentials DROP CONSTRAINT IF EXISTS credentials_user_id_fkey;
+ALTER TABLE credentials ADD CONSTRAINT credentials_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE SET NULL;
+
+ALTER TABLE hosts ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE hosts DROP CONSTRAINT IF EXISTS hosts_user_id_fkey;
+ALTER TABLE hosts ADD CONSTRAINT hosts_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE SET NULL;
+
+ALTER TABLE host_groups ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE host_groups DROP CONSTRAINT IF EXISTS host_groups_user_id_fkey;
+ALTER TABLE host_groups ADD CONSTRAINT host_groups_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE SET NULL;
+
+ALTER TABLE blueprints ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE blueprints DROP CONSTRAINT IF EXISTS blueprints_user_id_fkey;
+ALTER TABLE blueprints ADD CONSTRAINT blueprints_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE SET NULL;
+
+ALTER TABLE deployments ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE deployments DROP CONSTRAINT IF EXISTS deployments_user_id_fkey;
+ALTER TABLE deployments ADD CONSTRAINT deployments_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES auth.users(id) ON DELETE SET NULL;
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed seeds/components.sql
+var componentsSeed string
+
+// SeedComponents inserts the built-in components or refreshes them when they exist
+func SeedComponents(ctx context.Context, db *sqlx.DB) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, componentsSeed); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,3 +1,5 @@
+-- Built-in components. Re-running updates existing rows in place, matched by name.
+
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
 )
@@ -73,7 +75,14 @@ VALUES (
       "description": "List of site configuration files"
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -95,7 +104,14 @@ VALUES (
       "description": "Bash script file to run"
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -117,7 +133,14 @@ VALUES (
       "description": "Port value to open"
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -140,7 +163,14 @@ VALUES (
       "options": ["opensource"]
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -163,7 +193,14 @@ VALUES (
       "options": ["latest"]
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -186,7 +223,14 @@ VALUES (
       "options": ["8", "11", "17", "21"]
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -207,7 +251,14 @@ VALUES (
       "description": "Github URL"
     }
   ]'::jsonb
-);
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
 
 INSERT INTO components (
   name, display_name, description, label, ansible_role, parameters
@@ -236,4 +287,11 @@ VALUES (
       "description": "Site Config"
     }
   ]'::jsonb
-);
\ No newline at end of file
+)
+ON CONFLICT (name) DO UPDATE SET
+  display_name = EXCLUDED.display_name,
+  description = EXCLUDED.description,
+  label = EXCLUDED.label,
+  ansible_role = EXCLUDED.ansible_role,
+  parameters = EXCLUDED.parameters,
+  updated_at = NOW();
\ No newline at end of file
@@ -1,10 +1,164 @@
 package errors
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
 )
 
+// Kind classifies an Error, it decides the HTTP status the error is rendered with
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooManyRequests
+	KindUnavailable
+	KindPreconditionFailed
+	KindUnprocessable
+	KindTooLarge
+)
+
+// Status returns the HTTP status code for errors of kind k
+func (k Kind) Status() int {
+	switch k {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooManyRequests:
+		return http.StatusTooManyRequests
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is a domain error with a stable machine readable Code that clients can branch
+// on, Message is safe to show to the caller and Err is the cause, only logged. Details
+// list the invalid fields of a request that failed validation.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details []Detail
+	Err     error
+}
+
+// Detail is one invalid field, Field is its path in the request, e.g. hostIds[2]
+type Detail struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code, so a copy made by Wrap or Withf still matches
+// the declared error it came from
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// Withf returns a copy of e with a more specific message
+func (e *Error) Withf(format string, args ...any) *Error {
+	c := *e
+	c.Message = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// WithDetails returns a copy of e listing the invalid fields
+func (e *Error) WithDetails(details []Detail) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error   { return newError(KindValidation, code, message) }
+func Unauthorized(code, message string) *Error { return newError(KindUnauthorized, code, message) }
+func Forbidden(code, message string) *Error    { return newError(KindForbidden, code, message) }
+func NotFound(code, message string) *Error     { return newError(KindNotFound, code, message) }
+func Conflict(code, message string) *Error     { return newError(KindConflict, code, message) }
+func TooManyRequests(code, message string) *Error {
+	return newError(KindTooManyRequests, code, message)
+}
+func Unavailable(code, message string) *Error { return newError(KindUnavailable, code, message) }
+func PreconditionFailed(code, message string) *Error {
+	return newError(KindPreconditionFailed, code, message)
+}
+func Unprocessable(code, message string) *Error { return newError(KindUnprocessable, code, message) }
+func TooLarge(code, message string) *Error      { return newError(KindTooLarge, code, message) }
+
+// InvalidParameter reports a malformed path, query or body parameter
+func InvalidParameter(message string) *Error {
+	return Validation(CodeInvalidParameter, "Parameter(s) not correct. "+message)
+}
+
+// Codes that are not tied to a single declared error
+const (
+	CodeInternal         = "internal_error"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeNotFound         = "not_found"
+	CodeAlreadyExists    = "already_exists"
+	CodeInUse            = "in_use"
+	CodeInvalidReference = "invalid_reference"
+	CodeUnavailable      = "unavailable"
+	CodeLokiQuery        = "loki_query_failed"
+)
+
+// OrNotFound replaces sql.ErrNoRows, the repositories' not found result, with notFound
+func OrNotFound(err error, notFound *Error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound.Wrap(err)
+	}
+	return err
+}
+
+// From returns the Error in err's chain, or nil if it has none
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
 var ErrNoRows = errors.New("no rows in result set")
 
 func ErrLokiQuery(status int, body string) error {
@@ -13,5 +167,90 @@ func ErrLokiQuery(status int, body string) error {
 	if len(safe) > max {
 		safe = safe[:max] + "...(truncated)"
 	}
-	return fmt.Errorf("error fetching logs from loki: status=%d body=%s", status, safe)
+	return Unavailable(CodeLokiQuery, "job logs are currently unavailable").
+		Wrap(fmt.Errorf("error fetching logs from loki: status=%d body=%s", status, safe))
 }
+
+var ErrValidationFailed = Validation("validation_failed", "request is invalid, see details")
+
+// Authentication and request scoping
+
+var ErrMissingCredentials = Unauthorized("missing_credentials", "missing Authorization header")
+
+var ErrInvalidAuthorization = Unauthorized("invalid_authorization", "invalid Authorization format")
+
+var ErrInvalidToken = Unauthorized("invalid_token", "invalid or expired token")
+
+var ErrApiTokenNotAccepted = Forbidden("api_token_not_accepted", "this endpoint does not accept api tokens")
+
+var ErrOrganizationNotFound = NotFound("organization_not_found", "Organization not found")
+
+var ErrWrongOrganization = Forbidden("wrong_organization", "api token is not valid for this organization")
+
+var ErrRateLimited = TooManyRequests("rate_limited", "rate limit exceeded")
+
+var ErrUserTokenRejected = Unauthorized("user_token_rejected", "user tokens are not accepted on worker routes")
+
+var ErrUnknownWorker = Unauthorized("unknown_worker", "unknown worker")
+
+var ErrInvalidSignature = Unauthorized("invalid_signature", "invalid signature")
+
+var ErrSignatureExpired = Unauthorized("signature_expired", "signature expired")
+
+// Resources that do not exist or are not visible to the caller's organization
+
+var ErrUserNotFound = NotFound("user_not_found", "User not found")
+
+var ErrMemberNotFound = NotFound("member_not_found", "Organization or member not found")
+
+var ErrHostNotFound = NotFound("host_not_found", "Host not found")
+
+var ErrHostGroupNotFound = NotFound("host_group_not_found", "Host group not found")
+
+var ErrCredentialNotFound = NotFound("credential_not_found", "Credential not found")
+
+var ErrBlueprintNotFound = NotFound("blueprint_not_found", "Blueprint not found")
+
+var ErrDeploymentNotFound = NotFound("deployment_not_found", "Deployment not found")
+
+var ErrApiTokenNotFound = NotFound("api_token_not_found", "Api token not found")
+
+// Domain rules
+
+var ErrForbidden = Forbidden("forbidden", "insufficient permissions for this operation")
+
+var ErrLastOwner = Conflict("last_owner", "organization must keep at least one owner")
+
+var ErrInvalidApiToken = Unauthorized("invalid_api_token", "invalid, expired or revoked api token")
+
+var ErrInvalidScope = Validation("invalid_scope", "unknown token scope")
+
+var ErrInvalidStatusTransition = Conflict("invalid_status_transition", "deployment cannot move to the requested status")
+
+var ErrInvalidRole = Validation("invalid_role", "role must be one of owner, admin, operator, viewer")
+
+var ErrBlueprintBusy = Conflict("blueprint_busy", "blueprint already has a pending or running deployment")
+
+var ErrQueueUnavailable = Unavailable("queue_unavailable", "deployment queue is currently unavailable, retry later")
+
+var ErrComponentNotFound = Validation("component_not_found", "one or more components do not exist")
+
+var ErrInvalidIfMatch = Validation("invalid_if_match", "If-Match must be * or a comma separated list of entity tags")
+
+var ErrPreconditionFailed = PreconditionFailed("precondition_failed", "resource was modified since it was read, fetch it again")
+
+var ErrInvalidIdempotencyKey = Validation("invalid_idempotency_key", "Idempotency-Key must be 1 to 255 printable ASCII characters")
+
+var ErrIdempotencyKeyReused = Unprocessable("idempotency_key_reused", "Idempotency-Key was already used for a different request")
+
+var ErrIdempotencyKeyInProgress = Conflict("idempotency_key_in_progress", "a request with this Idempotency-Key is still in progress, retry later")
+
+var ErrInvalidInventory = Validation("invalid_inventory", "inventory could not be read")
+
+var ErrRequestTooLarge = TooLarge("request_too_large", "request body is too large")
+
+var ErrInventoryTooLarge = Validation("inventory_too_large", "inventory lists too many hosts")
+
+var ErrInvalidComponent = Validation("invalid_component", "blueprint component is invalid")
+
+var ErrShuttingDown = Unavailable("shutting_down", "server is shutting down")
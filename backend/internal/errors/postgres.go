@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// constraintErrors gives the constraints clients are likely to hit a specific code
+// and message, other violations get the generic already_exists, in_use or invalid_reference
+var constraintErrors = map[string]*Error{
+	"users_email_key":                                    Conflict("email_taken", "a user with this email already exists"),
+	"credentials_org_id_name_key":                        Conflict("credential_name_taken", "a credential with this name already exists"),
+	"blueprints_org_id_name_key":                         Conflict("blueprint_name_taken", "a blueprint with this name already exists"),
+	"host_groups_to_host_mapping_host_id_key":            Conflict("host_already_grouped", "a host can only belong to one host group"),
+	"blueprint_components_blueprint_id_component_id_key": Conflict("duplicate_component", "a component can only be used once per blueprint"),
+	"blueprint_components_blueprint_id_position_key":     Conflict("duplicate_position", "two components of the blueprint share a position"),
+	"api_tokens_token_hash_key":                          Conflict(CodeAlreadyExists, "api token already exists"),
+	"hosts_credential_id_fkey":                           Conflict("credential_in_use", "credential is still used by one or more hosts"),
+	"blueprint_components_component_id_fkey":             Conflict("component_in_use", "component is still used by one or more blueprints"),
+	"deployments_one_active_per_blueprint":               ErrBlueprintBusy,
+}
+
+// FromPostgres translates unique (23505) and foreign key (23503) violations into
+// Conflict and Validation errors, any other error is returned unchanged
+func FromPostgres(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		if known, ok := constraintErrors[pgErr.ConstraintName]; ok {
+			return known.Wrap(err)
+		}
+		return Conflict(CodeAlreadyExists, "resource already exists").Wrap(err)
+	case pgForeignKeyViolation:
+		// Deleting a row that is still referenced, as opposed to referencing a missing row
+		if strings.Contains(pgErr.Detail, "is still referenced") {
+			if known, ok := constraintErrors[pgErr.ConstraintName]; ok {
+				return known.Wrap(err)
+			}
+			return Conflict(CodeInUse, "resource is still referenced by "+pgErr.TableName).Wrap(err)
+		}
+		return Validation(CodeInvalidReference, "referenced resource does not exist").Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check returns nil when the dependency is usable
+type Check func(ctx context.Context) error
+
+type DependencyReport struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status       Status              `json:"status"`
+	Dependencies []*DependencyReport `json:"dependencies"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs every registered dependency check concurrently, each under its own timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) Register(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+func (c *Checker) Run(ctx context.Context) *Report {
+	report := &Report{
+		Status:       StatusUp,
+		Dependencies: make([]*DependencyReport, len(c.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Dependencies[i] = c.runCheck(ctx, nc)
+		}()
+	}
+	wg.Wait()
+
+	for _, dep := range report.Dependencies {
+		if dep.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (c *Checker) runCheck(ctx context.Context, nc namedCheck) *DependencyReport {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := nc.check(ctx)
+	dep := &DependencyReport{
+		Name:      nc.name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		dep.Status = StatusDown
+		dep.Error = err.Error()
+	}
+	return dep
+}
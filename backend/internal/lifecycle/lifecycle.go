@@ -0,0 +1,93 @@
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Lifecycle tracks the work that must finish before the server's dependencies are
+// closed: long-lived streams such as SSE connections and background goroutines.
+// Draining starts when the HTTP server begins shutting down, streams see Draining close,
+// tell their client to reconnect and return, background goroutines see their context cancelled.
+type Lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	draining   bool
+	streams    sync.WaitGroup
+	background sync.WaitGroup
+}
+
+func New() *Lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Lifecycle{ctx: ctx, cancel: cancel}
+}
+
+// Draining is closed once shutdown has begun
+func (l *Lifecycle) Draining() <-chan struct{} {
+	return l.ctx.Done()
+}
+
+// StartStream registers a long-lived stream, the returned func must be called when it ends.
+// It returns false once draining has started, the stream must not be opened then.
+func (l *Lifecycle) StartStream() (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		return nil, false
+	}
+	l.streams.Add(1)
+	return l.streams.Done, true
+}
+
+// Go runs fn in a goroutine that shutdown waits for, ctx is cancelled when draining starts.
+// Nothing is started once draining has begun.
+func (l *Lifecycle) Go(name string, fn func(ctx context.Context)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		slog.Debug("Not starting background work, shutting down", "Name", name)
+		return
+	}
+	l.background.Add(1)
+	go func() {
+		defer l.background.Done()
+		fn(l.ctx)
+	}()
+}
+
+// Drain signals streams and background work to stop, it is safe to call more than once
+func (l *Lifecycle) Drain() {
+	l.mu.Lock()
+	l.draining = true
+	l.mu.Unlock()
+	l.cancel()
+}
+
+// WaitStreams drains and waits until every stream has ended or ctx is done
+func (l *Lifecycle) WaitStreams(ctx context.Context) error {
+	l.Drain()
+	return wait(ctx, &l.streams)
+}
+
+// WaitBackground drains and waits until every background goroutine has returned or ctx is done
+func (l *Lifecycle) WaitBackground(ctx context.Context) error {
+	l.Drain()
+	return wait(ctx, &l.background)
+}
+
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"clouding/backend/internal/config"
+	"io"
 	"log/slog"
 	"os"
 	"time"
@@ -9,23 +10,42 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// LevelTrace is more verbose than debug, slog has no level of its own for it
+const LevelTrace = slog.Level(-8)
+
 func getSLogLevel() slog.Level {
 	switch config.Config.Server.LogLevel {
+	case "trace":
+		return LevelTrace
 	case "debug":
 		return slog.LevelDebug
 	case "info":
 		return slog.LevelInfo
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
 	default:
 		return slog.LevelInfo
 	}
 }
 
+// New creates the logger configured by server.logLevel and server.logFormat. The json
+// format writes one object per line for Loki, console is colored output for humans.
+// Sensitive attributes are redacted in both, see redactAttr.
 func New() *slog.Logger {
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	zerologLogger := zerolog.New(zerolog.ConsoleWriter{
-		Out:        os.Stderr,
-		NoColor:    false,
-		TimeFormat: time.RFC3339,
-	}).Level(toZerologLevel(getSLogLevel())).With().Timestamp().Logger()
+	var out io.Writer = os.Stderr
+	if config.Config.Server.LogFormat == "json" {
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+	} else {
+		zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+		out = zerolog.ConsoleWriter{
+			Out:        os.Stderr,
+			NoColor:    false,
+			TimeFormat: time.RFC3339,
+		}
+	}
+
+	zerologLogger := zerolog.New(out).Level(toZerologLevel(getSLogLevel())).With().Timestamp().Logger()
 	return slog.New(newZerologHandler(&zerologLogger))
 }
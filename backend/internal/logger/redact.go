@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"clouding/backend/internal/utils/redact"
+	"log/slog"
+	"net/http"
+)
+
+// redactAttr hides the value of sensitive attributes and the sensitive entries of headers
+// and maps, such as a credential's secret map, logged under any key
+func redactAttr(attr slog.Attr) slog.Attr {
+	if redact.IsSensitive(attr.Key) {
+		return slog.String(attr.Key, redact.Placeholder)
+	}
+	if attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+
+	switch v := attr.Value.Any().(type) {
+	case http.Header:
+		clean := make(http.Header, len(v))
+		for name, values := range v {
+			if redact.IsSensitive(name) {
+				values = []string{redact.Placeholder}
+			}
+			clean[name] = values
+		}
+		return slog.Any(attr.Key, clean)
+	case map[string]any:
+		return slog.Any(attr.Key, redact.Value(v))
+	case map[string]string:
+		clean := make(map[string]string, len(v))
+		for k, value := range v {
+			if redact.IsSensitive(k) {
+				value = redact.Placeholder
+			}
+			clean[k] = value
+		}
+		return slog.Any(attr.Key, clean)
+	}
+	return attr
+}
@@ -69,6 +69,7 @@ func addAttrToZerolog[T interface {
 	Interface(string, any) T
 	AnErr(key string, err error) T
 }](attr slog.Attr, target T) T {
+	attr = redactAttr(attr)
 	switch attr.Value.Kind() {
 	case slog.KindBool:
 		return target.Bool(attr.Key, attr.Value.Bool())
@@ -101,21 +102,24 @@ func addAttrToZerolog[T interface {
 		case error:
 			return target.AnErr(attr.Key, v)
 		default:
-			return target.Interface(attr.Key, attr.Value)
+			return target.Interface(attr.Key, v)
 		}
 	}
 }
 
-// toZerologLevel maps slog levels to zerolog levels
+// toZerologLevel maps slog levels to zerolog levels, levels in between round down,
+// e.g. slog.LevelWarn+2 is still a warning
 func toZerologLevel(level slog.Level) zerolog.Level {
 	switch {
-	case level == slog.LevelDebug:
+	case level <= LevelTrace:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
 		return zerolog.DebugLevel
-	case level == slog.LevelWarn:
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
 		return zerolog.WarnLevel
-	case level == slog.LevelError:
-		return zerolog.ErrorLevel
 	default:
-		return zerolog.InfoLevel
+		return zerolog.ErrorLevel
 	}
 }
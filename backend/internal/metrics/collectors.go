@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"clouding/backend/internal/model/deployment"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// scrapeTimeout bounds the queries run while collecting
+const scrapeTimeout = 5 * time.Second
+
+// RegisterDBStats exposes the connection pool statistics of db
+func RegisterDBStats(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// DeploymentCounter counts deployments per status, see DeploymentRepository.CountByStatus
+type DeploymentCounter func(ctx context.Context) (map[deployment.DeploymentStatus]int, error)
+
+type deploymentStatusCollector struct {
+	count DeploymentCounter
+	desc  *prometheus.Desc
+}
+
+// RegisterDeploymentStatus exposes the number of deployments per status, counted on every scrape
+func RegisterDeploymentStatus(count DeploymentCounter) error {
+	return Registry.Register(&deploymentStatusCollector{
+		count: count,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "deployments"),
+			"Deployments by status.",
+			[]string{"status"}, nil,
+		),
+	})
+}
+
+func (c *deploymentStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *deploymentStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		slog.Error("Failed to count deployments for metrics", "ERR", err)
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	// Every status is reported so that a status without deployments reads 0 rather than vanishing
+	for _, status := range deployment.Statuses {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
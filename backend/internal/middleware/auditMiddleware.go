@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/model/audit"
+	"clouding/backend/internal/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditContextKey = "auditRecord"
+	// maxAuditBodySize bounds how much of a request or response is kept for the diff
+	maxAuditBodySize = 64 << 10
+)
+
+var auditVerbs = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+type auditRecord struct {
+	action     string
+	resourceID string
+	before     any
+	after      any
+}
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < maxAuditBodySize {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// AuditMiddleware records every mutating request, and any request a controller marks
+// with AuditAction, once the handler has run. The action and resource are derived from
+// the route, e.g. PUT /api/v1/hostGroups/:id -> "hostGroups.update" on hostGroups/<id>.
+// The request body is the "after" side of the diff, controllers provide the "before"
+// side with AuditBefore.
+func AuditMiddleware(auditService service.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var writer *auditResponseWriter
+		if verb, ok := auditVerbs[c.Request.Method]; ok {
+			record := &auditRecord{action: routeAction(c.FullPath(), verb)}
+			if body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBodySize)); err == nil {
+				c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+				if json.Valid(body) {
+					record.after = json.RawMessage(body)
+				}
+			}
+			c.Set(auditContextKey, record)
+
+			writer = &auditResponseWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+		}
+
+		c.Next()
+
+		value, ok := c.Get(auditContextKey)
+		if !ok {
+			return
+		}
+		record := value.(*auditRecord)
+
+		if record.resourceID == "" {
+			record.resourceID = c.Param("id")
+		}
+		if record.resourceID == "" && writer != nil {
+			record.resourceID = createdResourceID(writer.body.Bytes())
+		}
+		if c.Request.Method == http.MethodDelete {
+			record.after = nil
+		}
+
+		entry := buildAuditEntry(c, record)
+		// The client may already be gone, the entry must still be written
+		if err := auditService.Record(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+			logger.FromContext(c).Error("Failed to write audit entry", "Action", record.action, "ERR", err)
+		}
+	}
+}
+
+// AuditAction records the current request under action even if it does not mutate anything,
+// used for sensitive reads such as credential secrets
+func AuditAction(c *gin.Context, action string) {
+	if value, ok := c.Get(auditContextKey); ok {
+		value.(*auditRecord).action = action
+		return
+	}
+	c.Set(auditContextKey, &auditRecord{action: action})
+}
+
+// AuditBefore stores the state of the resource before the current request changes it
+func AuditBefore(c *gin.Context, before any) {
+	if value, ok := c.Get(auditContextKey); ok {
+		value.(*auditRecord).before = before
+	}
+}
+
+func buildAuditEntry(c *gin.Context, record *auditRecord) *audit.Entry {
+	resourceType, _, _ := strings.Cut(record.action, ".")
+	statusCode := responseStatus(c)
+	sourceIP := c.ClientIP()
+	requestID := logger.RequestID(c)
+
+	entry := &audit.Entry{
+		ActorType:    audit.ActorUser,
+		Action:       &record.action,
+		ResourceType: &resourceType,
+		StatusCode:   &statusCode,
+		SourceIP:     &sourceIP,
+	}
+	if record.resourceID != "" {
+		entry.ResourceID = &record.resourceID
+	}
+	if requestID != "" {
+		entry.RequestID = &requestID
+	}
+
+	actorID := c.GetString("userId")
+	if tokenId, ok := c.Get("apiTokenId"); ok {
+		entry.ActorType = audit.ActorApiToken
+		actorID = fmt.Sprintf("%s:%d", actorID, tokenId)
+	}
+	if workerId := c.GetString("workerId"); workerId != "" {
+		entry.ActorType = audit.ActorWorker
+		actorID = workerId
+	}
+	entry.ActorID = &actorID
+
+	if orgId, ok := c.Get("orgId"); ok {
+		id := orgId.(int)
+		entry.OrgID = &id
+	} else if resourceType == "organizations" && record.resourceID != "" {
+		// Organization management routes take the organization from the path
+		if id, err := strconv.Atoi(record.resourceID); err == nil {
+			entry.OrgID = &id
+		}
+	}
+
+	changes, err := audit.Diff(record.before, record.after)
+	if err != nil {
+		logger.FromContext(c).Warn("Failed to diff audit entry", "Action", record.action, "ERR", err)
+	}
+	entry.Changes = changes
+
+	return entry
+}
+
+// routeAction turns a route into an action name from its static segments,
+// e.g. /api/v1/hostGroups/:id/hosts/:hostId + delete -> hostGroups.hosts.delete
+func routeAction(fullPath string, verb string) string {
+	var parts []string
+	for _, segment := range strings.Split(fullPath, "/") {
+		if segment == "" || segment == "api" || segment == "internal" || segment == "v1" ||
+			strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			continue
+		}
+		parts = append(parts, segment)
+	}
+	return strings.Join(append(parts, verb), ".")
+}
+
+// createdResourceID picks the id out of a {"data": {"id": ...}} response
+func createdResourceID(body []byte) string {
+	var resp struct {
+		Data struct {
+			ID any `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Data.ID == nil {
+		return ""
+	}
+	return fmt.Sprint(resp.Data.ID)
+}
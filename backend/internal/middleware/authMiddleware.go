@@ -2,41 +2,50 @@ package middleware
 
 import (
 	"clouding/backend/internal/config"
-	"clouding/backend/internal/utils"
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/apiToken"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/utils/jwks"
+	"context"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTAuthMiddleware() gin.HandlerFunc {
+// JWTAuthMiddleware accepts either a session JWT or a personal access token.
+// Session JWTs are HS256 tokens signed with the Supabase secret, or RS256/ES256
+// tokens verified against the configured JWKS.
+func JWTAuthMiddleware(apiTokenService service.ApiTokenService) gin.HandlerFunc {
+	parser, keyfunc := newJWTParser()
+
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewApiErrorResponse("missing Authorization header"))
+			abortWithError(c, apperrors.ErrMissingCredentials)
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewApiErrorResponse("invalid Authorization format"))
+			abortWithError(c, apperrors.ErrInvalidAuthorization)
 			return
 		}
 
 		tokenStr := parts[1]
 
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return config.Config.SupabaseAuth.JwtSecret, nil
-		})
+		if strings.HasPrefix(tokenStr, apiToken.TokenPrefix) {
+			authenticateApiToken(c, apiTokenService, tokenStr)
+			return
+		}
+
+		token, err := parser.Parse(tokenStr, keyfunc(c.Request.Context()))
 
 		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewApiErrorResponse(err.Error()))
+			abortWithError(c, apperrors.ErrInvalidToken.Wrap(err))
 			return
 		}
 
@@ -45,7 +54,7 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		if ok {
 			userId, err := claims.GetSubject()
 			if err != nil {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewApiErrorResponse(err.Error()))
+				abortWithError(c, apperrors.ErrInvalidToken.Wrap(err))
 				return
 			}
 			c.Set("userId", userId)
@@ -54,3 +63,80 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// newJWTParser returns the parser and, per request, the keyfunc resolving verification keys
+func newJWTParser() (*jwt.Parser, func(ctx context.Context) jwt.Keyfunc) {
+	authConfig := config.Config.Auth
+	secret := config.Config.SupabaseAuth.JwtSecret
+
+	var keySet *jwks.KeySet
+	var methods []string
+	if len(secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if authConfig.JwksURL != "" {
+		keySet = jwks.NewKeySet(authConfig.JwksURL, authConfig.JwksCacheTTL)
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if len(methods) == 0 {
+		slog.Warn("Neither SUPABASE.JWT.SECRET nor AUTH.JWKS.URL is set, only api tokens will be accepted")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(authConfig.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if authConfig.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(authConfig.Issuer))
+	}
+	if authConfig.Audience != "" {
+		opts = append(opts, jwt.WithAudience(authConfig.Audience))
+	}
+
+	keyfunc := func(ctx context.Context) jwt.Keyfunc {
+		return func(token *jwt.Token) (interface{}, error) {
+			switch token.Method.(type) {
+			case *jwt.SigningMethodHMAC:
+				if len(secret) > 0 {
+					return secret, nil
+				}
+			case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+				if keySet != nil {
+					return keySet.Keyfunc(ctx)(token)
+				}
+			}
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	}
+
+	return jwt.NewParser(opts...), keyfunc
+}
+
+func authenticateApiToken(c *gin.Context, apiTokenService service.ApiTokenService, tokenStr string) {
+	t, err := apiTokenService.Authenticate(c.Request.Context(), tokenStr)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.Set("userId", *t.UserID)
+	c.Set("apiTokenId", *t.ID)
+	c.Set("apiTokenOrgId", *t.OrgID)
+	c.Set("apiTokenScopes", []string(t.Scopes))
+	c.Next()
+}
+
+// RequireSessionAuth rejects requests authenticated with a personal access token,
+// so that a leaked token cannot mint or revoke other tokens, change the account or
+// manage organizations and their members
+func RequireSessionAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiTokenId"); ok {
+			abortWithError(c, apperrors.ErrApiTokenNotAccepted)
+			return
+		}
+		c.Next()
+	}
+}
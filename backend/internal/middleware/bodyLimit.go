@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"bytes"
+	apperrors "clouding/backend/internal/errors"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBufferedBodySize bounds the request bodies middlewares read into memory. Inventory
+// imports carry up to 1 MiB of file content, the rest leaves room for the JSON around it.
+const maxBufferedBodySize = 1<<20 + 64<<10
+
+// readBody reads the whole request body, at most maxBufferedBodySize, and puts it back for
+// the handler
+func readBody(c *gin.Context) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBufferedBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, apperrors.ErrRequestTooLarge.Withf("request body is larger than %d bytes", maxBufferedBodySize)
+		}
+		return nil, apperrors.InvalidParameter("could not read the request body").Wrap(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
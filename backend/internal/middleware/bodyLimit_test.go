@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		size     int
+		wantCode string
+	}{
+		{"empty", 0, ""},
+		{"at the limit", maxBufferedBodySize, ""},
+		{"over the limit", maxBufferedBodySize + 1, "request_too_large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", tt.size)))
+
+			body, err := readBody(c)
+			if tt.wantCode != "" {
+				appErr := apperrors.From(err)
+				if appErr == nil || appErr.Code != tt.wantCode || appErr.Kind.Status() != http.StatusRequestEntityTooLarge {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The handler reads the same body again
+			again, _ := io.ReadAll(c.Request.Body)
+			if len(body) != tt.size || string(again) != string(body) {
+				t.Fatalf("read %d bytes, then %d, want %d", len(body), len(again), tt.size)
+			}
+		})
+	}
+}
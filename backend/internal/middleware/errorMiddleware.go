@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/utils"
+	"database/sql"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errNotFound = apperrors.NotFound(apperrors.CodeNotFound, "Resource not found")
+	errInternal = &apperrors.Error{Kind: apperrors.KindInternal, Code: apperrors.CodeInternal, Message: "Internal Exception"}
+)
+
+// ErrorMiddleware renders the last error a handler or middleware added with c.Error,
+// unless a response was already written. Typed errors keep their status, code and message,
+// sql.ErrNoRows becomes a 404 and anything else a 500 whose details are only logged.
+// Validation errors list the invalid fields in details.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		appErr, message := resolveError(err)
+		status := appErr.Kind.Status()
+		if appErr.Kind == apperrors.KindInternal || appErr.Kind == apperrors.KindUnavailable {
+			logger.FromContext(c).Error("Request failed", "Status", status, "Code", appErr.Code, "ERR", err)
+		}
+		resp := utils.NewErrorResponse(c, appErr.Code, message)
+		if len(appErr.Details) > 0 {
+			resp["details"] = appErr.Details
+		}
+		c.AbortWithStatusJSON(status, resp)
+	}
+}
+
+// resolveError finds the typed error behind err and the message to show. Context added
+// around a typed error with fmt.Errorf("%w: ...") is kept in the message.
+func resolveError(err error) (*apperrors.Error, string) {
+	appErr := apperrors.From(err)
+	switch {
+	case appErr != nil:
+	case errors.Is(err, sql.ErrNoRows):
+		return errNotFound, errNotFound.Message
+	default:
+		return errInternal, errInternal.Message
+	}
+
+	if _, isTyped := err.(*apperrors.Error); isTyped || appErr.Kind == apperrors.KindInternal {
+		return appErr, appErr.Message
+	}
+	return appErr, err.Error()
+}
+
+// responseStatus is the status the request is answered with, also when the error is only
+// rendered by ErrorMiddleware after the calling middleware has returned
+func responseStatus(c *gin.Context) int {
+	if !c.Writer.Written() && len(c.Errors) > 0 {
+		appErr, _ := resolveError(c.Errors.Last().Err)
+		return appErr.Kind.Status()
+	}
+	return c.Writer.Status()
+}
+
+// abortWithError stops the chain, ErrorMiddleware renders err
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
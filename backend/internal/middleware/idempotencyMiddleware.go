@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"bytes"
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/service"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentResponseSize bounds the stored response, larger ones are not replayed
+	maxIdempotentResponseSize = 1 << 20
+)
+
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(b) > maxIdempotentResponseSize {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Idempotency makes a route safe to retry. The first request sent with an Idempotency-Key
+// runs, its response is stored when it succeeds and replayed, with Idempotent-Replayed set,
+// to later requests with the same key for the retention window. Keys are scoped to the
+// caller and organization, reusing one for a different request is rejected. Failed
+// requests release the key so that they can be retried. Requests without the header
+// are unaffected.
+func Idempotency(s service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			abortWithError(c, apperrors.ErrInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := readBody(c)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		orgId := c.GetInt("orgId")
+		userId := c.GetString("userId")
+		stored, err := s.Begin(c.Request.Context(), orgId, userId, key, requestHash(c, body))
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(*stored.StatusCode, "application/json; charset=utf-8", stored.Response)
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		// The client may be gone, which is when it retries, the outcome is recorded regardless
+		ctx := context.WithoutCancel(c.Request.Context())
+		status := writer.Status()
+		if len(c.Errors) == 0 && !writer.overflow && status >= http.StatusOK && status < http.StatusMultipleChoices {
+			if err := s.Complete(ctx, orgId, userId, key, status, writer.body.Bytes()); err != nil {
+				logger.FromContext(c).Error("Failed to store idempotent response", "ERR", err)
+			}
+			return
+		}
+		if err := s.Release(ctx, orgId, userId, key); err != nil {
+			logger.FromContext(c).Error("Failed to release idempotency key", "ERR", err)
+		}
+	}
+}
+
+// requestHash identifies a request so that a key reused for another one can be told apart
+func requestHash(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
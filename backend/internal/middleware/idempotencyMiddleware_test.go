@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/model/idempotency"
+	"clouding/backend/internal/service"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyRepository keeps keys like the idempotency_keys table, without expiry
+type memoryIdempotencyRepository struct {
+	mu   sync.Mutex
+	keys map[string]*idempotency.Key
+}
+
+func (r *memoryIdempotencyRepository) id(orgId int, userId, key string) string {
+	return fmt.Sprintf("%d/%s/%s", orgId, userId, key)
+}
+
+func (r *memoryIdempotencyRepository) ClaimKey(ctx context.Context, k *idempotency.Key, retention, lockTimeout time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.id(*k.OrgID, *k.UserID, *k.Key)
+	if _, ok := r.keys[id]; ok {
+		return false, nil
+	}
+	claimed := *k
+	r.keys[id] = &claimed
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) GetKey(ctx context.Context, orgId int, userId, key string) (*idempotency.Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[r.id(orgId, userId, key)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *k
+	return &copied, nil
+}
+
+func (r *memoryIdempotencyRepository) CompleteKey(ctx context.Context, orgId int, userId, key string, statusCode int, response []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := r.keys[r.id(orgId, userId, key)]
+	k.StatusCode = &statusCode
+	k.Response = append([]byte(nil), response...)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) ReleaseKey(ctx context.Context, orgId int, userId, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, r.id(orgId, userId, key))
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredKeys(ctx context.Context, retention time.Duration) error {
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memoryIdempotencyRepository{keys: map[string]*idempotency.Key{}}
+	s := service.NewIdempotencyService(repo, time.Hour, lifecycle.New())
+
+	runs := 0
+	r := gin.New()
+	r.Use(ErrorMiddleware(), func(c *gin.Context) {
+		c.Set("orgId", 1)
+		c.Set("userId", c.GetHeader("X-User"))
+	})
+	r.POST("/deployments", Idempotency(s), func(c *gin.Context) {
+		runs++
+		if c.Query("fail") != "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"run": runs})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"run": runs})
+	})
+
+	// Held by a request that is still running, with the hash requestHash gives an empty body
+	runningHash := sha256.Sum256([]byte("POST /deployments\n{}"))
+	if stored, err := s.Begin(context.Background(), 1, "a", "running", hex.EncodeToString(runningHash[:])); err != nil || stored != nil {
+		t.Fatalf("Begin() = %v, %v, want the key claimed", stored, err)
+	}
+
+	tests := []struct {
+		name         string
+		user         string
+		key          string
+		path         string
+		body         string
+		wantStatus   int
+		wantBody     string
+		wantReplayed bool
+	}{
+		{"without a key", "a", "", "/deployments", `{}`, http.StatusCreated, `{"run":1}`, false},
+		{"without a key again", "a", "", "/deployments", `{}`, http.StatusCreated, `{"run":2}`, false},
+		{"first request", "a", "k1", "/deployments", `{"blueprintId":1}`, http.StatusCreated, `{"run":3}`, false},
+		{"retry is replayed", "a", "k1", "/deployments", `{"blueprintId":1}`, http.StatusCreated, `{"run":3}`, true},
+		{"key reused for another body", "a", "k1", "/deployments", `{"blueprintId":2}`, http.StatusUnprocessableEntity, "idempotency_key_reused", false},
+		{"keys are per caller", "b", "k1", "/deployments", `{"blueprintId":1}`, http.StatusCreated, `{"run":4}`, false},
+		{"request in progress", "a", "running", "/deployments", `{}`, http.StatusConflict, "idempotency_key_in_progress", false},
+		{"failed request", "a", "k2", "/deployments?fail=1", `{}`, http.StatusServiceUnavailable, `{"run":5}`, false},
+		{"failed request released its key", "a", "k2", "/deployments", `{}`, http.StatusCreated, `{"run":6}`, false},
+		{"invalid key", "a", "k\x01", "/deployments", `{}`, http.StatusBadRequest, "invalid_idempotency_key", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-User", tt.user)
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+			if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplayed {
+				t.Fatalf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+		})
+	}
+}
@@ -1,22 +1,34 @@
 package middleware
 
 import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/logger"
 	"log/slog"
+	"math/rand/v2"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// SlogMiddleware writes the access log. Only server.accessLogSampling of the successful
+// requests are logged, client and server errors always are.
 func SlogMiddleware() gin.HandlerFunc {
+	sampling := config.Config.Server.AccessLogSampling
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
 		duration := time.Since(start)
 
-		slog.Info("HTTP Request",
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest && len(c.Errors) == 0 && sampling < 1 && rand.Float64() >= sampling {
+			return
+		}
+
+		logger.FromContext(c).Info("HTTP Request",
 			slog.String("method", c.Request.Method),
 			slog.String("path", c.Request.URL.Path),
-			slog.Int("status", c.Writer.Status()),
+			slog.Int("status", status),
 			slog.Duration("latency", duration),
 			slog.String("client_ip", c.ClientIP()),
 			slog.String("user_agent", c.Request.UserAgent()),
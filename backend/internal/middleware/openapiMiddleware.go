@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"clouding/backend/internal/config"
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/openapi"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxValidatedResponseSize bounds how much of a response is buffered to be checked, larger ones are skipped
+const maxValidatedResponseSize = 1 << 20
+
+type openAPIResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *openAPIResponseWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(b) > maxValidatedResponseSize {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// OpenAPIValidationMiddleware checks requests against the operation the OpenAPI document
+// has for the matched route, invalid parameters or bodies are rejected with a 400. With
+// openapi.validation set to both, successful JSON responses are checked too, mismatches
+// are only logged since the client already has the response.
+func OpenAPIValidationMiddleware(spec *openapi.Spec) gin.HandlerFunc {
+	mode := config.Config.OpenAPI.Validation
+	return func(c *gin.Context) {
+		if mode != "request" && mode != "both" {
+			c.Next()
+			return
+		}
+		doc := spec.Document()
+		op := doc.Operation(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		if err := validateRequest(c, doc, op); err != nil {
+			if apperrors.From(err) == nil {
+				err = apperrors.InvalidParameter(err.Error())
+			}
+			abortWithError(c, err)
+			return
+		}
+
+		status, schema := jsonSuccessResponse(op)
+		if mode != "both" || schema == nil {
+			c.Next()
+			return
+		}
+		writer := &openAPIResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		if writer.overflow || writer.Status() != status || writer.body.Len() == 0 {
+			return
+		}
+		if err := doc.ValidateJSON(schema, writer.body.Bytes(), "response"); err != nil {
+			logger.FromContext(c).Warn("Response does not match the OpenAPI document", "Operation", op.OperationID, "ERR", err)
+		}
+	}
+}
+
+func validateRequest(c *gin.Context, doc *openapi.Document, op *openapi.Operation) error {
+	for _, p := range op.Parameters {
+		var value string
+		switch p.In {
+		case openapi.InPath:
+			value = c.Param(p.Name)
+		case openapi.InQuery:
+			value = c.Query(p.Name)
+		default:
+			// Headers are checked by the authentication middlewares
+			continue
+		}
+		if value == "" {
+			if p.Required {
+				return errors.New(p.Name + " is required")
+			}
+			continue
+		}
+		if err := doc.ValidateParam(p, value); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	body, err := readBody(c)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errors.New("request body is required")
+	}
+	return doc.ValidateJSON(media.Schema, body, "body")
+}
+
+// jsonSuccessResponse is the status and body schema of an operation's JSON success response
+func jsonSuccessResponse(op *openapi.Operation) (int, *openapi.Schema) {
+	for code, resp := range op.Responses {
+		status, err := strconv.Atoi(code)
+		if err != nil || status >= 300 {
+			continue
+		}
+		if media, ok := resp.Content["application/json"]; ok {
+			return status, media.Schema
+		}
+	}
+	return 0, nil
+}
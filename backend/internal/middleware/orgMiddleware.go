@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/service"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const OrganizationHeader = "X-Organization-ID"
+
+// OrgMiddleware resolves the organization the request acts on and the caller's role in it.
+// Requests without the X-Organization-ID header act on the caller's personal organization.
+func OrgMiddleware(orgService service.OrganizationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.GetString("userId")
+
+		var requestedOrgId *int
+		if orgHeader := c.GetHeader(OrganizationHeader); orgHeader != "" {
+			orgId, err := strconv.Atoi(orgHeader)
+			if err != nil {
+				abortWithError(c, apperrors.InvalidParameter(OrganizationHeader+" must be a number"))
+				return
+			}
+			requestedOrgId = &orgId
+		}
+
+		// Api tokens are bound to the organization they were created in
+		if tokenOrgId, ok := c.Get("apiTokenOrgId"); ok {
+			orgId := tokenOrgId.(int)
+			if requestedOrgId != nil && *requestedOrgId != orgId {
+				abortWithError(c, apperrors.ErrWrongOrganization)
+				return
+			}
+			requestedOrgId = &orgId
+		}
+
+		membership, err := orgService.ResolveMembership(c.Request.Context(), userId, requestedOrgId)
+		if err != nil {
+			abortWithError(c, apperrors.OrNotFound(err, apperrors.ErrOrganizationNotFound))
+			return
+		}
+
+		c.Set("orgId", *membership.OrganizationID)
+		c.Set("orgRole", *membership.Role)
+		c.Next()
+	}
+}
+
+// RequirePermission rejects the request unless the caller's role in the current organization grants perm
+func RequirePermission(perm organization.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			abortWithError(c, apperrors.ErrForbidden.Withf("missing permission %s", perm))
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the caller's role grants perm and, for api tokens,
+// whether the token was scoped to it
+func HasPermission(c *gin.Context, perm organization.Permission) bool {
+	role, ok := c.Get("orgRole")
+	if !ok {
+		return false
+	}
+	r, ok := role.(organization.Role)
+	if !ok || !r.Can(perm) {
+		return false
+	}
+	if scopes, ok := c.Get("apiTokenScopes"); ok {
+		return slices.Contains(scopes.([]string), string(perm))
+	}
+	return true
+}
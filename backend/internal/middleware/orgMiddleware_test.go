@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/service"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memberships stands in for the organization service, a user is a member of the
+// organizations listed for them and the first one is their personal organization
+type memberships struct {
+	service.OrganizationService
+	orgs map[string][]int
+	// asked is the organization the last membership was resolved for, 0 for the personal one
+	asked int
+}
+
+func (m *memberships) ResolveMembership(ctx context.Context, userId string, orgId *int) (*organization.Membership, error) {
+	orgs := m.orgs[userId]
+	m.asked = 0
+	if orgId == nil {
+		orgId = &orgs[0]
+	} else {
+		m.asked = *orgId
+	}
+	for _, id := range orgs {
+		if id == *orgId {
+			role := organization.RoleOperator
+			return &organization.Membership{OrganizationID: &id, UserID: &userId, Role: &role}, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestOrgMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const user = "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11"
+	tests := []struct {
+		name       string
+		header     string
+		tokenOrgId int
+		wantStatus int
+		wantBody   string
+		wantAsked  int
+	}{
+		{"personal organization", "", 0, http.StatusOK, "1", 0},
+		{"member", "2", 0, http.StatusOK, "2", 2},
+		{"not a member", "3", 0, http.StatusNotFound, "organization_not_found", 3},
+		{"header not a number", "two", 0, http.StatusBadRequest, "invalid_parameter", 0},
+		{"token organization", "", 2, http.StatusOK, "2", 2},
+		{"token with its organization", "2", 2, http.StatusOK, "2", 2},
+		{"token with another organization", "1", 2, http.StatusForbidden, "wrong_organization", 0},
+		{"token of an organization the user left", "", 3, http.StatusNotFound, "organization_not_found", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := &memberships{orgs: map[string][]int{user: {1, 2}}}
+			r := gin.New()
+			r.Use(ErrorMiddleware(), func(c *gin.Context) {
+				c.Set("userId", user)
+				if tt.tokenOrgId != 0 {
+					c.Set("apiTokenOrgId", tt.tokenOrgId)
+				}
+			})
+			r.GET("/hosts", OrgMiddleware(orgs), func(c *gin.Context) {
+				c.String(http.StatusOK, strconv.Itoa(c.GetInt("orgId")))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/hosts", nil)
+			if tt.header != "" {
+				req.Header.Set(OrganizationHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d: %s, want %d with %s", w.Code, w.Body, tt.wantStatus, tt.wantBody)
+			}
+			if orgs.asked != tt.wantAsked {
+				t.Fatalf("membership resolved for organization %d, want %d", orgs.asked, tt.wantAsked)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionChecksTokenScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		role       organization.Role
+		scopes     []string
+		wantStatus int
+	}{
+		{"role grants it", organization.RoleAdmin, nil, http.StatusOK},
+		{"role lacks it", organization.RoleOperator, nil, http.StatusForbidden},
+		{"token scoped to it", organization.RoleAdmin, []string{"hosts:write"}, http.StatusOK},
+		{"token scoped to something else", organization.RoleAdmin, []string{"hosts:read"}, http.StatusForbidden},
+		{"token scope beyond the role", organization.RoleOperator, []string{"hosts:write"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorMiddleware(), func(c *gin.Context) {
+				c.Set("orgRole", tt.role)
+				if tt.scopes != nil {
+					c.Set("apiTokenScopes", tt.scopes)
+				}
+			})
+			r.POST("/hosts", RequirePermission(organization.PermHostsWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hosts", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
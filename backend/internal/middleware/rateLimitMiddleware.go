@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/utils/rateLimiter"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	routeGroupsMu sync.RWMutex
+	routeGroups   = map[string]string{}
+)
+
+// RateLimitGroup charges the requests served by handler to group instead of the group
+// RateLimit was registered with, so that every request is counted in a single bucket
+func RateLimitGroup(handler gin.HandlerFunc, group string) {
+	routeGroupsMu.Lock()
+	defer routeGroupsMu.Unlock()
+	// Matches gin.Context.HandlerName, like openapi.Describe
+	routeGroups[runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()] = group
+}
+
+func routeGroup(c *gin.Context, fallback string) string {
+	routeGroupsMu.RLock()
+	defer routeGroupsMu.RUnlock()
+	if group, ok := routeGroups[c.HandlerName()]; ok {
+		return group
+	}
+	return fallback
+}
+
+// RateLimit limits requests per api token, user or client IP, in that order of preference,
+// using the rule configured for group, or for the route's own group, see RateLimitGroup.
+// A nil limiter disables rate limiting.
+func RateLimit(limiter *rateLimiter.Limiter, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		group := routeGroup(c, group)
+
+		res, err := limiter.Take(c.Request.Context(), group, rateLimitKey(c))
+		if err != nil {
+			// Fail open, an unavailable store must not take the API down with it
+			logger.FromContext(c).Error("Rate limit store unavailable", "Group", group, "ERR", err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter.Seconds())))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter.Seconds())))
+			abortWithError(c, apperrors.ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context) string {
+	if tokenId, ok := c.Get("apiTokenId"); ok {
+		return fmt.Sprintf("token:%v", tokenId)
+	}
+	if userId := c.GetString("userId"); userId != "" {
+		return "user:" + userId
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(seconds float64) int {
+	return int(math.Max(0, math.Ceil(seconds)))
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"clouding/backend/internal/utils/rateLimiter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func limitedHealth(c *gin.Context) { c.Status(http.StatusOK) }
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := rateLimiter.NewLimiter(
+		rateLimiter.NewMemoryStore(),
+		rateLimiter.Rule{Requests: 3, Period: time.Hour},
+		map[string]rateLimiter.Rule{"limited": {Requests: 2, Period: time.Hour}},
+	)
+
+	r := gin.New()
+	r.Use(ErrorMiddleware(), func(c *gin.Context) {
+		c.Set("userId", c.GetHeader("X-User"))
+	}, RateLimit(limiter, rateLimiter.DefaultGroup))
+	r.GET("/default", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/limited", limitedHealth)
+	RateLimitGroup(limitedHealth, "limited")
+
+	tests := []struct {
+		name       string
+		user       string
+		path       string
+		wantStatus int
+		wantLimit  string
+		wantLeft   string
+	}{
+		{"route group, first", "a", "/limited", http.StatusOK, "2", "1"},
+		{"route group, second", "a", "/limited", http.StatusOK, "2", "0"},
+		{"route group, exhausted", "a", "/limited", http.StatusTooManyRequests, "2", "0"},
+		// The route group requests were not charged to the default bucket
+		{"default, first", "a", "/default", http.StatusOK, "3", "2"},
+		{"default, second", "a", "/default", http.StatusOK, "3", "1"},
+		{"default, third", "a", "/default", http.StatusOK, "3", "0"},
+		{"default, exhausted", "a", "/default", http.StatusTooManyRequests, "3", "0"},
+		{"other caller", "b", "/default", http.StatusOK, "3", "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-User", tt.user)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("X-RateLimit-Limit"); got != tt.wantLimit {
+				t.Errorf("X-RateLimit-Limit = %s, want %s", got, tt.wantLimit)
+			}
+			if got := w.Header().Get("X-RateLimit-Remaining"); got != tt.wantLeft {
+				t.Errorf("X-RateLimit-Remaining = %s, want %s", got, tt.wantLeft)
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("expected a Retry-After header")
+			}
+		})
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(nil, rateLimiter.DefaultGroup))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatalf("status = %d, headers = %v, want an unlimited 200", w.Code, w.Header())
+	}
+}
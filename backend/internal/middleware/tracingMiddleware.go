@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"clouding/backend/internal/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// TracingMiddleware opens a server span per request, continuing the trace from an
+// incoming traceparent header. Handlers pass c.Request.Context() down, so repository,
+// queue, secret and Loki spans become its children. Probe and scrape requests are skipped.
+func TracingMiddleware() gin.HandlerFunc {
+	return otelgin.Middleware(config.Config.Tracing.ServiceName,
+		otelgin.WithFilter(func(r *http.Request) bool {
+			switch r.URL.Path {
+			case "/healthz", "/readyz", config.Config.Metrics.Path:
+				return false
+			}
+			return true
+		}),
+	)
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"clouding/backend/internal/config"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWorkerAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.CloudingConfig{}
+	cfg.Worker.Secret = "shared"
+	cfg.Worker.Secrets = map[string]string{"worker-a": "own"}
+	cfg.Worker.MaxClockSkew = 5 * time.Minute
+	previous := config.Config
+	config.Config = cfg
+	t.Cleanup(func() { config.Config = previous })
+
+	const uri = "/internal/v1/deployments/6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11/status"
+	const body = `{"status":"started"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sign := func(secret, timestamp, body string) string {
+		return hex.EncodeToString(SignWorkerRequest(secret, timestamp, http.MethodPut, uri, []byte(body)))
+	}
+
+	tests := []struct {
+		name       string
+		workerId   string
+		timestamp  string
+		signature  string
+		body       string
+		authHeader string
+		wantStatus int
+	}{
+		{"own secret", "worker-a", now, sign("own", now, body), body, "", http.StatusOK},
+		{"shared secret", "worker-b", now, sign("shared", now, body), body, "", http.StatusOK},
+		{"shared secret for a worker with its own", "worker-a", now, sign("shared", now, body), body, "", http.StatusUnauthorized},
+		{"missing worker id", "", now, sign("shared", now, body), body, "", http.StatusUnauthorized},
+		{"tampered body", "worker-a", now, sign("own", now, body), `{"status":"completed"}`, "", http.StatusUnauthorized},
+		{"signature not hex", "worker-a", now, "zz", body, "", http.StatusUnauthorized},
+		{"missing signature", "worker-a", now, "", body, "", http.StatusUnauthorized},
+		{"timestamp not a number", "worker-a", "soon", sign("own", "soon", body), body, "", http.StatusUnauthorized},
+		{
+			"expired timestamp", "worker-a",
+			strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10),
+			sign("own", strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10), body),
+			body, "", http.StatusUnauthorized,
+		},
+		{"user token", "worker-a", now, sign("own", now, body), body, "Bearer user-token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handlerBody string
+			r := gin.New()
+			r.Use(ErrorMiddleware())
+			r.PUT("/internal/v1/deployments/:id/status", WorkerAuthMiddleware(), func(c *gin.Context) {
+				raw, _ := io.ReadAll(c.Request.Body)
+				handlerBody = string(raw)
+				c.String(http.StatusOK, c.GetString("workerId"))
+			})
+
+			req := httptest.NewRequest(http.MethodPut, uri, strings.NewReader(tt.body))
+			req.Header.Set(WorkerIDHeader, tt.workerId)
+			req.Header.Set(WorkerTimestampHeader, tt.timestamp)
+			req.Header.Set(WorkerSignatureHeader, tt.signature)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK && (w.Body.String() != tt.workerId || handlerBody != tt.body) {
+				t.Fatalf("handler saw worker %q and body %q", w.Body, handlerBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package apiToken
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TokenPrefix marks a bearer token as a personal access token rather than a session JWT
+const TokenPrefix = "cld_pat_"
+
+type ApiToken struct {
+	ID          *int           `db:"id" json:"id"`
+	UserID      *string        `db:"user_id" json:"userId"`
+	OrgID       *int           `db:"org_id" json:"orgId"`
+	Name        *string        `db:"name" json:"name"`
+	TokenPrefix *string        `db:"token_prefix" json:"tokenPrefix"`
+	TokenHash   *string        `db:"token_hash" json:"-"`
+	Scopes      pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt   *time.Time     `db:"expires_at" json:"expiresAt"`
+	LastUsedAt  *time.Time     `db:"last_used_at" json:"lastUsedAt"`
+	RevokedAt   *time.Time     `db:"revoked_at" json:"revokedAt"`
+	CreatedAt   *time.Time     `db:"created_at" json:"createdAt"`
+	UpdatedAt   *time.Time     `db:"updated_at" json:"updatedAt"`
+}
+
+// Response structs
+
+// CreateApiTokenResponse is the only time the plain token is returned
+type CreateApiTokenResponse struct {
+	ID        *int       `json:"id"`
+	Token     string     `json:"token"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type RevokeApiTokenResponse struct {
+	ID        *int `json:"id"`
+	IsRevoked bool `json:"isRevoked"`
+}
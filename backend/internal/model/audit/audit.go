@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ActorType string
+
+const (
+	ActorUser     ActorType = "user"
+	ActorApiToken ActorType = "api_token"
+	ActorWorker   ActorType = "worker"
+)
+
+type Entry struct {
+	ID           *int64           `db:"id" json:"id"`
+	OrgID        *int             `db:"org_id" json:"orgId"`
+	ActorID      *string          `db:"actor_id" json:"actorId"`
+	ActorType    ActorType        `db:"actor_type" json:"actorType"`
+	Action       *string          `db:"action" json:"action"`
+	ResourceType *string          `db:"resource_type" json:"resourceType"`
+	ResourceID   *string          `db:"resource_id" json:"resourceId"`
+	RequestID    *string          `db:"request_id" json:"requestId"`
+	SourceIP     *string          `db:"source_ip" json:"sourceIp"`
+	StatusCode   *int             `db:"status_code" json:"statusCode"`
+	Changes      *json.RawMessage `db:"changes" json:"changes"`
+	CreatedAt    *time.Time       `db:"created_at" json:"createdAt"`
+}
+
+// Filter narrows down GET /audit. Empty fields are ignored.
+type Filter struct {
+	OrgID        int
+	ActorID      string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	From         *time.Time
+	To           *time.Time
+	// Cursor is the id of the last entry of the previous page
+	Cursor *int64
+	Limit  int
+}
+
+// Response structs
+type ListAuditResponse struct {
+	Entries    []*Entry `json:"entries"`
+	NextCursor *int64   `json:"nextCursor"`
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"clouding/backend/internal/utils/redact"
+	"encoding/json"
+	"reflect"
+)
+
+type change struct {
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+// Diff returns the top level fields that differ between before and after as
+// {"field": {"before": ..., "after": ...}}, with secret-looking values redacted.
+// When both are set only the fields present in after are compared, since
+// updates are partial. It returns nil when nothing changed.
+func Diff(before, after any) (*json.RawMessage, error) {
+	b, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]change{}
+	switch {
+	case b == nil:
+		for k, v := range a {
+			changes[k] = change{After: v}
+		}
+	case a == nil:
+		for k, v := range b {
+			changes[k] = change{Before: v}
+		}
+	default:
+		for k, v := range a {
+			if !reflect.DeepEqual(b[k], v) {
+				changes[k] = change{Before: b[k], After: v}
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	msg := json.RawMessage(raw)
+	return &msg, nil
+}
+
+func toMap(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	var raw []byte
+	switch t := v.(type) {
+	case []byte:
+		raw = t
+	case json.RawMessage:
+		raw = t
+	default:
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	switch d := redact.Value(decoded).(type) {
+	case map[string]any:
+		return d, nil
+	case nil:
+		return nil, nil
+	default:
+		// Arrays and scalars, e.g. a list of host ids, are recorded as a single value
+		return map[string]any{"value": d}, nil
+	}
+}
@@ -9,19 +9,27 @@ import (
 
 type Blueprint struct {
 	ID          *int             `db:"id" json:"id"`
-	Name        *string          `db:"name" json:"name"`
-	Description *string          `db:"description" json:"description"`
+	Name        *string          `db:"name" json:"name" validate:"required,min=1,max=100,name"`
+	Description *string          `db:"description" json:"description" validate:"omitempty,max=1000"`
 	UserID      *string          `db:"user_id" json:"userId"`
-	Status      *BlueprintStatus `db:"status" json:"status"`
+	OrgID       *int             `db:"org_id" json:"orgId"`
+	Status      *BlueprintStatus `db:"status" json:"status" validate:"omitempty,oneof=draft deployed archived"`
 	CreatedAt   *time.Time       `db:"created_at" json:"createdAt"`
 	UpdatedAt   *time.Time       `db:"updated_at" json:"updatedAt"`
 }
 
+// Filter narrows down GET /blueprints. Empty fields are ignored.
+type Filter struct {
+	OrgID      int
+	Status     string
+	NamePrefix string
+}
+
 type BlueprintComponent struct {
 	ID          *int                 `db:"id" json:"id"`
 	BlueprintID *int                 `db:"blueprint_id" json:"blueprintId"`
-	ComponentID *int                 `db:"component_id" json:"componentId"`
-	Position    *int                 `db:"position" json:"position"`
+	ComponentID *int                 `db:"component_id" json:"componentId" validate:"required"`
+	Position    *int                 `db:"position" json:"position" validate:"required,min=0"`
 	Parameters  *BlueprintParameters `db:"parameters" json:"parameters"`
 	CreatedAt   *time.Time           `db:"created_at" json:"createdAt"`
 	UpdatedAt   *time.Time           `db:"updated_at" json:"updatedAt"`
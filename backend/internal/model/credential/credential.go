@@ -13,13 +13,23 @@ const (
 
 type Credential struct {
 	ID        *int                   `db:"id" json:"id"`
-	Name      *string                `db:"name" json:"name"`
-	Type      *CredentialType        `db:"type" json:"type"`
+	Name      *string                `db:"name" json:"name" validate:"required,min=1,max=100,name"`
+	Type      *CredentialType        `db:"type" json:"type" validate:"required,oneof=ssh_key ssl_cert password api_key"`
 	UserID    *string                `db:"user_id" json:"userId"`
+	OrgID     *int                   `db:"org_id" json:"orgId"`
 	ExpiresAt *time.Time             `db:"expires_at" json:"expiresAt"`
 	CreatedAt *time.Time             `db:"created_at" json:"createdAt"`
 	UpdatedAt *time.Time             `db:"updated_at" json:"updatedAt"`
-	Secret    map[string]interface{} `json:"secret"`
+	Secret    map[string]interface{} `json:"secret" validate:"required,min=1"`
+	// LegacySecretName is set on credentials whose secret still has its pre-organization name
+	LegacySecretName *string `db:"legacy_secret_name" json:"-"`
+}
+
+// Filter narrows down GET /credentials. Empty fields are ignored.
+type Filter struct {
+	OrgID      int
+	Type       string
+	NamePrefix string
 }
 
 // Response structs
@@ -36,3 +46,22 @@ type DeleteCredentialResponse struct {
 	ID        *int `json:"id"`
 	IsDeleted bool `json:"isDeleted"`
 }
+
+// SecretCheck is the outcome of verifying that a credential's secret exists
+type SecretCheck struct {
+	ID    *int    `json:"id"`
+	OrgID *int    `json:"orgId"`
+	Name  *string `json:"name"`
+	Error string  `json:"error,omitempty"`
+}
+
+// SecretMigration is the outcome of moving a credential's secret to its current name
+type SecretMigration struct {
+	ID    *int    `json:"id"`
+	OrgID *int    `json:"orgId"`
+	Name  *string `json:"name"`
+	// Shared is set when other credentials used the same legacy secret, its content may
+	// belong to any of them and should be verified
+	Shared bool   `json:"shared"`
+	Error  string `json:"error,omitempty"`
+}
@@ -5,15 +5,26 @@ import "time"
 type Deployment struct {
 	ID          *string          `db:"id" json:"id"`
 	UserID      *string          `db:"user_id" json:"userId"`
-	HostIDs     []int            `db:"host_id" json:"hostIds"`
+	OrgID       *int             `db:"org_id" json:"orgId"`
+	HostIDs     []int            `db:"host_id" json:"hostIds" validate:"omitempty,dive,min=1"`
 	HostGroupID *int             `db:"host_group_id" json:"hostGroupId"`
-	BlueprintID *int             `db:"blueprint_id" json:"blueprintId"`
-	Type        DeploymentType   `db:"type" json:"type"`     // "plan" or "deploy"
+	BlueprintID *int             `db:"blueprint_id" json:"blueprintId" validate:"required"`
+	Type        DeploymentType   `db:"type" json:"type" validate:"required,oneof=plan deploy"`
 	Status      DeploymentStatus `db:"status" json:"status"` // "pending", "started", etc.
 	CreatedAt   time.Time        `db:"created_at" json:"createdAt"`
 	UpdatedAt   time.Time        `db:"updated_at" json:"updatedAt"`
 }
 
+// Filter narrows down GET /deployments/type/:type. Empty fields are ignored.
+type Filter struct {
+	OrgID       int
+	Type        DeploymentType
+	Status      DeploymentStatus
+	BlueprintID *int
+	From        *time.Time
+	To          *time.Time
+}
+
 type DeploymentHostMapping struct {
 	DeploymentID *string          `db:"deployment_id" json:"deploymentId"`
 	HostID       *int             `db:"host_id" json:"hostId"`
@@ -23,10 +34,15 @@ type DeploymentHostMapping struct {
 type DeploymentMessage struct {
 	JobID       *string        `json:"jobId"`
 	UserID      *string        `json:"userId"`
+	OrgID       *int           `json:"orgId"`
 	HostIDs     []int          `json:"hostIds"`
 	BlueprintID *int           `json:"blueprintId"`
 	Type        DeploymentType `json:"type"`
 	CreatedAt   time.Time      `json:"created_at"`
+	RequestID   string         `json:"requestId,omitempty"` // the API request that created the job, added to the worker's Loki streams
+
+	// CredentialSecrets maps the ID of every credential used by the hosts to its secret name
+	CredentialSecrets map[string]string `json:"credentialSecrets"`
 }
 
 type UpdateDeploymentStatusPayload struct {
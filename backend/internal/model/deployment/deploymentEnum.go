@@ -15,3 +15,24 @@ const (
 	StatusCompleted DeploymentStatus = "completed"
 	StatusFailed    DeploymentStatus = "failed"
 )
+
+// Statuses lists every deployment status in lifecycle order
+var Statuses = []DeploymentStatus{StatusPending, StatusStarted, StatusCompleted, StatusFailed}
+
+// previousStatuses lists the statuses a deployment may move to each status from.
+// Reporting the current status again is allowed so that worker retries are harmless.
+var previousStatuses = map[DeploymentStatus][]DeploymentStatus{
+	StatusPending:   {StatusPending},
+	StatusStarted:   {StatusPending, StatusStarted},
+	StatusCompleted: {StatusPending, StatusStarted, StatusCompleted},
+	StatusFailed:    {StatusPending, StatusStarted, StatusFailed},
+}
+
+func (s DeploymentStatus) IsValid() bool {
+	_, ok := previousStatuses[s]
+	return ok
+}
+
+func (s DeploymentStatus) PreviousStatuses() []DeploymentStatus {
+	return previousStatuses[s]
+}
@@ -8,15 +8,24 @@ import (
 type Host struct {
 	ID           *int             `db:"id" json:"id"`
 	UserID       *string          `db:"user_id" json:"userId"`
-	Name         *string          `db:"name" json:"name"`
-	IP           *string          `db:"ip" json:"ip"`
-	Os           *string          `db:"os" json:"os"`
-	CredentialID *string          `db:"credential_id" json:"credentialId"`
+	OrgID        *int             `db:"org_id" json:"orgId"`
+	Name         *string          `db:"name" json:"name" validate:"required,min=1,max=100,name"`
+	IP           *string          `db:"ip" json:"ip" validate:"required,hostaddr"`
+	Os           *string          `db:"os" json:"os" validate:"required,min=1,max=50"`
+	CredentialID *string          `db:"credential_id" json:"credentialId" validate:"required,numeric"`
 	MetaData     *json.RawMessage `db:"meta_data" json:"metaData"`
 	CreatedAt    *time.Time       `db:"created_at" json:"createdAt"`
 	UpdatedAt    *time.Time       `db:"updated_at" json:"updatedAt"`
 }
 
+// Filter narrows down GET /hosts. Empty fields are ignored.
+type Filter struct {
+	OrgID        int
+	Os           string
+	NamePrefix   string
+	CredentialID *int
+}
+
 type HostHealth struct {
 	HostID    *int      `json:"hostId,omitempty"`
 	Status    *bool     `json:"status,omitempty"`
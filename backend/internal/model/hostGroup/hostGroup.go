@@ -9,13 +9,20 @@ import (
 type HostGroup struct {
 	ID          *int          `json:"id" db:"id"`
 	UserID      *string       `json:"userId" db:"user_id"`
-	Name        *string       `json:"name" db:"name"`
-	Description *string       `json:"description" db:"description"`
+	OrgID       *int          `json:"orgId" db:"org_id"`
+	Name        *string       `json:"name" db:"name" validate:"required,min=1,max=100,name"`
+	Description *string       `json:"description" db:"description" validate:"omitempty,max=1000"`
 	HostIds     pq.Int64Array `json:"hostIds" db:"host_ids"`
 	CreatedAt   *time.Time    `json:"createdAt" db:"created_at"`
 	UpdatedAt   *time.Time    `json:"updatedAt" db:"updated_at"`
 }
 
+// Filter narrows down GET /hostGroups. Empty fields are ignored.
+type Filter struct {
+	OrgID      int
+	NamePrefix string
+}
+
 type HostGroupCreateResponse struct {
 	ID        *int       `json:"id" db:"id"`
 	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
@@ -31,5 +38,5 @@ type HostGroupDeleteResponse struct {
 }
 
 type AddHostToHostgroupRequest struct {
-	HostIDs []int `json:"hostIds"`
+	HostIDs []int `json:"hostIds" validate:"required,min=1,dive,min=1"`
 }
@@ -0,0 +1,15 @@
+package idempotency
+
+import "time"
+
+// Key is a request sent with an Idempotency-Key header. StatusCode and Response are nil
+// while the request is still in progress.
+type Key struct {
+	OrgID       *int       `db:"org_id"`
+	UserID      *string    `db:"user_id"`
+	Key         *string    `db:"key"`
+	RequestHash *string    `db:"request_hash"`
+	StatusCode  *int       `db:"status_code"`
+	Response    []byte     `db:"response"`
+	CreatedAt   *time.Time `db:"created_at"`
+}
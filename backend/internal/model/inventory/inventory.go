@@ -0,0 +1,80 @@
+package inventory
+
+import "encoding/json"
+
+// Format is a file format hosts are imported from and exported to
+type Format string
+
+const (
+	FormatCSV         Format = "csv"
+	FormatAnsibleINI  Format = "ansible_ini"
+	FormatAnsibleYAML Format = "ansible_yaml"
+	FormatSSHConfig   Format = "ssh_config"
+)
+
+var Formats = []Format{FormatCSV, FormatAnsibleINI, FormatAnsibleYAML, FormatSSHConfig}
+
+// Action is what an import does, or would do on a dry run, with a host or group
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	// ActionSkip leaves a host alone that already exists under the same name
+	ActionSkip Action = "skip"
+	// ActionFail marks a host that is invalid, its errors are listed with its line
+	ActionFail Action = "fail"
+	// ActionExisting adds hosts to a group that already exists under the same name
+	ActionExisting Action = "existing"
+)
+
+type ImportRequest struct {
+	Format  *Format `json:"format" validate:"required,oneof=csv ansible_ini ansible_yaml ssh_config"`
+	Content *string `json:"content" validate:"required,min=1,max=1048576"`
+	// CredentialID and Os apply to the hosts that don't set their own
+	CredentialID *string `json:"credentialId" validate:"omitempty,numeric"`
+	Os           *string `json:"os" validate:"omitempty,min=1,max=50"`
+	// DryRun reports what the import would do without changing anything
+	DryRun bool `json:"dryRun"`
+}
+
+// Issue is a problem with one line of the imported file, Field names the host's field
+// it concerns, if any
+type Issue struct {
+	Line    int    `json:"line"`
+	Host    string `json:"host,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ImportedHost struct {
+	Line         int              `json:"line"`
+	ID           *int             `json:"id"`
+	Name         *string          `json:"name"`
+	IP           *string          `json:"ip"`
+	Os           *string          `json:"os"`
+	CredentialID *string          `json:"credentialId"`
+	MetaData     *json.RawMessage `json:"metaData"`
+	Group        *string          `json:"group"`
+	Action       Action           `json:"action"`
+}
+
+type ImportedGroup struct {
+	ID     *int   `json:"id"`
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	// Hosts are the names of the hosts the import adds to the group
+	Hosts []string `json:"hosts"`
+}
+
+type ImportResult struct {
+	DryRun  bool             `json:"dryRun"`
+	Created int              `json:"created"`
+	Skipped int              `json:"skipped"`
+	Failed  int              `json:"failed"`
+	Hosts   []*ImportedHost  `json:"hosts"`
+	Groups  []*ImportedGroup `json:"groups"`
+	// Errors are the reasons hosts failed, Warnings the parts of the file that were ignored
+	Errors   []Issue `json:"errors"`
+	Warnings []Issue `json:"warnings"`
+}
@@ -0,0 +1,39 @@
+package organization
+
+import "time"
+
+type Organization struct {
+	ID        *int       `db:"id" json:"id"`
+	Name      *string    `db:"name" json:"name"`
+	Personal  *bool      `db:"personal" json:"personal"`
+	CreatedBy *string    `db:"created_by" json:"createdBy"`
+	Role      *Role      `db:"role" json:"role,omitempty"`
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+type Membership struct {
+	OrganizationID *int       `db:"organization_id" json:"organizationId"`
+	UserID         *string    `db:"user_id" json:"userId"`
+	Role           *Role      `db:"role" json:"role"`
+	CreatedAt      *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt      *time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// Response structs
+type CreateOrganizationResponse struct {
+	ID *int `json:"id"`
+}
+
+type UpdateMembershipResponse struct {
+	OrganizationID *int       `json:"organizationId"`
+	UserID         *string    `json:"userId"`
+	Role           *Role      `json:"role"`
+	UpdatedAt      *time.Time `json:"updatedAt"`
+}
+
+type DeleteMembershipResponse struct {
+	OrganizationID *int    `json:"organizationId"`
+	UserID         *string `json:"userId"`
+	IsDeleted      bool    `json:"isDeleted"`
+}
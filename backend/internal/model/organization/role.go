@@ -0,0 +1,99 @@
+package organization
+
+type Role string
+
+const (
+	RoleOwner    Role = "owner"
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleViewer   Role = "viewer"
+)
+
+type Permission string
+
+const (
+	PermHostsRead         Permission = "hosts:read"
+	PermHostsWrite        Permission = "hosts:write"
+	PermHostGroupsRead    Permission = "hostGroups:read"
+	PermHostGroupsWrite   Permission = "hostGroups:write"
+	PermCredentialsRead   Permission = "credentials:read"
+	PermCredentialsSecret Permission = "credentials:secrets"
+	PermCredentialsWrite  Permission = "credentials:write"
+	PermBlueprintsRead    Permission = "blueprints:read"
+	PermBlueprintsWrite   Permission = "blueprints:write"
+	PermDeploymentsRead   Permission = "deployments:read"
+	PermDeploymentsWrite  Permission = "deployments:write"
+	PermMetricsRead       Permission = "metrics:read"
+	PermMembersRead       Permission = "members:read"
+	PermMembersWrite      Permission = "members:write"
+	PermAuditRead         Permission = "audit:read"
+)
+
+var readPermissions = []Permission{
+	PermHostsRead,
+	PermHostGroupsRead,
+	PermCredentialsRead,
+	PermBlueprintsRead,
+	PermDeploymentsRead,
+	PermMetricsRead,
+	PermMembersRead,
+}
+
+// operatorPermissions lets a role run deployments and read secrets without changing the inventory
+var operatorPermissions = append([]Permission{
+	PermCredentialsSecret,
+	PermDeploymentsWrite,
+}, readPermissions...)
+
+var adminPermissions = append([]Permission{
+	PermHostsWrite,
+	PermHostGroupsWrite,
+	PermCredentialsWrite,
+	PermBlueprintsWrite,
+	PermMembersWrite,
+	PermAuditRead,
+}, operatorPermissions...)
+
+var rolePermissions = map[Role]map[Permission]struct{}{
+	RoleOwner:    toSet(adminPermissions),
+	RoleAdmin:    toSet(adminPermissions),
+	RoleOperator: toSet(operatorPermissions),
+	RoleViewer:   toSet(readPermissions),
+}
+
+// rank orders roles so that a member can never grant a role above their own
+var rank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+	RoleOwner:    4,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rank[r]
+	return ok
+}
+
+func (r Role) Can(p Permission) bool {
+	_, ok := rolePermissions[r][p]
+	return ok
+}
+
+func (r Role) AtLeast(other Role) bool {
+	return rank[r] >= rank[other]
+}
+
+func toSet(perms []Permission) map[Permission]struct{} {
+	set := make(map[Permission]struct{}, len(perms))
+	for _, p := range perms {
+		set[p] = struct{}{}
+	}
+	return set
+}
+
+var allPermissions = toSet(adminPermissions)
+
+func (p Permission) IsValid() bool {
+	_, ok := allPermissions[p]
+	return ok
+}
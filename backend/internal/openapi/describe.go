@@ -0,0 +1,362 @@
+package openapi
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/pagination"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route documents what a handler accepts and returns. Request and Response are zero
+// values of the body types, e.g. host.Host{} or []*host.Host{}, nil when there is none.
+type Route struct {
+	Summary     string
+	Description string
+	Request     any
+	Response    any
+	// Status is the success status, 200 when unset
+	Status int
+	Params []Param
+	// List adds pagination's limit, cursor and sort parameters and meta to the response
+	List bool
+	// Stream answers with server sent events instead of JSON
+	Stream bool
+	// Public routes need no credentials
+	Public bool
+	// Raw answers with Response itself rather than inside the success envelope
+	Raw bool
+	// MediaTypes of a Raw response, application/json when unset
+	MediaTypes []string
+}
+
+// Param is a query or path parameter, path parameters are strings unless described
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Format      string
+	Description string
+	Required    bool
+}
+
+const (
+	InQuery  = "query"
+	InPath   = "path"
+	InHeader = "header"
+)
+
+// IfMatch is the header that makes an update or delete conditional on the version of the
+// resource the client read, described on the routes that support it
+var IfMatch = Param{
+	Name:        "If-Match",
+	In:          InHeader,
+	Description: "ETags of the versions the change is based on, 412 precondition_failed when the resource changed since",
+}
+
+// IdempotencyKey is the header that makes a create safe to retry, described on the routes
+// that support it
+var IdempotencyKey = Param{
+	Name:        "Idempotency-Key",
+	In:          InHeader,
+	Description: "Unique key of the request, a retry with the same key within the retention window gets the original response",
+}
+
+var (
+	routesMu sync.RWMutex
+	routes   = map[string]Route{}
+)
+
+// Describe annotates a handler, the document picks the annotation up for every route
+// the handler is registered on
+func Describe(handler gin.HandlerFunc, r Route) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	routes[handlerName(handler)] = r
+}
+
+// handlerName matches gin.RouteInfo.Handler
+func handlerName(handler gin.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+}
+
+func describedRoute(handler string) Route {
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+	return routes[handler]
+}
+
+// Build describes the /api/v1 and /internal/v1 routes registered on a gin engine,
+// probes and the banner are left out
+func Build(infos gin.RoutesInfo) *Document {
+	g := &generator{schemas: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "Clouding API",
+			Description: "Generated from the registered routes, every JSON response is wrapped in the success envelope or ErrorResponse.",
+			Version:     "v1",
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Supabase JWT or an API token"},
+				"workerAuth": {Type: "apiKey", In: "header", Name: "X-Worker-Signature", Description: "HMAC signature of a deployment worker, sent with X-Worker-ID and X-Worker-Timestamp"},
+			},
+		},
+	}
+	g.schemas["ErrorResponse"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success":   {Type: "boolean"},
+			"error":     {Type: "string"},
+			"code":      {Type: "string"},
+			"data":      {Nullable: true},
+			"requestId": {Type: "string"},
+			"details":   {Type: "array", Items: g.schemaOf(reflect.TypeFor[apperrors.Detail]())},
+		},
+		Required: []string{"success", "error", "code", "requestId"},
+	}
+
+	infos = append(gin.RoutesInfo(nil), infos...)
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		return infos[i].Method < infos[j].Method
+	})
+
+	operationIDs := map[string]int{}
+	for _, info := range infos {
+		internal := strings.HasPrefix(info.Path, "/internal/v1/")
+		if !internal && !strings.HasPrefix(info.Path, "/api/v1/") {
+			continue
+		}
+		r := describedRoute(info.Handler)
+		op := g.operation(info, r, internal)
+
+		// Handlers may be shared by several routes, operation IDs must stay unique
+		operationIDs[op.OperationID]++
+		if n := operationIDs[op.OperationID]; n > 1 {
+			op.OperationID += strconv.Itoa(n)
+		}
+
+		path := PathOf(info.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(info.Method)] = op
+	}
+	return doc
+}
+
+func (g *generator) operation(info gin.RouteInfo, r Route, internal bool) *Operation {
+	prefix := "/api/v1/"
+	if internal {
+		prefix = "/internal/v1/"
+	}
+	tag, _, _ := strings.Cut(strings.TrimPrefix(info.Path, prefix), "/")
+	tag = strings.TrimSuffix(tag, ".json")
+
+	op := &Operation{
+		OperationID: operationID(info.Handler),
+		Summary:     r.Summary,
+		Description: r.Description,
+		Tags:        []string{tag},
+		Responses:   map[string]*Response{},
+	}
+
+	switch {
+	case r.Public:
+	case internal:
+		op.Security = []map[string][]string{{"workerAuth": {}}}
+		op.Parameters = append(op.Parameters,
+			&Parameter{Name: "X-Worker-ID", In: "header", Required: true, Schema: &Schema{Type: "string"}},
+			&Parameter{Name: "X-Worker-Timestamp", In: "header", Required: true, Schema: &Schema{Type: "string"}, Description: "Unix time the request was signed at"},
+		)
+	default:
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name: "X-Organization-ID", In: "header", Schema: &Schema{Type: "integer"},
+			Description: "Organization the request acts on, the personal organization when unset",
+		})
+	}
+
+	described := map[string]Param{}
+	for _, p := range r.Params {
+		if p.In == InPath {
+			described[p.Name] = p
+		} else {
+			op.Parameters = append(op.Parameters, p.parameter())
+		}
+	}
+	for _, name := range pathParams(info.Path) {
+		p, ok := described[name]
+		if !ok {
+			p = Param{Name: name, In: InPath, Type: "string"}
+		}
+		p.Required = true
+		op.Parameters = append(op.Parameters, p.parameter())
+	}
+	if r.List {
+		op.Parameters = append(op.Parameters,
+			&Parameter{Name: "limit", In: InQuery, Schema: &Schema{Type: "integer"}, Description: "Page size, " + strconv.Itoa(pagination.DefaultLimit) + " by default and at most " + strconv.Itoa(pagination.MaxLimit)},
+			&Parameter{Name: "cursor", In: InQuery, Schema: &Schema{Type: "string"}, Description: "meta.nextCursor of the previous page"},
+			&Parameter{Name: "sort", In: InQuery, Schema: &Schema{Type: "string"}, Description: "Field to sort by, prefixed with - for descending order"},
+		)
+	}
+
+	if r.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(reflect.TypeOf(r.Request))}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case r.Stream:
+		success.Content = map[string]MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}}
+	case r.Raw:
+		raw := &Schema{}
+		if r.Response != nil {
+			raw = g.schemaOf(reflect.TypeOf(r.Response))
+		}
+		mediaTypes := r.MediaTypes
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{"application/json"}
+		}
+		success.Content = map[string]MediaType{}
+		for _, m := range mediaTypes {
+			success.Content[m] = MediaType{Schema: raw}
+		}
+	default:
+		success.Content = map[string]MediaType{"application/json": {Schema: g.envelope(r)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = &Response{
+		Description: "Error",
+		Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: refPrefix + "ErrorResponse"}}},
+	}
+	return op
+}
+
+// envelope is utils.NewSuccessResponse around the route's response
+func (g *generator) envelope(r Route) *Schema {
+	data := &Schema{Nullable: true}
+	if r.Response != nil {
+		data = g.schemaOf(reflect.TypeOf(r.Response))
+	}
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success":   {Type: "boolean"},
+			"error":     {Type: "string", Nullable: true},
+			"data":      data,
+			"requestId": {Type: "string"},
+		},
+		Required: []string{"success", "data", "requestId"},
+	}
+	if r.List {
+		s.Properties["meta"] = g.schemaOf(reflect.TypeFor[pagination.Meta]())
+		s.Required = append(s.Required, "meta")
+	}
+	return s
+}
+
+func (p Param) parameter() *Parameter {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+	return &Parameter{
+		Name:        p.Name,
+		In:          p.In,
+		Description: p.Description,
+		Required:    p.Required,
+		Schema:      &Schema{Type: typ, Format: p.Format},
+	}
+}
+
+// PathOf turns a gin path into an OpenAPI one, /hosts/:id becomes /hosts/{id}
+func PathOf(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(ginPath string) []string {
+	var names []string
+	for _, s := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			names = append(names, s[1:])
+		}
+	}
+	return names
+}
+
+// operationID shortens a handler name such as
+// clouding/backend/internal/controller/v1.(*HostController).GetAllHosts-fm to HostController.GetAllHosts
+func operationID(handler string) string {
+	name := handler[strings.LastIndex(handler, "/")+1:]
+	if _, rest, ok := strings.Cut(name, "."); ok {
+		name = rest
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
+
+// Spec builds the document of an engine on first use, once every route is registered
+type Spec struct {
+	engine *gin.Engine
+	once   sync.Once
+	doc    *Document
+	raw    []byte
+}
+
+func NewSpec(engine *gin.Engine) *Spec {
+	return &Spec{engine: engine}
+}
+
+func (s *Spec) build() {
+	s.once.Do(func() {
+		s.doc = Build(s.engine.Routes())
+		s.raw, _ = json.Marshal(s.doc)
+	})
+}
+
+func (s *Spec) Document() *Document {
+	s.build()
+	return s.doc
+}
+
+// JSON is the encoded document as served at /api/v1/openapi.json
+func (s *Spec) JSON() []byte {
+	s.build()
+	return s.raw
+}
+
+// Operation finds the operation of a route by its gin path, nil for undocumented routes
+func (d *Document) Operation(method string, ginPath string) *Operation {
+	item, ok := d.Paths[PathOf(ginPath)]
+	if !ok {
+		return nil
+	}
+	return item[strings.ToLower(method)]
+}
@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ValidateJSON checks a JSON document against a schema of d, the error names the
+// offending field, e.g. body.hosts[2].name
+func (d *Document) ValidateJSON(s *Schema, raw []byte, name string) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	// Numbers are kept as written so that integers can be told apart
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%s is not valid JSON", name)
+	}
+	return d.validate(s, value, name)
+}
+
+// ValidateParam checks a query or path parameter, which arrive as text
+func (d *Document) ValidateParam(p *Parameter, value string) error {
+	s := d.Resolve(p.Schema)
+	if s == nil {
+		return nil
+	}
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%s must be an integer", p.Name)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number", p.Name)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false", p.Name)
+		}
+	case "string":
+		return d.validate(s, value, p.Name)
+	}
+	return nil
+}
+
+func (d *Document) validate(s *Schema, value any, name string) error {
+	s = d.Resolve(s)
+	if s == nil {
+		return nil
+	}
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", name)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", name)
+		}
+		for _, field := range s.Required {
+			if _, ok := obj[field]; !ok {
+				return fmt.Errorf("%s.%s is required", name, field)
+			}
+		}
+		for field, v := range obj {
+			prop, ok := s.Properties[field]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if err := d.validate(prop, v, name+"."+field); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", name)
+		}
+		for i, v := range items {
+			if err := d.validate(s.Items, v, name+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", name)
+		}
+		if s.Enum != nil && !slices.Contains(s.Enum, any(str)) {
+			return fmt.Errorf("%s must be one of %v", name, s.Enum)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+		}
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+		if _, err := num.Int64(); err != nil {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s must be a number", name)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", name)
+		}
+	}
+	return nil
+}
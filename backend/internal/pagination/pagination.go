@@ -0,0 +1,257 @@
+package pagination
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 500
+	// CountCap bounds the count behind Meta.TotalEstimate, so large lists stay cheap to page
+	CountCap = 10000
+)
+
+// Page is the limit, sort and cursor a client asked for on a list endpoint
+type Page struct {
+	Limit int
+	// Sort is the query name of the field to sort by, empty for the list's default
+	Sort   string
+	Desc   bool
+	Cursor *Cursor
+}
+
+// Cursor points after the last item of the previous page. It records the sort it was
+// made for, since it can only be used to continue the same ordering. Null is set instead
+// of Value when the item had no sort value.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	Null  bool   `json:"n,omitempty"`
+	ID    string `json:"id"`
+}
+
+// Meta is returned next to data by every paginated list
+type Meta struct {
+	Limit int `json:"limit"`
+	// NextCursor is null on the last page
+	NextCursor *string `json:"nextCursor"`
+	// TotalEstimate is the number of matching items, counted up to CountCap
+	TotalEstimate int `json:"totalEstimate"`
+}
+
+// Field is a column a list can be sorted by. Value reads the field from an item to build
+// the next cursor, nil when it is NULL, Cast is the SQL type the cursor value is compared as.
+type Field[T any] struct {
+	Column string
+	Cast   string
+	Value  func(T) *string
+}
+
+// Sort describes how a list can be ordered. ID must be unique, it breaks ties between
+// items with the same sort value so that no item is skipped or repeated across pages.
+type Sort[T any] struct {
+	Fields  map[string]Field[T]
+	ID      Field[T]
+	Default string
+	// DefaultDesc orders the default field newest or highest first
+	DefaultDesc bool
+}
+
+// FromQuery reads limit, cursor and sort from the query string. sort is a field name,
+// prefixed with - for descending order, e.g. sort=-createdAt.
+func FromQuery(q url.Values) (*Page, error) {
+	p := &Page{Limit: DefaultLimit}
+
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, apperrors.InvalidParameter("limit must be a positive number")
+		}
+		p.Limit = min(limit, MaxLimit)
+	}
+
+	if value := q.Get("sort"); value != "" {
+		p.Sort, p.Desc = strings.CutPrefix(value, "-")
+	}
+
+	if value := q.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil || cursor.Sort == "" || cursor.ID == "" {
+			return nil, apperrors.InvalidParameter("invalid cursor")
+		}
+		p.Cursor = cursor
+	}
+	return p, nil
+}
+
+// List runs base, a select with the list's filters, one page at a time and counts
+// the items matching the filters for Meta.TotalEstimate
+func List[T any](ctx context.Context, db *sqlx.DB, base sq.SelectBuilder, p *Page, s Sort[T]) ([]T, *Meta, error) {
+	name, desc := p.Sort, p.Desc
+	if name == "" {
+		name, desc = s.Default, s.DefaultDesc
+	}
+	field, ok := s.Fields[name]
+	if !ok {
+		return nil, nil, apperrors.InvalidParameter("unknown sort field " + name)
+	}
+
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	query := base.OrderBy(field.Column+" "+dir, s.ID.Column+" "+dir)
+	if p.Cursor != nil {
+		if p.Cursor.Sort != name || p.Cursor.Desc != desc {
+			return nil, nil, apperrors.InvalidParameter("cursor was made for a different sort")
+		}
+		// Cursors come from the client, a value Postgres can't cast would fail the query
+		validSort := p.Cursor.Value == ""
+		if !p.Cursor.Null {
+			validSort = validValue(field.Cast, p.Cursor.Value)
+		}
+		if !validSort || !validValue(s.ID.Cast, p.Cursor.ID) {
+			return nil, nil, apperrors.InvalidParameter("invalid cursor")
+		}
+		query = query.Where(after(field, s.ID, p.Cursor))
+	}
+
+	total, err := count(ctx, db, base)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fetch one extra item to know whether there is a next page
+	query = query.Limit(uint64(p.Limit + 1))
+
+	sqlStr, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []T
+	if err := db.SelectContext(ctx, &items, sqlStr, args...); err != nil {
+		return nil, nil, err
+	}
+
+	meta := &Meta{Limit: p.Limit, TotalEstimate: total}
+	if len(items) > p.Limit {
+		items = items[:p.Limit]
+		last := items[p.Limit-1]
+		cursor := Cursor{Sort: name, Desc: desc}
+		if value := field.Value(last); value != nil {
+			cursor.Value = *value
+		} else {
+			cursor.Null = true
+		}
+		if id := s.ID.Value(last); id != nil {
+			cursor.ID = *id
+		}
+		next := encodeCursor(cursor)
+		meta.NextCursor = &next
+	}
+	if items == nil {
+		items = []T{}
+	}
+	return items, meta, nil
+}
+
+// after is the keyset condition for the items that follow the cursor. Postgres sorts NULL
+// last in ascending and first in descending order, and a row comparison with NULL is never
+// true, so items without a sort value are compared on their own.
+func after[T any](field Field[T], id Field[T], c *Cursor) sq.Sqlizer {
+	cmp := ">"
+	if c.Desc {
+		cmp = "<"
+	}
+	idValue := "CAST(? AS " + id.Cast + ")"
+	isNull := sq.Expr(field.Column + " IS NULL")
+	if c.Null {
+		sameNull := sq.And{isNull, sq.Expr(id.Column+" "+cmp+" "+idValue, c.ID)}
+		if c.Desc {
+			return sq.Or{sameNull, sq.Expr(field.Column + " IS NOT NULL")}
+		}
+		return sameNull
+	}
+	// Row comparison so that items sharing the sort value are split by id
+	rows := sq.Expr(
+		"("+field.Column+", "+id.Column+") "+cmp+" (CAST(? AS "+field.Cast+"), "+idValue+")",
+		c.Value, c.ID,
+	)
+	if c.Desc {
+		return rows
+	}
+	return sq.Or{rows, isNull}
+}
+
+// validValue reports whether value can be cast to the SQL type cast
+func validValue(cast, value string) bool {
+	switch cast {
+	case "int":
+		_, err := strconv.ParseInt(value, 10, 32)
+		return err == nil
+	case "bigint":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "uuid":
+		// uuid.Parse also accepts the urn:uuid: form, which Postgres does not
+		_, err := uuid.Parse(value)
+		return err == nil && len(value) == 36
+	case "timestamptz":
+		_, err := time.Parse(time.RFC3339Nano, value)
+		return err == nil
+	case "text":
+		return !strings.ContainsRune(value, 0)
+	}
+	return false
+}
+
+func count(ctx context.Context, db *sqlx.DB, base sq.SelectBuilder) (int, error) {
+	query, args, err := sq.Select("COUNT(*)").
+		FromSelect(base.RemoveColumns().Columns("1").Limit(CountCap), "matching").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	err = db.GetContext(ctx, &total, query, args...)
+	return total, err
+}
+
+// TimeValue formats a timestamp sort value for a cursor, at the microsecond precision Postgres keeps
+func TimeValue(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC().Format(time.RFC3339Nano)
+	return &value
+}
+
+func encodeCursor(c Cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
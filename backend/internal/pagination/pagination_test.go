@@ -0,0 +1,211 @@
+package pagination
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type item struct {
+	ID        string
+	Name      string
+	Size      int
+	CreatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+var itemSort = Sort[item]{
+	Fields: map[string]Field[item]{
+		"name":      {Column: "name", Cast: "text", Value: func(i item) *string { return ptr(i.Name) }},
+		"size":      {Column: "size", Cast: "int", Value: func(i item) *string { return ptr(strconv.Itoa(i.Size)) }},
+		"createdAt": {Column: "created_at", Cast: "timestamptz", Value: func(i item) *string { return TimeValue(&i.CreatedAt) }},
+		"deletedAt": {Column: "deleted_at", Cast: "timestamptz", Value: func(i item) *string { return TimeValue(i.DeletedAt) }},
+	},
+	ID:      Field[item]{Column: "id", Cast: "uuid", Value: func(i item) *string { return ptr(i.ID) }},
+	Default: "createdAt",
+}
+
+const itemID = "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11"
+
+func TestFromQuery(t *testing.T) {
+	valid := encodeCursor(Cursor{Sort: "name", Value: "web", ID: itemID})
+	tests := []struct {
+		name    string
+		query   string
+		want    Page
+		wantErr bool
+	}{
+		{"defaults", "", Page{Limit: DefaultLimit}, false},
+		{"limit", "limit=10", Page{Limit: 10}, false},
+		{"limit is capped", "limit=100000", Page{Limit: MaxLimit}, false},
+		{"zero limit", "limit=0", Page{}, true},
+		{"limit is not a number", "limit=ten", Page{}, true},
+		{"descending sort", "sort=-name", Page{Limit: DefaultLimit, Sort: "name", Desc: true}, false},
+		{"cursor", "cursor=" + valid, Page{Limit: DefaultLimit, Cursor: &Cursor{Sort: "name", Value: "web", ID: itemID}}, false},
+		{"cursor is not base64", "cursor=!!!", Page{}, true},
+		{"cursor is not json", "cursor=" + encodeRaw("nope"), Page{}, true},
+		{"cursor without an id", "cursor=" + encodeCursor(Cursor{Sort: "name", Value: "web"}), Page{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := FromQuery(q)
+			if tt.wantErr {
+				assertInvalidParameter(t, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Limit != tt.want.Limit || got.Sort != tt.want.Sort || got.Desc != tt.want.Desc {
+				t.Fatalf("FromQuery() = %+v, want %+v", got, tt.want)
+			}
+			if (got.Cursor == nil) != (tt.want.Cursor == nil) || (got.Cursor != nil && *got.Cursor != *tt.want.Cursor) {
+				t.Fatalf("cursor = %+v, want %+v", got.Cursor, tt.want.Cursor)
+			}
+		})
+	}
+}
+
+// TestListRejectsCursors checks cursors before anything is queried, so no database is needed
+func TestListRejectsCursors(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   Page
+		cursor Cursor
+	}{
+		{"unknown sort field", Page{Sort: "owner"}, Cursor{Sort: "owner", Value: "x", ID: itemID}},
+		{"different sort", Page{Sort: "name"}, Cursor{Sort: "size", Value: "1", ID: itemID}},
+		{"different direction", Page{Sort: "name"}, Cursor{Sort: "name", Desc: true, Value: "web", ID: itemID}},
+		{"tampered id", Page{Sort: "name"}, Cursor{Sort: "name", Value: "web", ID: "1 OR 1=1"}},
+		{"urn id", Page{Sort: "name"}, Cursor{Sort: "name", Value: "web", ID: "urn:uuid:" + itemID}},
+		{"tampered int", Page{Sort: "size"}, Cursor{Sort: "size", Value: "big", ID: itemID}},
+		{"int out of range", Page{Sort: "size"}, Cursor{Sort: "size", Value: "4294967296", ID: itemID}},
+		{"tampered time", Page{}, Cursor{Sort: "createdAt", Value: "yesterday", ID: itemID}},
+		{"text with a nul byte", Page{Sort: "name"}, Cursor{Sort: "name", Value: "web\x00", ID: itemID}},
+		{"null with a value", Page{Sort: "deletedAt"}, Cursor{Sort: "deletedAt", Null: true, Value: "x", ID: itemID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.page
+			p.Limit = DefaultLimit
+			p.Cursor = &tt.cursor
+			_, _, err := List(context.Background(), nil, sq.Select("*").From("items"), &p, itemSort)
+			assertInvalidParameter(t, err)
+		})
+	}
+}
+
+func TestValidValue(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.FixedZone("", 2*3600))
+	tests := []struct {
+		cast  string
+		value string
+		want  bool
+	}{
+		{"int", "42", true},
+		{"int", "-1", true},
+		{"int", "4.2", false},
+		{"bigint", "4294967296", true},
+		{"uuid", itemID, true},
+		{"uuid", "not-a-uuid", false},
+		{"timestamptz", *TimeValue(&created), true},
+		{"timestamptz", "2024-05-01", false},
+		{"text", "anything at all", true},
+		{"interval", "1 day", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cast+" "+tt.value, func(t *testing.T) {
+			if got := validValue(tt.cast, tt.value); got != tt.want {
+				t.Errorf("validValue(%q, %q) = %v, want %v", tt.cast, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, c := range []Cursor{
+		{Sort: "createdAt", Desc: true, Value: "2024-05-01T10:30:00.123456Z", ID: itemID},
+		{Sort: "deletedAt", Null: true, ID: itemID},
+	} {
+		got, err := decodeCursor(encodeCursor(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *got != c {
+			t.Fatalf("decodeCursor(encodeCursor(c)) = %+v, want %+v", got, c)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	field, id := itemSort.Fields["deletedAt"], itemSort.ID
+	const deleted = "2024-05-01T10:30:00.123456Z"
+	tests := []struct {
+		name     string
+		cursor   Cursor
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			"ascending", Cursor{Value: deleted, ID: itemID},
+			"((deleted_at, id) > (CAST(? AS timestamptz), CAST(? AS uuid)) OR deleted_at IS NULL)",
+			[]any{deleted, itemID},
+		},
+		{
+			"descending", Cursor{Desc: true, Value: deleted, ID: itemID},
+			"(deleted_at, id) < (CAST(? AS timestamptz), CAST(? AS uuid))",
+			[]any{deleted, itemID},
+		},
+		// NULL is sorted last in ascending order, only the NULLs with a higher id follow
+		{
+			"ascending from null", Cursor{Null: true, ID: itemID},
+			"(deleted_at IS NULL AND id > CAST(? AS uuid))",
+			[]any{itemID},
+		},
+		// and first in descending order, every value follows
+		{
+			"descending from null", Cursor{Desc: true, Null: true, ID: itemID},
+			"((deleted_at IS NULL AND id < CAST(? AS uuid)) OR deleted_at IS NOT NULL)",
+			[]any{itemID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := after(field, id, &tt.cursor).ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.wantSQL {
+				t.Errorf("query = %s\nwant    %s", query, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func encodeRaw(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func assertInvalidParameter(t *testing.T, err error) {
+	t.Helper()
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.CodeInvalidParameter {
+		t.Fatalf("error = %v, want an invalid parameter error", err)
+	}
+}
@@ -1,73 +1,185 @@
 package queue
 
 import (
+	"clouding/backend/internal/metrics"
+	"clouding/backend/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// reconnectInterval is the least time between two attempts to reconnect, so that requests
+// made while RabbitMQ is down fail fast instead of each waiting for a dial
+const reconnectInterval = 5 * time.Second
+
+const dialTimeout = 5 * time.Second
+
+// Publisher publishes jobs to a durable queue. It connects on the first publish or readiness
+// probe and reconnects when the connection or channel was closed, so a RabbitMQ outage fails
+// publishes and the readiness probe rather than the process.
 type Publisher struct {
-	Conn    *amqp091.Connection
-	Channel *amqp091.Channel
-	Queue   *amqp091.Queue
+	endpoint  string
+	queueName string
+
+	mu          sync.Mutex
+	conn        *amqp091.Connection
+	channel     *amqp091.Channel
+	lastAttempt time.Time
+	lastErr     error
 }
 
 func NewPublisher(url, port, username, password, queueName string) *Publisher {
+	return &Publisher{
+		endpoint:  "amqp://" + username + ":" + password + "@" + url + ":" + port,
+		queueName: queueName,
+	}
+}
 
-	endpoint := "amqp://" + username + ":" + password + "@" + url + ":" + port
+// open returns the open channel, connecting first when there is none
+func (p *Publisher) open() (*amqp091.Channel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	conn, err := amqp091.Dial(endpoint)
-	if err != nil {
-		panic(err)
+	if p.conn != nil && !p.conn.IsClosed() && p.channel != nil && !p.channel.IsClosed() {
+		return p.channel, nil
+	}
+	if p.lastErr != nil && time.Since(p.lastAttempt) < reconnectInterval {
+		return nil, p.lastErr
 	}
+	p.lastAttempt = time.Now()
+	p.lastErr = p.connect()
+	if p.lastErr != nil {
+		return nil, p.lastErr
+	}
+	return p.channel, nil
+}
 
+func (p *Publisher) connect() error {
+	p.closeLocked()
+
+	conn, err := amqp091.DialConfig(p.endpoint, amqp091.Config{Dial: amqp091.DefaultDial(dialTimeout)})
+	if err != nil {
+		return fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
 	ch, err := conn.Channel()
 	if err != nil {
-		slog.Error("Error in Rabbitmq connection")
-		panic(err)
+		conn.Close()
+		return fmt.Errorf("failed to open rabbitmq channel: %w", err)
 	}
-
-	q, err := ch.QueueDeclare(
-		queueName,
+	if _, err := ch.QueueDeclare(
+		p.queueName,
 		true,
 		false,
 		false,
 		false,
 		nil,
+	); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to declare queue %s: %w", p.queueName, err)
+	}
+
+	p.conn = conn
+	p.channel = ch
+	slog.Info("Connected to RabbitMQ", "Queue", p.queueName)
+	return nil
+}
+
+// Publish sends body to the queue. The W3C trace context of ctx is added to the message
+// headers (traceparent, tracestate, baggage) so the worker can continue the trace.
+func (p *Publisher) Publish(ctx context.Context, body []byte) error {
+	ctx, span := tracing.Start(ctx, p.queueName+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(p.queueName),
+			semconv.MessagingMessageBodySize(len(body)),
+		),
 	)
-	if err != nil {
-		slog.Error("Error declaring queue", "queue", queueName, "error", err)
-		panic(err)
+
+	headers := amqp091.Table{}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+
+	ch, err := p.open()
+	if err == nil {
+		err = ch.PublishWithContext(
+			ctx,
+			"",          // exchange
+			p.queueName, // routing key
+			false,       // mandatory
+			false,       // immediate
+			amqp091.Publishing{
+				ContentType: "application/json",
+				Headers:     headers,
+				Body:        body,
+			},
+		)
 	}
+	metrics.QueuePublishes.WithLabelValues(metrics.Result(err)).Inc()
+	tracing.End(span, err)
+	return err
+}
 
-	return &Publisher{
-		Conn:    conn,
-		Channel: ch,
-		Queue:   &q,
+// headerCarrier lets the otel propagators read and write AMQP message headers
+type headerCarrier amqp091.Table
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
 	}
+	return keys
 }
 
-func (p *Publisher) Publish(body []byte) error {
-	return p.Channel.Publish(
-		"",           // exchange
-		p.Queue.Name, // routing key
-		false,        // mandatory
-		false,        // immediate
-		amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+// Ping reports whether the publisher is connected, reconnecting when the connection or
+// channel was closed. It gives up when ctx is done, the reconnect carries on.
+func (p *Publisher) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.open()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (p *Publisher) Close() error {
-	err := p.Channel.Close()
-	if err != nil {
-		return err
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeLocked()
+}
+
+// closeLocked closes the channel and connection if they are open, p.mu must be held
+func (p *Publisher) closeLocked() error {
+	var err error
+	if p.channel != nil && !p.channel.IsClosed() {
+		err = p.channel.Close()
 	}
-	err = p.Conn.Close()
-	if err != nil {
-		return err
+	if p.conn != nil && !p.conn.IsClosed() {
+		err = errors.Join(err, p.conn.Close())
 	}
-	return nil
+	p.channel = nil
+	p.conn = nil
+	return err
 }
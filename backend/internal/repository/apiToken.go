@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"clouding/backend/internal/model/apiToken"
+	"context"
+	"database/sql"
+	_ "embed" // Required for embedding
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ApiTokenRepository defines data access for personal access tokens
+type ApiTokenRepository interface {
+	CreateApiToken(ctx context.Context, t *apiToken.ApiToken) error
+	GetApiTokensByUserId(ctx context.Context, userId string, orgId int) ([]*apiToken.ApiToken, error)
+	GetApiTokenByHash(ctx context.Context, hash string) (*apiToken.ApiToken, error)
+	RevokeApiToken(ctx context.Context, id int, userId string) error
+	TouchApiToken(ctx context.Context, id int) error
+}
+
+// Queries
+
+//go:embed sql/apiToken/createApiToken.sql
+var createApiTokenQuery string
+
+//go:embed sql/apiToken/getApiTokensByUserId.sql
+var getApiTokensByUserIdQuery string
+
+//go:embed sql/apiToken/getApiTokenByHash.sql
+var getApiTokenByHashQuery string
+
+//go:embed sql/apiToken/revokeApiToken.sql
+var revokeApiTokenQuery string
+
+//go:embed sql/apiToken/touchApiToken.sql
+var touchApiTokenQuery string
+
+type apiTokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewApiTokenRepository(db *sqlx.DB) ApiTokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+func (r *apiTokenRepository) CreateApiToken(ctx context.Context, t *apiToken.ApiToken) (err error) {
+	defer translateError(&err)
+	rows, err := r.db.NamedQueryContext(ctx, createApiTokenQuery, t)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id int
+	var createdAt, updatedAt time.Time
+	if rows.Next() {
+		if err := rows.Scan(&id, &createdAt, &updatedAt); err != nil {
+			return err
+		}
+		t.ID = &id
+		t.CreatedAt = &createdAt
+		t.UpdatedAt = &updatedAt
+	}
+	return rows.Err()
+}
+
+func (r *apiTokenRepository) GetApiTokensByUserId(ctx context.Context, userId string, orgId int) ([]*apiToken.ApiToken, error) {
+	var tokens []*apiToken.ApiToken
+	if err := r.db.SelectContext(ctx, &tokens, getApiTokensByUserIdQuery, userId, orgId); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenRepository) GetApiTokenByHash(ctx context.Context, hash string) (*apiToken.ApiToken, error) {
+	var t apiToken.ApiToken
+	if err := r.db.GetContext(ctx, &t, getApiTokenByHashQuery, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *apiTokenRepository) RevokeApiToken(ctx context.Context, id int, userId string) (err error) {
+	defer translateError(&err)
+	result, err := r.db.ExecContext(ctx, revokeApiTokenQuery, id, userId)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) TouchApiToken(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, touchApiTokenQuery, id)
+	return err
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"clouding/backend/internal/model/audit"
+	"context"
+	_ "embed" // Required for embedding
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditRepository defines data access for the append-only audit log
+type AuditRepository interface {
+	CreateEntry(ctx context.Context, e *audit.Entry) error
+	GetEntries(ctx context.Context, f *audit.Filter) ([]*audit.Entry, error)
+}
+
+//go:embed sql/audit/createAuditEntry.sql
+var createAuditEntryQuery string
+
+type auditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) CreateEntry(ctx context.Context, e *audit.Entry) error {
+	// changes is passed as text, raw bytes would be sent as bytea over the simple protocol
+	var changes *string
+	if e.Changes != nil {
+		c := string(*e.Changes)
+		changes = &c
+	}
+
+	_, err := r.db.NamedExecContext(ctx, createAuditEntryQuery, map[string]any{
+		"org_id":        e.OrgID,
+		"actor_id":      e.ActorID,
+		"actor_type":    e.ActorType,
+		"action":        e.Action,
+		"resource_type": e.ResourceType,
+		"resource_id":   e.ResourceID,
+		"request_id":    e.RequestID,
+		"source_ip":     e.SourceIP,
+		"status_code":   e.StatusCode,
+		"changes":       changes,
+	})
+	return err
+}
+
+// GetEntries returns entries newest first, starting after f.Cursor
+func (r *auditRepository) GetEntries(ctx context.Context, f *audit.Filter) ([]*audit.Entry, error) {
+	builder := sq.
+		Select("id", "org_id", "actor_id", "actor_type", "action", "resource_type", "resource_id",
+			"request_id", "source_ip", "status_code", "changes", "created_at").
+		From("audit_logs").
+		Where(sq.Eq{"org_id": f.OrgID}).
+		OrderBy("id DESC").
+		Limit(uint64(f.Limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	if f.ActorID != "" {
+		builder = builder.Where(sq.Eq{"actor_id": f.ActorID})
+	}
+	if f.Action != "" {
+		builder = builder.Where(sq.Eq{"action": f.Action})
+	}
+	if f.ResourceType != "" {
+		builder = builder.Where(sq.Eq{"resource_type": f.ResourceType})
+	}
+	if f.ResourceID != "" {
+		builder = builder.Where(sq.Eq{"resource_id": f.ResourceID})
+	}
+	if f.From != nil {
+		builder = builder.Where(sq.GtOrEq{"created_at": *f.From})
+	}
+	if f.To != nil {
+		builder = builder.Where(sq.Lt{"created_at": *f.To})
+	}
+	if f.Cursor != nil {
+		builder = builder.Where(sq.Lt{"id": *f.Cursor})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*audit.Entry
+	if err := r.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
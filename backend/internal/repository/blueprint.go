@@ -2,6 +2,7 @@ package repository
 
 import (
 	"clouding/backend/internal/model/blueprint"
+	"clouding/backend/internal/pagination"
 	"context"
 	"database/sql"
 	_ "embed"
@@ -9,17 +10,22 @@ import (
 	"log/slog"
 	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type BlueprintRepository interface {
-	GetBlueprint(ctx context.Context, id int) (*blueprint.Blueprint, error)
-	GetAllBlueprints(ctx context.Context, userId string) ([]*blueprint.Blueprint, error)
-	GetComponentsByBlueprintID(ctx context.Context, blueprintId int) ([]*blueprint.BlueprintComponent, error)
+	GetBlueprint(ctx context.Context, id int, orgId int) (*blueprint.Blueprint, error)
+	GetAllBlueprints(ctx context.Context, f *blueprint.Filter, p *pagination.Page) ([]*blueprint.Blueprint, *pagination.Meta, error)
+	GetComponentsByBlueprintID(ctx context.Context, blueprintId int, orgId int) ([]*blueprint.BlueprintComponent, error)
 	CreateBlueprint(ctx context.Context, b *blueprint.Blueprint) error
-	UpdateBlueprint(ctx context.Context, b *blueprint.Blueprint) error
-	UpdateBlueprintComponents(ctx context.Context, bluePrintId int, components []*blueprint.BlueprintComponent) error
-	DeleteBlueprint(ctx context.Context, id int) error
+	// The writes below only apply to the given versions of the blueprint when there are any,
+	// ErrPreconditionFailed is returned if the blueprint changed since. Replacing the
+	// components counts as a change of the blueprint, the new version is returned.
+	UpdateBlueprint(ctx context.Context, b *blueprint.Blueprint, versions []time.Time) error
+	UpdateBlueprintComponents(ctx context.Context, bluePrintId int, orgId int, components []*blueprint.BlueprintComponent, versions []time.Time) (*time.Time, error)
+	DeleteBlueprint(ctx context.Context, id int, orgId int, versions []time.Time) error
 }
 
 // Queries
@@ -27,9 +33,6 @@ type BlueprintRepository interface {
 //go:embed sql/blueprint/getBlueprintById.sql
 var getBlueprintByIdQuery string
 
-//go:embed sql/blueprint/getBlueprintsByUserId.sql
-var getBlueprintsByUserIdQuery string
-
 //go:embed sql/blueprint/createBlueprint.sql
 var createBlueprintQuery string
 
@@ -45,12 +48,29 @@ var updateBlueprintQuery string
 //go:embed sql/blueprint/updateBlueprintComponent.sql
 var updateBlueprintComponentQuery string
 
+//go:embed sql/blueprint/lockBlueprintById.sql
+var lockBlueprintByIdQuery string
+
+//go:embed sql/blueprint/touchBlueprint.sql
+var touchBlueprintQuery string
+
 //go:embed sql/blueprint/deleteBlueprintById.sql
 var deleteBlueprintByIdQuery string
 
 //go:embed sql/blueprint/deleteBlueprintComponent.sql
 var deleteBlueprintComponentByComponentIdAndBlueprintIdQuery string
 
+var blueprintSort = pagination.Sort[*blueprint.Blueprint]{
+	Fields: map[string]pagination.Field[*blueprint.Blueprint]{
+		"id":        intField("id", func(b *blueprint.Blueprint) *int { return b.ID }),
+		"name":      textField("name", func(b *blueprint.Blueprint) *string { return b.Name }),
+		"createdAt": timeField("created_at", func(b *blueprint.Blueprint) *time.Time { return b.CreatedAt }),
+		"updatedAt": timeField("updated_at", func(b *blueprint.Blueprint) *time.Time { return b.UpdatedAt }),
+	},
+	ID:      intField("id", func(b *blueprint.Blueprint) *int { return b.ID }),
+	Default: "id",
+}
+
 type blueprintRepository struct {
 	db *sqlx.DB
 }
@@ -59,9 +79,9 @@ func NewBlueprintRepository(db *sqlx.DB) BlueprintRepository {
 	return &blueprintRepository{db: db}
 }
 
-func (r *blueprintRepository) GetBlueprint(ctx context.Context, id int) (*blueprint.Blueprint, error) {
+func (r *blueprintRepository) GetBlueprint(ctx context.Context, id int, orgId int) (*blueprint.Blueprint, error) {
 	var bp blueprint.Blueprint
-	err := r.db.GetContext(ctx, &bp, getBlueprintByIdQuery, id)
+	err := r.db.GetContext(ctx, &bp, getBlueprintByIdQuery, id, orgId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -71,18 +91,26 @@ func (r *blueprintRepository) GetBlueprint(ctx context.Context, id int) (*bluepr
 	return &bp, nil
 }
 
-func (r *blueprintRepository) GetAllBlueprints(ctx context.Context, userId string) ([]*blueprint.Blueprint, error) {
-	var bps []*blueprint.Blueprint
-	err := r.db.SelectContext(ctx, &bps, getBlueprintsByUserIdQuery, userId)
-	if err != nil {
-		return nil, err
+func (r *blueprintRepository) GetAllBlueprints(ctx context.Context, f *blueprint.Filter, p *pagination.Page) ([]*blueprint.Blueprint, *pagination.Meta, error) {
+	builder := sq.
+		Select("id", "name", "description", "user_id", "org_id", "status", "created_at", "updated_at").
+		From("blueprints").
+		Where(sq.Eq{"org_id": f.OrgID})
+
+	if f.Status != "" {
+		// Compared as text, an unknown status matches nothing instead of failing the enum cast
+		builder = builder.Where(sq.Eq{"status::text": f.Status})
+	}
+	if f.NamePrefix != "" {
+		builder = builder.Where(likePrefix("name", f.NamePrefix))
 	}
-	return bps, nil
+
+	return pagination.List(ctx, r.db, builder, p, blueprintSort)
 }
 
-func (r *blueprintRepository) GetComponentsByBlueprintID(ctx context.Context, blueprintId int) ([]*blueprint.BlueprintComponent, error) {
+func (r *blueprintRepository) GetComponentsByBlueprintID(ctx context.Context, blueprintId int, orgId int) ([]*blueprint.BlueprintComponent, error) {
 	var comps []*blueprint.BlueprintComponent
-	err := r.db.SelectContext(ctx, &comps, getComponentsByBlueprintIdQuery, blueprintId)
+	err := r.db.SelectContext(ctx, &comps, getComponentsByBlueprintIdQuery, blueprintId, orgId)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +118,7 @@ func (r *blueprintRepository) GetComponentsByBlueprintID(ctx context.Context, bl
 }
 
 func (r *blueprintRepository) CreateBlueprint(ctx context.Context, b *blueprint.Blueprint) (err error) {
+	defer translateError(&err)
 	// Prepare and insert blueprint
 	rows, err := r.db.NamedQueryContext(ctx, createBlueprintQuery, b)
 	if err != nil {
@@ -102,7 +131,8 @@ func (r *blueprintRepository) CreateBlueprint(ctx context.Context, b *blueprint.
 	return nil
 }
 
-func (r *blueprintRepository) UpdateBlueprint(ctx context.Context, blprint *blueprint.Blueprint) (err error) {
+func (r *blueprintRepository) UpdateBlueprint(ctx context.Context, blprint *blueprint.Blueprint, versions []time.Time) (err error) {
+	defer translateError(&err)
 	// Update blueprint using static SQL
 	updateBlueprintStmt, err := r.db.PrepareNamedContext(ctx, updateBlueprintQuery)
 	if err != nil {
@@ -111,7 +141,11 @@ func (r *blueprintRepository) UpdateBlueprint(ctx context.Context, blprint *blue
 	defer updateBlueprintStmt.Close()
 
 	var updatedAt time.Time
-	rows, err := updateBlueprintStmt.QueryContext(ctx, blprint)
+	args := struct {
+		*blueprint.Blueprint
+		IfMatch any `db:"if_match"`
+	}{blprint, pq.Array(versions)}
+	rows, err := updateBlueprintStmt.QueryContext(ctx, args)
 	if err != nil {
 		return err
 	}
@@ -121,17 +155,18 @@ func (r *blueprintRepository) UpdateBlueprint(ctx context.Context, blprint *blue
 			return err
 		}
 	} else {
-		return sql.ErrNoRows
+		return missedWrite(ctx, r.db, "blueprints", *blprint.ID, *blprint.OrgID, versions)
 	}
 	blprint.UpdatedAt = &updatedAt
 
 	return nil
 }
 
-func (r *blueprintRepository) UpdateBlueprintComponents(ctx context.Context, bluePrintId int, components []*blueprint.BlueprintComponent) error {
+func (r *blueprintRepository) UpdateBlueprintComponents(ctx context.Context, bluePrintId int, orgId int, components []*blueprint.BlueprintComponent, versions []time.Time) (updatedAt *time.Time, err error) {
+	defer translateError(&err)
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -146,9 +181,19 @@ func (r *blueprintRepository) UpdateBlueprintComponents(ctx context.Context, blu
 		}
 	}()
 
-	existingComponents, err := r.GetComponentsByBlueprintID(ctx, bluePrintId)
-	if err != nil {
-		return err
+	// Lock the blueprint row, this also makes sure it belongs to the organization and is
+	// still at the version the client read
+	var lockedId int
+	if err = tx.GetContext(ctx, &lockedId, lockBlueprintByIdQuery, bluePrintId, orgId, pq.Array(versions)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = missedWrite(ctx, tx, "blueprints", bluePrintId, orgId, versions)
+		}
+		return nil, err
+	}
+
+	var existingComponents []*blueprint.BlueprintComponent
+	if err = tx.SelectContext(ctx, &existingComponents, getComponentsByBlueprintIdQuery, bluePrintId, orgId); err != nil {
+		return nil, err
 	}
 	existingComponentMap := make(map[int]*blueprint.BlueprintComponent)
 	for _, comp := range existingComponents {
@@ -163,20 +208,20 @@ func (r *blueprintRepository) UpdateBlueprintComponents(ctx context.Context, blu
 		if _, exists := newComponentMap[id]; !exists {
 			_, err = tx.ExecContext(ctx, deleteBlueprintComponentByComponentIdAndBlueprintIdQuery, bluePrintId, id)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
 	updateComponentStmt, err := tx.PrepareNamedContext(ctx, updateBlueprintComponentQuery)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer updateComponentStmt.Close()
 
 	createComponentStmt, err := tx.PrepareNamedContext(ctx, createBlueprintComponentQuery)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer createComponentStmt.Close()
 
@@ -186,12 +231,12 @@ func (r *blueprintRepository) UpdateBlueprintComponents(ctx context.Context, blu
 			var compUpdatedAt time.Time
 			compRows, err := updateComponentStmt.QueryContext(ctx, comp)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if compRows.Next() {
 				if err = compRows.Scan(&comp.ID); err != nil {
 					compRows.Close()
-					return err
+					return nil, err
 				}
 			}
 			compRows.Close()
@@ -199,23 +244,31 @@ func (r *blueprintRepository) UpdateBlueprintComponents(ctx context.Context, blu
 		} else {
 			compRows, err := createComponentStmt.QueryContext(ctx, comp)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if compRows.Next() {
 				if err = compRows.Scan(&comp.ID); err != nil {
 					compRows.Close()
-					return err
+					return nil, err
 				}
 			}
 			compRows.Close()
 		}
 	}
 
-	return tx.Commit()
+	var touchedAt time.Time
+	if err = tx.GetContext(ctx, &touchedAt, touchBlueprintQuery, bluePrintId); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &touchedAt, nil
 }
 
-func (r *blueprintRepository) DeleteBlueprint(ctx context.Context, id int) error {
-	result, err := r.db.ExecContext(ctx, deleteBlueprintByIdQuery, id)
+func (r *blueprintRepository) DeleteBlueprint(ctx context.Context, id int, orgId int, versions []time.Time) (err error) {
+	defer translateError(&err)
+	result, err := r.db.ExecContext(ctx, deleteBlueprintByIdQuery, id, orgId, pq.Array(versions))
 	if err != nil {
 		return err
 	}
@@ -224,7 +277,7 @@ func (r *blueprintRepository) DeleteBlueprint(ctx context.Context, id int) error
 		return err
 	}
 	if rows == 0 {
-		return sql.ErrNoRows
+		return missedWrite(ctx, r.db, "blueprints", id, orgId, versions)
 	}
 	return nil
 }
@@ -2,25 +2,35 @@ package repository
 
 import (
 	"clouding/backend/internal/model/credential"
+	"clouding/backend/internal/pagination"
 	secretmanager "clouding/backend/internal/utils/secretManager"
 	"context"
 	"database/sql"
 	_ "embed"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type CredentialRepository interface {
-	GetCredential(ctx context.Context, id int) (*credential.Credential, error)
-	GetAllCredentials(ctx context.Context, userId string) ([]*credential.Credential, error)
+	GetCredential(ctx context.Context, id int, orgId int, withSecret bool) (*credential.Credential, error)
+	GetAllCredentials(ctx context.Context, f *credential.Filter, p *pagination.Page, withSecrets bool) ([]*credential.Credential, *pagination.Meta, error)
 	CreateCredential(ctx context.Context, c *credential.Credential) error
-	UpdateCredential(ctx context.Context, c *credential.Credential) error
-	DeleteCredential(ctx context.Context, id int) error
+	// UpdateCredential and DeleteCredential only apply to the given versions of the credential
+	// when there are any, ErrPreconditionFailed is returned if the credential changed since
+	UpdateCredential(ctx context.Context, c *credential.Credential, versions []time.Time) error
+	DeleteCredential(ctx context.Context, id int, orgId int, versions []time.Time) error
+	GetAllCredentialsUnscoped(ctx context.Context) ([]*credential.Credential, error)
+	CheckSecret(ctx context.Context, cred *credential.Credential) error
+	// MigrateSecret moves a secret from its legacy name to SecretName, the legacy secret is
+	// deleted once no credential uses it anymore
+	MigrateSecret(ctx context.Context, cred *credential.Credential) error
 }
 
 // Queries
@@ -28,8 +38,8 @@ type CredentialRepository interface {
 //go:embed sql/credential/getCredentialById.sql
 var getCredentialByIdQuery string
 
-//go:embed sql/credential/getCredentialsByUserId.sql
-var getCredentialsByUserIdQuery string
+//go:embed sql/credential/getAllCredentials.sql
+var getAllCredentialsQuery string
 
 //go:embed sql/credential/createCredential.sql
 var createCredentialQuery string
@@ -37,6 +47,23 @@ var createCredentialQuery string
 //go:embed sql/credential/deleteCredentialById.sql
 var deleteCredentialByIdQuery string
 
+//go:embed sql/credential/isLegacySecretShared.sql
+var isLegacySecretSharedQuery string
+
+//go:embed sql/credential/clearLegacySecretName.sql
+var clearLegacySecretNameQuery string
+
+var credentialSort = pagination.Sort[*credential.Credential]{
+	Fields: map[string]pagination.Field[*credential.Credential]{
+		"id":        intField("id", func(c *credential.Credential) *int { return c.ID }),
+		"name":      textField("name", func(c *credential.Credential) *string { return c.Name }),
+		"createdAt": timeField("created_at", func(c *credential.Credential) *time.Time { return c.CreatedAt }),
+		"updatedAt": timeField("updated_at", func(c *credential.Credential) *time.Time { return c.UpdatedAt }),
+	},
+	ID:      intField("id", func(c *credential.Credential) *int { return c.ID }),
+	Default: "id",
+}
+
 type credentialRepository struct {
 	db             *sqlx.DB
 	secretsManager secretmanager.SecretsManager
@@ -46,9 +73,9 @@ func NewCredentialRepository(db *sqlx.DB, secretsManager secretmanager.SecretsMa
 	return &credentialRepository{db: db, secretsManager: secretsManager}
 }
 
-func (r *credentialRepository) GetCredential(ctx context.Context, id int) (*credential.Credential, error) {
+func (r *credentialRepository) GetCredential(ctx context.Context, id int, orgId int, withSecret bool) (*credential.Credential, error) {
 	var cred credential.Credential
-	err := r.db.GetContext(ctx, &cred, getCredentialByIdQuery, id)
+	err := r.db.GetContext(ctx, &cred, getCredentialByIdQuery, id, orgId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -56,7 +83,11 @@ func (r *credentialRepository) GetCredential(ctx context.Context, id int) (*cred
 		return nil, err
 	}
 
-	secret, err := r.secretsManager.GetSecret(getSecretNameFromCred(&cred))
+	if !withSecret {
+		return &cred, nil
+	}
+
+	secret, err := r.secretsManager.GetSecret(ctx, SecretName(&cred))
 	if err != nil {
 		return &cred, err
 	}
@@ -69,26 +100,40 @@ func (r *credentialRepository) GetCredential(ctx context.Context, id int) (*cred
 	return &cred, nil
 }
 
-func (r *credentialRepository) GetAllCredentials(ctx context.Context, userId string) ([]*credential.Credential, error) {
-	var creds []*credential.Credential
-	err := r.db.SelectContext(ctx, &creds, getCredentialsByUserIdQuery, userId)
-	if err != nil {
-		return nil, err
+func (r *credentialRepository) GetAllCredentials(ctx context.Context, f *credential.Filter, p *pagination.Page, withSecrets bool) ([]*credential.Credential, *pagination.Meta, error) {
+	builder := sq.
+		Select("id", "name", "type", "user_id", "org_id", "expires_at", "created_at", "updated_at", "legacy_secret_name").
+		From("credentials").
+		Where(sq.Eq{"org_id": f.OrgID})
+
+	if f.Type != "" {
+		// Compared as text, an unknown type matches nothing instead of failing the enum cast
+		builder = builder.Where(sq.Eq{"type::text": f.Type})
+	}
+	if f.NamePrefix != "" {
+		builder = builder.Where(likePrefix("name", f.NamePrefix))
+	}
+
+	creds, meta, err := pagination.List(ctx, r.db, builder, p, credentialSort)
+	if err != nil || !withSecrets {
+		return creds, meta, err
 	}
 
+	// Secrets are only read for the requested page
 	for _, cred := range creds {
-		secretJson, err := r.secretsManager.GetSecret(getSecretNameFromCred(cred))
+		secretJson, err := r.secretsManager.GetSecret(ctx, SecretName(cred))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if err := json.Unmarshal([]byte(secretJson), &cred.Secret); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
-	return creds, nil
+	return creds, meta, nil
 }
 
-func (r *credentialRepository) CreateCredential(ctx context.Context, c *credential.Credential) error {
+func (r *credentialRepository) CreateCredential(ctx context.Context, c *credential.Credential) (err error) {
+	defer translateError(&err)
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -119,14 +164,14 @@ func (r *credentialRepository) CreateCredential(ctx context.Context, c *credenti
 	}
 	c.ID = &id
 
-	secretName := getSecretNameFromCred(c)
+	secretName := SecretName(c)
 
-	if err := r.secretsManager.SetSecret(secretName, c.Secret); err != nil {
+	if err := r.secretsManager.SetSecret(ctx, secretName, c.Secret); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(); err != nil {
-		if delErr := r.secretsManager.DeleteSecret(secretName); delErr != nil {
+		if delErr := r.secretsManager.DeleteSecret(ctx, secretName); delErr != nil {
 			slog.Error("DB commit failed AND secret rollback failed",
 				"secretName", secretName,
 				"commitError", err.Error(),
@@ -145,8 +190,8 @@ func (r *credentialRepository) CreateCredential(ctx context.Context, c *credenti
 	return nil
 }
 
-func (r *credentialRepository) UpdateCredential(ctx context.Context, c *credential.Credential) error {
-	secretName := getSecretNameFromCred(c)
+func (r *credentialRepository) UpdateCredential(ctx context.Context, c *credential.Credential, versions []time.Time) (err error) {
+	defer translateError(&err)
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
@@ -165,23 +210,19 @@ func (r *credentialRepository) UpdateCredential(ctx context.Context, c *credenti
 		}
 	}()
 
-	builder := sq.
-		Update("credentials").
-		Set("updated_at", "NOW()").
-		Where(sq.And{
-			sq.Eq{"id": c.ID},
-			sq.Eq{"name": c.Name},
-		}).
-		Suffix("RETURNING updated_at").
-		PlaceholderFormat(sq.Dollar)
-
-	if c.Type != nil {
-		builder = builder.Set("type", *c.Type)
+	// A secret still under its legacy name is moved to its own on update, the legacy one may
+	// be shared with a credential of another organization
+	var existing credential.Credential
+	if err = tx.GetContext(ctx, &existing, getCredentialByIdQuery, c.ID, c.OrgID); err != nil {
+		return err
 	}
+	legacyName := existing.LegacySecretName
+	existing.LegacySecretName = nil
+	secretName := SecretName(&existing)
 
-	if c.ExpiresAt != nil {
-		builder = builder.Set("expires_at", *c.ExpiresAt)
-	}
+	// Without a new secret the legacy one stays where it is, it can't be moved unread
+	moveSecret := legacyName != nil && c.Secret != nil
+	builder := updateCredentialQuery(c, versions, moveSecret)
 
 	query, args, err := builder.ToSql()
 
@@ -190,11 +231,21 @@ func (r *credentialRepository) UpdateCredential(ctx context.Context, c *credenti
 	}
 
 	var updatedAt time.Time
-	if err := tx.GetContext(ctx, &updatedAt, query, args...); err != nil {
+	if err = tx.GetContext(ctx, &updatedAt, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return missedWrite(ctx, tx, "credentials", *c.ID, *c.OrgID, versions)
+		}
 		return err
 	}
 	c.UpdatedAt = &updatedAt
-	if err := r.secretsManager.UpdateSecret(secretName, c.Secret); err != nil {
+	switch {
+	case c.Secret == nil:
+	case moveSecret:
+		err = r.secretsManager.SetSecret(ctx, secretName, c.Secret)
+	default:
+		err = r.secretsManager.UpdateSecret(ctx, secretName, c.Secret)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -203,11 +254,44 @@ func (r *credentialRepository) UpdateCredential(ctx context.Context, c *credenti
 		return err
 	}
 
+	if moveSecret {
+		r.deleteLegacySecret(ctx, *legacyName, *c.ID)
+	}
 	return nil
 }
 
-func (r *credentialRepository) DeleteCredential(ctx context.Context, id int) error {
-	cred, err := r.GetCredential(ctx, id)
+// updateCredentialQuery sets the fields of c that are present. Secrets are named after the
+// credential's id, so the name can change like any other field.
+func updateCredentialQuery(c *credential.Credential, versions []time.Time, clearLegacySecret bool) sq.UpdateBuilder {
+	builder := sq.
+		Update("credentials").
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.And{
+			sq.Eq{"id": c.ID},
+			sq.Eq{"org_id": c.OrgID},
+			ifMatch(versions),
+		}).
+		Suffix("RETURNING updated_at").
+		PlaceholderFormat(sq.Dollar)
+
+	if c.Name != nil {
+		builder = builder.Set("name", *c.Name)
+	}
+	if c.Type != nil {
+		builder = builder.Set("type", *c.Type)
+	}
+	if c.ExpiresAt != nil {
+		builder = builder.Set("expires_at", *c.ExpiresAt)
+	}
+	if clearLegacySecret {
+		builder = builder.Set("legacy_secret_name", nil)
+	}
+	return builder
+}
+
+func (r *credentialRepository) DeleteCredential(ctx context.Context, id int, orgId int, versions []time.Time) (err error) {
+	defer translateError(&err)
+	cred, err := r.GetCredential(ctx, id, orgId, false)
 	if err != nil {
 		return err
 	}
@@ -232,17 +316,26 @@ func (r *credentialRepository) DeleteCredential(ctx context.Context, id int) err
 		}
 	}()
 
-	result, err := tx.ExecContext(ctx, deleteCredentialByIdQuery, id)
+	result, err := tx.ExecContext(ctx, deleteCredentialByIdQuery, id, orgId, pq.Array(versions))
 	if err != nil {
 		return err
 	}
 
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return sql.ErrNoRows
+		return missedWrite(ctx, tx, "credentials", id, orgId, versions)
 	}
-	if err := r.secretsManager.DeleteSecret(getSecretNameFromCred(cred)); err != nil {
-		return err
+	// A legacy secret shared with another credential stays for that credential
+	shared := false
+	if cred.LegacySecretName != nil {
+		if err = tx.GetContext(ctx, &shared, isLegacySecretSharedQuery, *cred.LegacySecretName, id); err != nil {
+			return err
+		}
+	}
+	if !shared {
+		if err := r.secretsManager.DeleteSecret(ctx, SecretName(cred)); err != nil {
+			return err
+		}
 	}
 	if err := tx.Commit(); err != nil {
 
@@ -256,6 +349,81 @@ func (r *credentialRepository) DeleteCredential(ctx context.Context, id int) err
 	return nil
 }
 
-func getSecretNameFromCred(cred *credential.Credential) string {
-	return *cred.Name + "-" + *cred.UserID
+// GetAllCredentialsUnscoped lists credentials of every organization, for operator tooling
+func (r *credentialRepository) GetAllCredentialsUnscoped(ctx context.Context) ([]*credential.Credential, error) {
+	var creds []*credential.Credential
+	if err := r.db.SelectContext(ctx, &creds, getAllCredentialsQuery); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// CheckSecret reads the credential's secret and reports whether it is missing or unreadable
+func (r *credentialRepository) CheckSecret(ctx context.Context, cred *credential.Credential) error {
+	secretJson, err := r.secretsManager.GetSecret(ctx, SecretName(cred))
+	if err != nil {
+		return err
+	}
+	var secret map[string]interface{}
+	return json.Unmarshal([]byte(secretJson), &secret)
+}
+
+func (r *credentialRepository) MigrateSecret(ctx context.Context, cred *credential.Credential) (err error) {
+	defer translateError(&err)
+	if cred.LegacySecretName == nil {
+		return nil
+	}
+	legacyName := *cred.LegacySecretName
+
+	secretJson, err := r.secretsManager.GetSecret(ctx, legacyName)
+	if err != nil {
+		return err
+	}
+	var secret map[string]interface{}
+	if err := json.Unmarshal([]byte(secretJson), &secret); err != nil {
+		return err
+	}
+
+	migrated := *cred
+	migrated.LegacySecretName = nil
+	secretName := SecretName(&migrated)
+	// The secret exists already when an earlier run stopped before clearing the legacy name
+	if err := r.secretsManager.SetSecret(ctx, secretName, secret); err != nil {
+		if err := r.secretsManager.UpdateSecret(ctx, secretName, secret); err != nil {
+			return err
+		}
+	}
+	if _, err := r.db.ExecContext(ctx, clearLegacySecretNameQuery, *cred.ID); err != nil {
+		return err
+	}
+	cred.LegacySecretName = nil
+
+	r.deleteLegacySecret(ctx, legacyName, *cred.ID)
+	return nil
+}
+
+// deleteLegacySecret deletes a legacy secret once id, which no longer uses it, was its last
+// credential. Failing only leaves an unused secret behind, it is logged.
+func (r *credentialRepository) deleteLegacySecret(ctx context.Context, legacyName string, id int) {
+	var shared bool
+	if err := r.db.GetContext(ctx, &shared, isLegacySecretSharedQuery, legacyName, id); err != nil {
+		slog.Error("Failed to check legacy secret usage", "secretName", legacyName, "error", err)
+		return
+	}
+	if shared {
+		return
+	}
+	if err := r.secretsManager.DeleteSecret(ctx, legacyName); err != nil {
+		slog.Error("Failed to delete legacy secret", "secretName", legacyName, "error", err)
+	}
+}
+
+// SecretName is the name of the credential's secret. Secrets are named by organization and
+// credential ID, as credential names are only unique within an organization. Credentials
+// created before that keep their legacy name until their secret is migrated.
+func SecretName(cred *credential.Credential) string {
+	if cred.LegacySecretName != nil {
+		return *cred.LegacySecretName
+	}
+	return fmt.Sprintf("org-%d-credential-%d", *cred.OrgID, *cred.ID)
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"clouding/backend/internal/model/credential"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUpdateCredentialQuery(t *testing.T) {
+	id, orgId := 7, 3
+	name := "renamed"
+	sshKey := credential.CredentialType("ssh_key")
+	version := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		credential  credential.Credential
+		versions    []time.Time
+		clearLegacy bool
+		wantSQL     string
+		wantArgs    []any
+	}{
+		{
+			name:       "rename",
+			credential: credential.Credential{ID: &id, OrgID: &orgId, Name: &name},
+			wantSQL:    "UPDATE credentials SET updated_at = NOW(), name = $1 WHERE (id = $2 AND org_id = $3 AND (1=1)) RETURNING updated_at",
+			wantArgs:   []any{"renamed", 7, 3},
+		},
+		{
+			name:       "without a name",
+			credential: credential.Credential{ID: &id, OrgID: &orgId, Type: &sshKey},
+			versions:   []time.Time{version},
+			wantSQL:    "UPDATE credentials SET updated_at = NOW(), type = $1 WHERE (id = $2 AND org_id = $3 AND updated_at IN ($4)) RETURNING updated_at",
+			wantArgs:   []any{sshKey, 7, 3, version},
+		},
+		{
+			name:        "moving a legacy secret",
+			credential:  credential.Credential{ID: &id, OrgID: &orgId},
+			clearLegacy: true,
+			wantSQL:     "UPDATE credentials SET updated_at = NOW(), legacy_secret_name = $1 WHERE (id = $2 AND org_id = $3 AND (1=1)) RETURNING updated_at",
+			wantArgs:    []any{nil, 7, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := updateCredentialQuery(&tt.credential, tt.versions, tt.clearLegacy).ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.wantSQL {
+				t.Errorf("query = %s\nwant    %s", query, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
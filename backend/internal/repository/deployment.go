@@ -1,13 +1,16 @@
 package repository
 
 import (
+	apperrors "clouding/backend/internal/errors"
 	"clouding/backend/internal/model/deployment"
+	"clouding/backend/internal/pagination"
 	"context"
 	"database/sql"
 	_ "embed"
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
@@ -18,10 +21,11 @@ import (
 type DeploymentRepository interface {
 	Create(ctx context.Context, d *deployment.Deployment) error
 	UpdateStatus(ctx context.Context, id string, updateDeploymentStatusPayload *deployment.UpdateDeploymentStatusPayload) error
-	GetByID(ctx context.Context, id string) (*deployment.Deployment, error)
-	GetByUserAndType(ctx context.Context, userId string, dType string) ([]*deployment.Deployment, error)
-	GetByBlueprintID(ctx context.Context, blueprintId int, limit int) ([]*deployment.Deployment, error)
-	GetDeploymentHostMappingByIds(ctx context.Context, ids []string) ([]*deployment.DeploymentHostMapping, error)
+	GetByID(ctx context.Context, id string, orgId int) (*deployment.Deployment, error)
+	GetByOrgAndType(ctx context.Context, f *deployment.Filter, p *pagination.Page) ([]*deployment.Deployment, *pagination.Meta, error)
+	GetByBlueprintID(ctx context.Context, blueprintId int, orgId int, limit int) ([]*deployment.Deployment, error)
+	GetDeploymentHostMappingByIds(ctx context.Context, ids []string, orgId int) ([]*deployment.DeploymentHostMapping, error)
+	CountByStatus(ctx context.Context) (map[deployment.DeploymentStatus]int, error)
 }
 
 // Embedded SQL queries
@@ -29,15 +33,28 @@ type DeploymentRepository interface {
 //go:embed sql/deployment/getDeploymentById.sql
 var getDeploymentByIdQuery string
 
-//go:embed sql/deployment/getDeploymentByUser.sql
-var getByUserAndTypeQuery string
-
 //go:embed sql/deployment/getDeploymentsByBlueprintId.sql
 var getByBlueprintIDQuery string
 
 //go:embed sql/deployment/getDeploymentHostMapping.sql
 var getDeploymentHostMapping string
 
+//go:embed sql/deployment/getDeploymentStatus.sql
+var getDeploymentStatusQuery string
+
+//go:embed sql/deployment/countDeploymentsByStatus.sql
+var countDeploymentsByStatusQuery string
+
+var deploymentSort = pagination.Sort[*deployment.Deployment]{
+	Fields: map[string]pagination.Field[*deployment.Deployment]{
+		"createdAt": timeField("created_at", func(d *deployment.Deployment) *time.Time { return &d.CreatedAt }),
+		"updatedAt": timeField("updated_at", func(d *deployment.Deployment) *time.Time { return &d.UpdatedAt }),
+	},
+	ID:          pagination.Field[*deployment.Deployment]{Column: "id", Cast: "uuid", Value: func(d *deployment.Deployment) *string { return d.ID }},
+	Default:     "createdAt",
+	DefaultDesc: true,
+}
+
 type deploymentRepository struct {
 	db *sqlx.DB
 }
@@ -48,7 +65,9 @@ func NewDeploymentRepository(db *sqlx.DB) DeploymentRepository {
 	}
 }
 
-func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deployment) error {
+// Create inserts a deployment and its host mappings, filling in its status and timestamps
+func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deployment) (err error) {
+	defer translateError(&err)
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
@@ -68,10 +87,11 @@ func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deploym
 	}()
 
 	deploymentBuilder := sq.Insert("deployments").
-		Columns("id", "user_id", "blueprint_id", "type", "status").
+		Columns("id", "user_id", "org_id", "blueprint_id", "type", "status").
+		Suffix("RETURNING status, created_at, updated_at").
 		PlaceholderFormat(sq.Dollar)
 	deploymentBuilder = deploymentBuilder.Values(
-		d.ID, d.UserID, d.BlueprintID, d.Type, deployment.StatusPending,
+		d.ID, d.UserID, d.OrgID, d.BlueprintID, d.Type, deployment.StatusPending,
 	)
 
 	deployementQuery, deploymentArgs, err := deploymentBuilder.ToSql()
@@ -80,8 +100,7 @@ func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deploym
 		return fmt.Errorf("failed to build deployment query: %w", err)
 	}
 
-	// @ TODO - handle unique voilation
-	_, err = tx.Exec(deployementQuery, deploymentArgs...)
+	err = tx.QueryRowContext(ctx, deployementQuery, deploymentArgs...).Scan(&d.Status, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -101,7 +120,7 @@ func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deploym
 		return fmt.Errorf("failed to build deployement host mapping query: %w", err)
 	}
 
-	_, err = tx.Exec(deployementHostMappingQuery, deploymentHostMappingArgs...)
+	_, err = tx.ExecContext(ctx, deployementHostMappingQuery, deploymentHostMappingArgs...)
 	if err != nil {
 		return err
 	}
@@ -111,6 +130,7 @@ func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deploym
 			"DB commit failed for deployemt",
 			"ID", d.ID,
 			"userId", d.UserID,
+			"orgId", d.OrgID,
 			"blueprintId", d.BlueprintID,
 			"type", d.Type,
 			"status", d.Status,
@@ -121,12 +141,15 @@ func (r *deploymentRepository) Create(ctx context.Context, d *deployment.Deploym
 	return err
 }
 
-func (r *deploymentRepository) UpdateStatus(ctx context.Context, id string, updateDeploymentStatusPayload *deployment.UpdateDeploymentStatusPayload) error {
+// UpdateStatus moves a deployment to a new status. It is called by workers, so it is not
+// scoped to an organization, and it refuses transitions out of a finished deployment.
+func (r *deploymentRepository) UpdateStatus(ctx context.Context, id string, updateDeploymentStatusPayload *deployment.UpdateDeploymentStatusPayload) (err error) {
+	defer translateError(&err)
 	builder := sq.
 		Update("deployments").
 		Set("status", updateDeploymentStatusPayload.Status).
 		Set("updated_at", sq.Expr("NOW()")).
-		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"id": id, "status": updateDeploymentStatusPayload.Status.PreviousStatuses()}).
 		Suffix("RETURNING updated_at").
 		PlaceholderFormat(sq.Dollar)
 
@@ -136,6 +159,14 @@ func (r *deploymentRepository) UpdateStatus(ctx context.Context, id string, upda
 	}
 
 	err = r.db.QueryRowContext(ctx, query, args...).Scan(&updateDeploymentStatusPayload.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Tell a missing deployment apart from one in a status it cannot leave
+		var current deployment.DeploymentStatus
+		if err := r.db.GetContext(ctx, &current, getDeploymentStatusQuery, id); err != nil {
+			return err
+		}
+		return apperrors.ErrInvalidStatusTransition
+	}
 	if err != nil {
 		return err
 	}
@@ -143,10 +174,10 @@ func (r *deploymentRepository) UpdateStatus(ctx context.Context, id string, upda
 	return nil
 }
 
-func (r *deploymentRepository) GetByID(ctx context.Context, id string) (*deployment.Deployment, error) {
+func (r *deploymentRepository) GetByID(ctx context.Context, id string, orgId int) (*deployment.Deployment, error) {
 	var d deployment.Deployment
 
-	err := r.db.GetContext(ctx, &d, getDeploymentByIdQuery, id)
+	err := r.db.GetContext(ctx, &d, getDeploymentByIdQuery, id, orgId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // not found
@@ -156,20 +187,32 @@ func (r *deploymentRepository) GetByID(ctx context.Context, id string) (*deploym
 	return &d, nil
 }
 
-func (r *deploymentRepository) GetByUserAndType(ctx context.Context, userId string, dType string) ([]*deployment.Deployment, error) {
-	var deployments []*deployment.Deployment
+func (r *deploymentRepository) GetByOrgAndType(ctx context.Context, f *deployment.Filter, p *pagination.Page) ([]*deployment.Deployment, *pagination.Meta, error) {
+	builder := sq.
+		Select("id", "user_id", "org_id", "blueprint_id", "type", "status", "created_at", "updated_at").
+		From("deployments").
+		Where(sq.Eq{"org_id": f.OrgID, "type::text": string(f.Type)})
 
-	err := r.db.SelectContext(ctx, &deployments, getByUserAndTypeQuery, userId, dType)
-	if err != nil {
-		return nil, err
+	if f.Status != "" {
+		builder = builder.Where(sq.Eq{"status": string(f.Status)})
 	}
-	return deployments, nil
+	if f.BlueprintID != nil {
+		builder = builder.Where(sq.Eq{"blueprint_id": *f.BlueprintID})
+	}
+	if f.From != nil {
+		builder = builder.Where(sq.GtOrEq{"created_at": *f.From})
+	}
+	if f.To != nil {
+		builder = builder.Where(sq.Lt{"created_at": *f.To})
+	}
+
+	return pagination.List(ctx, r.db, builder, p, deploymentSort)
 }
 
-func (r *deploymentRepository) GetByBlueprintID(ctx context.Context, blueprintId int, limit int) ([]*deployment.Deployment, error) {
+func (r *deploymentRepository) GetByBlueprintID(ctx context.Context, blueprintId int, orgId int, limit int) ([]*deployment.Deployment, error) {
 	var deployments []*deployment.Deployment
 
-	err := r.db.SelectContext(ctx, &deployments, getByBlueprintIDQuery, blueprintId, limit)
+	err := r.db.SelectContext(ctx, &deployments, getByBlueprintIDQuery, blueprintId, orgId, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -177,10 +220,27 @@ func (r *deploymentRepository) GetByBlueprintID(ctx context.Context, blueprintId
 	return deployments, nil
 }
 
-func (r *deploymentRepository) GetDeploymentHostMappingByIds(ctx context.Context, ids []string) ([]*deployment.DeploymentHostMapping, error) {
+func (r *deploymentRepository) GetDeploymentHostMappingByIds(ctx context.Context, ids []string, orgId int) ([]*deployment.DeploymentHostMapping, error) {
 	var deploymentHostMapping []*deployment.DeploymentHostMapping
-	if err := r.db.SelectContext(ctx, &deploymentHostMapping, getDeploymentHostMapping, pq.Array(ids)); err != nil {
+	if err := r.db.SelectContext(ctx, &deploymentHostMapping, getDeploymentHostMapping, pq.Array(ids), orgId); err != nil {
 		return nil, err
 	}
 	return deploymentHostMapping, nil
 }
+
+// CountByStatus counts deployments of every organization per status, for metrics
+func (r *deploymentRepository) CountByStatus(ctx context.Context) (map[deployment.DeploymentStatus]int, error) {
+	var rows []struct {
+		Status deployment.DeploymentStatus `db:"status"`
+		Count  int                         `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, countDeploymentsByStatusQuery); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[deployment.DeploymentStatus]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
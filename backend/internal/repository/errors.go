@@ -0,0 +1,9 @@
+package repository
+
+import apperrors "clouding/backend/internal/errors"
+
+// translateError turns constraint violations into typed errors, mutating methods defer it
+// on their named error result
+func translateError(err *error) {
+	*err = apperrors.FromPostgres(*err)
+}
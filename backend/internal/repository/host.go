@@ -2,6 +2,7 @@ package repository
 
 import (
 	"clouding/backend/internal/model/host"
+	"clouding/backend/internal/pagination"
 	"context"
 	"database/sql"
 	_ "embed" // Required for embedding
@@ -16,11 +17,13 @@ import (
 
 // HostRepository defines data access for hosts
 type HostRepository interface {
-	GetHosts(ctx context.Context, id []int) ([]*host.Host, error)
-	GetAllHosts(ctx context.Context, userId string) ([]*host.Host, error)
+	GetHosts(ctx context.Context, ids []int, orgId int) ([]*host.Host, error)
+	GetAllHosts(ctx context.Context, f *host.Filter, p *pagination.Page) ([]*host.Host, *pagination.Meta, error)
 	CreateHost(ctx context.Context, h *host.Host) error
-	UpdateHost(ctx context.Context, h *host.Host) error
-	DeleteHost(ctx context.Context, id int) error
+	// UpdateHost and DeleteHost only apply to the given versions of the host when there are
+	// any, ErrPreconditionFailed is returned if the host changed since
+	UpdateHost(ctx context.Context, h *host.Host, versions []time.Time) error
+	DeleteHost(ctx context.Context, id int, orgId int, versions []time.Time) error
 }
 
 // Queries
@@ -28,15 +31,23 @@ type HostRepository interface {
 //go:embed sql/host/getHostById.sql
 var getHostByIdQuery string
 
-//go:embed sql/host/getHostsByUserId.sql
-var getHostsByUserId string
-
 //go:embed sql/host/createHost.sql
 var createHostQuery string
 
 //go:embed sql/host/deleteHostById.sql
 var deleteHostQuery string
 
+var hostSort = pagination.Sort[*host.Host]{
+	Fields: map[string]pagination.Field[*host.Host]{
+		"id":        intField("id", func(h *host.Host) *int { return h.ID }),
+		"name":      textField("name", func(h *host.Host) *string { return h.Name }),
+		"createdAt": timeField("created_at", func(h *host.Host) *time.Time { return h.CreatedAt }),
+		"updatedAt": timeField("updated_at", func(h *host.Host) *time.Time { return h.UpdatedAt }),
+	},
+	ID:      intField("id", func(h *host.Host) *int { return h.ID }),
+	Default: "id",
+}
+
 type hostRepository struct {
 	db *sqlx.DB
 }
@@ -47,10 +58,10 @@ func NewHostRepository(db *sqlx.DB) HostRepository {
 	}
 }
 
-func (r *hostRepository) GetHosts(ctx context.Context, ids []int) ([]*host.Host, error) {
+func (r *hostRepository) GetHosts(ctx context.Context, ids []int, orgId int) ([]*host.Host, error) {
 	var hosts []*host.Host
 
-	err := r.db.SelectContext(ctx, &hosts, getHostByIdQuery, pq.Array(ids))
+	err := r.db.SelectContext(ctx, &hosts, getHostByIdQuery, pq.Array(ids), orgId)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -62,19 +73,27 @@ func (r *hostRepository) GetHosts(ctx context.Context, ids []int) ([]*host.Host,
 	return hosts, nil
 }
 
-func (r *hostRepository) GetAllHosts(ctx context.Context, userId string) ([]*host.Host, error) {
-	var hosts []*host.Host
-
-	err := r.db.SelectContext(ctx, &hosts, getHostsByUserId, userId)
+func (r *hostRepository) GetAllHosts(ctx context.Context, f *host.Filter, p *pagination.Page) ([]*host.Host, *pagination.Meta, error) {
+	builder := sq.
+		Select("id", "user_id", "org_id", "name", "ip", "os", "credential_id", "meta_data", "created_at", "updated_at").
+		From("hosts").
+		Where(sq.Eq{"org_id": f.OrgID})
 
-	if err != nil {
-		return nil, err
+	if f.Os != "" {
+		builder = builder.Where(sq.Eq{"os": f.Os})
+	}
+	if f.NamePrefix != "" {
+		builder = builder.Where(likePrefix("name", f.NamePrefix))
+	}
+	if f.CredentialID != nil {
+		builder = builder.Where(sq.Eq{"credential_id": *f.CredentialID})
 	}
 
-	return hosts, nil
+	return pagination.List(ctx, r.db, builder, p, hostSort)
 }
 
-func (r *hostRepository) CreateHost(ctx context.Context, h *host.Host) error {
+func (r *hostRepository) CreateHost(ctx context.Context, h *host.Host) (err error) {
+	defer translateError(&err)
 	rows, err := r.db.NamedQueryContext(ctx, createHostQuery, h)
 	if err != nil {
 		return err
@@ -86,12 +105,14 @@ func (r *hostRepository) CreateHost(ctx context.Context, h *host.Host) error {
 
 	return nil
 }
-func (r *hostRepository) UpdateHost(ctx context.Context, h *host.Host) error {
+func (r *hostRepository) UpdateHost(ctx context.Context, h *host.Host, versions []time.Time) (err error) {
+	defer translateError(&err)
 
 	builder := sq.
 		Update("hosts").
-		Set("updated_at", "NOW()").
-		Where(sq.Eq{"id": h.ID}).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": h.ID, "org_id": h.OrgID}).
+		Where(ifMatch(versions)).
 		Suffix("RETURNING updated_at").
 		PlaceholderFormat(sq.Dollar)
 
@@ -118,6 +139,9 @@ func (r *hostRepository) UpdateHost(ctx context.Context, h *host.Host) error {
 
 	var updatedAt time.Time
 	if err := r.db.GetContext(ctx, &updatedAt, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return missedWrite(ctx, r.db, "hosts", *h.ID, *h.OrgID, versions)
+		}
 		return err
 	}
 	h.UpdatedAt = &updatedAt
@@ -125,14 +149,15 @@ func (r *hostRepository) UpdateHost(ctx context.Context, h *host.Host) error {
 	return nil
 }
 
-func (r *hostRepository) DeleteHost(ctx context.Context, id int) error {
-	result, err := r.db.ExecContext(ctx, deleteHostQuery, id)
+func (r *hostRepository) DeleteHost(ctx context.Context, id int, orgId int, versions []time.Time) (err error) {
+	defer translateError(&err)
+	result, err := r.db.ExecContext(ctx, deleteHostQuery, id, orgId, pq.Array(versions))
 	if err != nil {
 		return err
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return sql.ErrNoRows
+		return missedWrite(ctx, r.db, "hosts", id, orgId, versions)
 	}
 	return nil
 }
@@ -2,44 +2,61 @@ package repository
 
 import (
 	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/pagination"
 	"context"
 	"database/sql"
 	_ "embed" // Required for embedding
+	"errors"
+	"log/slog"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 
 	"github.com/jmoiron/sqlx"
 )
 
 // HostRepository defines data access for hosts
 type HostGroupRepository interface {
-	GetAllHostGroups(ctx context.Context, userId string) ([]*hostgroup.HostGroup, error)
-	GetHostGroupByID(ctx context.Context, id int) (*hostgroup.HostGroup, error)
+	GetAllHostGroups(ctx context.Context, f *hostgroup.Filter, p *pagination.Page) ([]*hostgroup.HostGroup, *pagination.Meta, error)
+	GetHostGroupByID(ctx context.Context, id int, orgId int) (*hostgroup.HostGroup, error)
 	CreateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error
-	UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error
-	AddHostsToGroup(ctx context.Context, groupID int, newHosts []int) error
-	RemoveHostFromGroup(ctx context.Context, groupID int, hostID int) error
-	DeleteHostGroup(ctx context.Context, id int) error
+	// UpdateHostGroup and DeleteHostGroup only apply to the given versions of the group
+	// when there are any, ErrPreconditionFailed is returned if the group changed since
+	UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup, versions []time.Time) error
+	AddHostsToGroup(ctx context.Context, groupID int, orgId int, newHosts []int) error
+	RemoveHostFromGroup(ctx context.Context, groupID int, hostID int, orgId int) error
+	DeleteHostGroup(ctx context.Context, id int, orgId int, versions []time.Time) error
 }
 
 // SQL Queries (embed the .sql files)
 
-//go:embed sql/hostGroup/getAllHostGroupByUser.sql
-var getAllHostGroupsQuery string
-
 //go:embed sql/hostGroup/getHostGroupById.sql
 var getHostGroupByIDQuery string
 
 //go:embed sql/hostGroup/createHostGroup.sql
 var createHostGroupQuery string
 
+//go:embed sql/hostGroup/addHostsToGroup.sql
+var addHostsToGroupQuery string
+
 //go:embed sql/hostGroup/removeHostFromGroup.sql
 var removeHostFromGroupQuery string
 
 //go:embed sql/hostGroup/deleteHostGroupById.sql
 var deleteHostGroupQuery string
 
+var hostGroupSort = pagination.Sort[*hostgroup.HostGroup]{
+	Fields: map[string]pagination.Field[*hostgroup.HostGroup]{
+		"id":        intField("hg.id", func(g *hostgroup.HostGroup) *int { return g.ID }),
+		"name":      textField("hg.name", func(g *hostgroup.HostGroup) *string { return g.Name }),
+		"createdAt": timeField("hg.created_at", func(g *hostgroup.HostGroup) *time.Time { return g.CreatedAt }),
+		"updatedAt": timeField("hg.updated_at", func(g *hostgroup.HostGroup) *time.Time { return g.UpdatedAt }),
+	},
+	ID:      intField("hg.id", func(g *hostgroup.HostGroup) *int { return g.ID }),
+	Default: "id",
+}
+
 type hostGroupRepository struct {
 	db *sqlx.DB
 }
@@ -50,23 +67,32 @@ func NewHostGroupRepository(db *sqlx.DB) HostGroupRepository {
 	}
 }
 
-func (r *hostGroupRepository) GetAllHostGroups(ctx context.Context, userId string) ([]*hostgroup.HostGroup, error) {
-	var hostGroups []*hostgroup.HostGroup
-	if err := r.db.SelectContext(ctx, &hostGroups, getAllHostGroupsQuery, userId); err != nil {
-		return nil, err
+func (r *hostGroupRepository) GetAllHostGroups(ctx context.Context, f *hostgroup.Filter, p *pagination.Page) ([]*hostgroup.HostGroup, *pagination.Meta, error) {
+	builder := sq.
+		Select("hg.id", "hg.name", "hg.user_id", "hg.org_id", "hg.description", "hg.created_at", "hg.updated_at",
+			"COALESCE(array_agg(DISTINCT hgm.host_id) FILTER (WHERE hgm.host_id IS NOT NULL), ARRAY[]::bigint[]) AS host_ids").
+		From("host_groups AS hg").
+		LeftJoin("host_groups_to_host_mapping AS hgm ON hgm.host_group_id = hg.id").
+		Where(sq.Eq{"hg.org_id": f.OrgID}).
+		GroupBy("hg.id")
+
+	if f.NamePrefix != "" {
+		builder = builder.Where(likePrefix("hg.name", f.NamePrefix))
 	}
-	return hostGroups, nil
+
+	return pagination.List(ctx, r.db, builder, p, hostGroupSort)
 }
 
-func (r *hostGroupRepository) GetHostGroupByID(ctx context.Context, id int) (*hostgroup.HostGroup, error) {
+func (r *hostGroupRepository) GetHostGroupByID(ctx context.Context, id int, orgId int) (*hostgroup.HostGroup, error) {
 	var hostGroup hostgroup.HostGroup
-	if err := r.db.GetContext(ctx, &hostGroup, getHostGroupByIDQuery, id); err != nil {
+	if err := r.db.GetContext(ctx, &hostGroup, getHostGroupByIDQuery, id, orgId); err != nil {
 		return nil, err
 	}
 	return &hostGroup, nil
 }
 
-func (r *hostGroupRepository) CreateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error {
+func (r *hostGroupRepository) CreateHostGroup(ctx context.Context, h *hostgroup.HostGroup) (err error) {
+	defer translateError(&err)
 	rows, err := r.db.NamedQueryContext(ctx, createHostGroupQuery, h)
 	if err != nil {
 		return err
@@ -87,10 +113,12 @@ func (r *hostGroupRepository) CreateHostGroup(ctx context.Context, h *hostgroup.
 	return nil
 }
 
-func (r *hostGroupRepository) UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error {
+func (r *hostGroupRepository) UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup, versions []time.Time) (err error) {
+	defer translateError(&err)
 	builder := sq.Update("host_groups").
 		Set("updated_at", sq.Expr("NOW()")).
-		Where(sq.Eq{"id": *h.ID}).
+		Where(sq.Eq{"id": *h.ID, "org_id": *h.OrgID}).
+		Where(ifMatch(versions)).
 		Suffix("RETURNING updated_at").
 		PlaceholderFormat(sq.Dollar)
 
@@ -108,6 +136,9 @@ func (r *hostGroupRepository) UpdateHostGroup(ctx context.Context, h *hostgroup.
 
 	var updatedAt time.Time
 	err = r.db.GetContext(ctx, &updatedAt, updateHostGroupQuery, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return missedWrite(ctx, r.db, "host_groups", *h.ID, *h.OrgID, versions)
+	}
 	if err != nil {
 		return err
 	}
@@ -115,32 +146,57 @@ func (r *hostGroupRepository) UpdateHostGroup(ctx context.Context, h *hostgroup.
 	return err
 }
 
-func (r *hostGroupRepository) AddHostsToGroup(ctx context.Context, groupID int, newHosts []int) error {
-
-	builder := sq.Insert("host_groups_to_host_mapping").
-		Columns("host_group_id", "host_id").
-		PlaceholderFormat(sq.Dollar)
-
+func (r *hostGroupRepository) AddHostsToGroup(ctx context.Context, groupID int, orgId int, newHosts []int) (err error) {
+	defer translateError(&err)
+	unique := make(map[int]struct{}, len(newHosts))
+	hostIds := make([]int, 0, len(newHosts))
 	for _, hostId := range newHosts {
-		builder = builder.Values(groupID, hostId)
+		if _, exists := unique[hostId]; !exists {
+			unique[hostId] = struct{}{}
+			hostIds = append(hostIds, hostId)
+		}
+	}
+	if len(hostIds) == 0 {
+		return nil
 	}
 
-	query, args, err := builder.ToSql()
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	_, err = r.db.ExecContext(ctx, query, args...)
+	defer func() {
+		if p := recover(); p != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction after panic", "error", rollbackErr)
+			}
+			panic(p)
+		} else if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
 
-	return err
-}
+	// Only hosts of the same organization as the group are inserted
+	result, err := tx.ExecContext(ctx, addHostsToGroupQuery, groupID, orgId, pq.Array(hostIds))
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != int64(len(hostIds)) {
+		err = sql.ErrNoRows
+		return err
+	}
 
-func (r *hostGroupRepository) RemoveHostFromGroup(ctx context.Context, groupID int, hostID int) error {
-	_, err := r.db.ExecContext(ctx, removeHostFromGroupQuery, hostID, groupID)
-	return err
+	return tx.Commit()
 }
 
-func (r *hostGroupRepository) DeleteHostGroup(ctx context.Context, id int) error {
-	result, err := r.db.ExecContext(ctx, deleteHostGroupQuery, id)
+func (r *hostGroupRepository) RemoveHostFromGroup(ctx context.Context, groupID int, hostID int, orgId int) (err error) {
+	defer translateError(&err)
+	result, err := r.db.ExecContext(ctx, removeHostFromGroupQuery, hostID, groupID, orgId)
 	if err != nil {
 		return err
 	}
@@ -150,3 +206,16 @@ func (r *hostGroupRepository) DeleteHostGroup(ctx context.Context, id int) error
 	}
 	return nil
 }
+
+func (r *hostGroupRepository) DeleteHostGroup(ctx context.Context, id int, orgId int, versions []time.Time) (err error) {
+	defer translateError(&err)
+	result, err := r.db.ExecContext(ctx, deleteHostGroupQuery, id, orgId, pq.Array(versions))
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return missedWrite(ctx, r.db, "host_groups", id, orgId, versions)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"clouding/backend/internal/model/idempotency"
+	"context"
+	"database/sql"
+	_ "embed" // Required for embedding
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyRepository stores the requests sent with an Idempotency-Key and their responses
+type IdempotencyRepository interface {
+	ClaimKey(ctx context.Context, k *idempotency.Key, retention time.Duration, lockTimeout time.Duration) (bool, error)
+	GetKey(ctx context.Context, orgId int, userId string, key string) (*idempotency.Key, error)
+	CompleteKey(ctx context.Context, orgId int, userId string, key string, statusCode int, response []byte) error
+	ReleaseKey(ctx context.Context, orgId int, userId string, key string) error
+	DeleteExpiredKeys(ctx context.Context, retention time.Duration) error
+}
+
+//go:embed sql/idempotency/claimIdempotencyKey.sql
+var claimIdempotencyKeyQuery string
+
+//go:embed sql/idempotency/getIdempotencyKey.sql
+var getIdempotencyKeyQuery string
+
+//go:embed sql/idempotency/completeIdempotencyKey.sql
+var completeIdempotencyKeyQuery string
+
+//go:embed sql/idempotency/releaseIdempotencyKey.sql
+var releaseIdempotencyKeyQuery string
+
+//go:embed sql/idempotency/deleteExpiredIdempotencyKeys.sql
+var deleteExpiredIdempotencyKeysQuery string
+
+type idempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// ClaimKey reports whether the key was free, or could be taken over, and now belongs to
+// the caller's request
+func (r *idempotencyRepository) ClaimKey(ctx context.Context, k *idempotency.Key, retention time.Duration, lockTimeout time.Duration) (bool, error) {
+	err := r.db.QueryRowContext(ctx, claimIdempotencyKeyQuery,
+		k.OrgID, k.UserID, k.Key, k.RequestHash, interval(retention), interval(lockTimeout),
+	).Scan(&k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *idempotencyRepository) GetKey(ctx context.Context, orgId int, userId string, key string) (*idempotency.Key, error) {
+	var k idempotency.Key
+	if err := r.db.GetContext(ctx, &k, getIdempotencyKeyQuery, orgId, userId, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // not found
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *idempotencyRepository) CompleteKey(ctx context.Context, orgId int, userId string, key string, statusCode int, response []byte) error {
+	_, err := r.db.ExecContext(ctx, completeIdempotencyKeyQuery, orgId, userId, key, statusCode, response)
+	return err
+}
+
+func (r *idempotencyRepository) ReleaseKey(ctx context.Context, orgId int, userId string, key string) error {
+	_, err := r.db.ExecContext(ctx, releaseIdempotencyKeyQuery, orgId, userId, key)
+	return err
+}
+
+func (r *idempotencyRepository) DeleteExpiredKeys(ctx context.Context, retention time.Duration) error {
+	_, err := r.db.ExecContext(ctx, deleteExpiredIdempotencyKeysQuery, interval(retention))
+	return err
+}
+
+// interval formats a duration as a Postgres interval
+func interval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int(d.Seconds()))
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"clouding/backend/internal/model/host"
+	hostgroup "clouding/backend/internal/model/hostGroup"
+	"context"
+	_ "embed" // Required for embedding
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// InventoryRepository reads and writes an organization's hosts and host groups at once
+type InventoryRepository interface {
+	GetInventory(ctx context.Context, orgId int) ([]*host.Host, []*hostgroup.HostGroup, error)
+	// ImportInventory creates the groups without an ID and the hosts in one transaction and
+	// adds each host to the group at the same index of hostGroups, if any. IDs are set on
+	// the created groups and hosts.
+	ImportInventory(ctx context.Context, orgId int, groups []*hostgroup.HostGroup, hosts []*host.Host, hostGroups []*hostgroup.HostGroup) error
+}
+
+//go:embed sql/inventory/getInventoryHosts.sql
+var getInventoryHostsQuery string
+
+//go:embed sql/inventory/getInventoryHostGroups.sql
+var getInventoryHostGroupsQuery string
+
+type inventoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewInventoryRepository(db *sqlx.DB) InventoryRepository {
+	return &inventoryRepository{db: db}
+}
+
+func (r *inventoryRepository) GetInventory(ctx context.Context, orgId int) ([]*host.Host, []*hostgroup.HostGroup, error) {
+	var hosts []*host.Host
+	if err := r.db.SelectContext(ctx, &hosts, getInventoryHostsQuery, orgId); err != nil {
+		return nil, nil, err
+	}
+	var groups []*hostgroup.HostGroup
+	if err := r.db.SelectContext(ctx, &groups, getInventoryHostGroupsQuery, orgId); err != nil {
+		return nil, nil, err
+	}
+	return hosts, groups, nil
+}
+
+func (r *inventoryRepository) ImportInventory(ctx context.Context, orgId int, groups []*hostgroup.HostGroup, hosts []*host.Host, hostGroups []*hostgroup.HostGroup) (err error) {
+	defer translateError(&err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction after panic", "error", rollbackErr)
+			}
+			panic(p)
+		} else if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	createGroup, err := tx.PrepareNamedContext(ctx, createHostGroupQuery)
+	if err != nil {
+		return err
+	}
+	defer createGroup.Close()
+	for _, g := range groups {
+		if g.ID != nil {
+			continue
+		}
+		if err = createGroup.QueryRowxContext(ctx, g).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	createHost, err := tx.PrepareNamedContext(ctx, createHostQuery)
+	if err != nil {
+		return err
+	}
+	defer createHost.Close()
+	members := map[int][]int{}
+	var order []int
+	for i, h := range hosts {
+		if err = createHost.QueryRowxContext(ctx, h).Scan(&h.ID); err != nil {
+			return err
+		}
+		if g := hostGroups[i]; g != nil {
+			if _, ok := members[*g.ID]; !ok {
+				order = append(order, *g.ID)
+			}
+			members[*g.ID] = append(members[*g.ID], *h.ID)
+		}
+	}
+
+	for _, groupId := range order {
+		if _, err = tx.ExecContext(ctx, addHostsToGroupQuery, groupId, orgId, pq.Array(members[groupId])); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
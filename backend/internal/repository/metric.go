@@ -9,13 +9,13 @@ import (
 )
 
 type MetricRepository interface {
-	GetOverview(ctx context.Context, userId string) ([]*metric.Overview, error)
+	GetOverview(ctx context.Context, orgId int) ([]*metric.Overview, error)
 }
 
 // SQL Queries (embed the .sql files)
 
 //go:embed sql/metric/overview.sql
-var getOverviewByUserIdQuery string
+var getOverviewByOrgIdQuery string
 
 type metricRepository struct {
 	db *sqlx.DB
@@ -27,9 +27,9 @@ func NewMetricRepository(db *sqlx.DB) MetricRepository {
 	}
 }
 
-func (r *metricRepository) GetOverview(ctx context.Context, userId string) ([]*metric.Overview, error) {
+func (r *metricRepository) GetOverview(ctx context.Context, orgId int) ([]*metric.Overview, error) {
 	var overview []*metric.Overview
-	if err := r.db.SelectContext(ctx, &overview, getOverviewByUserIdQuery, userId); err != nil {
+	if err := r.db.SelectContext(ctx, &overview, getOverviewByOrgIdQuery, orgId); err != nil {
 		return nil, err
 	}
 	return overview, nil
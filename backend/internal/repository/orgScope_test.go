@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// orgTableUse finds the statements that read or write a table holding an organization's resources
+var orgTableUse = regexp.MustCompile(`(?i)\b(from|update|into|join)\s+(credentials|hosts|host_groups|blueprints|deployments)\b`)
+
+// unscopedQueries use those tables without org_id, each for the reason given
+var unscopedQueries = map[string]string{
+	"blueprint/touchBlueprint.sql":            "runs after lockBlueprintById checked the organization in the same transaction",
+	"credential/clearLegacySecretName.sql":    "serves secrets migrate, which moves the secrets of every organization",
+	"credential/isLegacySecretShared.sql":     "looks for other users of a secret before it is deleted, in any organization",
+	"deployment/countDeploymentsByStatus.sql": "feeds the deployment metrics across organizations",
+	"deployment/getDeploymentStatus.sql":      "serves the worker status updates, which have no organization",
+}
+
+// Every query on an organization's resources must be limited to the caller's organization,
+// checked on the SQL itself so that no database is needed
+func TestQueriesAreScopedToOrganization(t *testing.T) {
+	seen := map[string]bool{}
+	err := filepath.WalkDir("sql", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".sql" {
+			return err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(path, "sql"+string(filepath.Separator)))
+		query := string(raw)
+		if !orgTableUse.MatchString(query) {
+			return nil
+		}
+		if reason, ok := unscopedQueries[name]; ok {
+			seen[name] = true
+			if strings.Contains(query, "org_id") {
+				t.Errorf("%s is scoped now, remove it from unscopedQueries (%s)", name, reason)
+			}
+			return nil
+		}
+		if !strings.Contains(query, "org_id") {
+			t.Errorf("%s uses %s without org_id", name, orgTableUse.FindStringSubmatch(query)[2])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range unscopedQueries {
+		if !seen[name] {
+			t.Errorf("%s is listed in unscopedQueries but no longer uses an organization's table", name)
+		}
+	}
+}
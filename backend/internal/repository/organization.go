@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"clouding/backend/internal/model/organization"
+	"context"
+	"database/sql"
+	_ "embed" // Required for embedding
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OrganizationRepository defines data access for organizations and their members
+type OrganizationRepository interface {
+	GetOrganizationsByUserId(ctx context.Context, userId string) ([]*organization.Organization, error)
+	GetPersonalOrganization(ctx context.Context, userId string) (*organization.Organization, error)
+	CreateOrganization(ctx context.Context, org *organization.Organization) error
+	GetMembership(ctx context.Context, orgId int, userId string) (*organization.Membership, error)
+	GetMembers(ctx context.Context, orgId int) ([]*organization.Membership, error)
+	UpsertMembership(ctx context.Context, m *organization.Membership) error
+	DeleteMembership(ctx context.Context, orgId int, userId string) error
+	CountOwners(ctx context.Context, orgId int) (int, error)
+}
+
+// Queries
+
+//go:embed sql/organization/getOrganizationsByUserId.sql
+var getOrganizationsByUserIdQuery string
+
+//go:embed sql/organization/getPersonalOrganization.sql
+var getPersonalOrganizationQuery string
+
+//go:embed sql/organization/createOrganization.sql
+var createOrganizationQuery string
+
+//go:embed sql/organization/getMembership.sql
+var getMembershipQuery string
+
+//go:embed sql/organization/getMembers.sql
+var getMembersQuery string
+
+//go:embed sql/organization/upsertMembership.sql
+var upsertMembershipQuery string
+
+//go:embed sql/organization/deleteMembership.sql
+var deleteMembershipQuery string
+
+//go:embed sql/organization/countOwners.sql
+var countOwnersQuery string
+
+type organizationRepository struct {
+	db *sqlx.DB
+}
+
+func NewOrganizationRepository(db *sqlx.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) GetOrganizationsByUserId(ctx context.Context, userId string) ([]*organization.Organization, error) {
+	var orgs []*organization.Organization
+	if err := r.db.SelectContext(ctx, &orgs, getOrganizationsByUserIdQuery, userId); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (r *organizationRepository) GetPersonalOrganization(ctx context.Context, userId string) (*organization.Organization, error) {
+	var org organization.Organization
+	if err := r.db.GetContext(ctx, &org, getPersonalOrganizationQuery, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// CreateOrganization inserts the organization and makes its creator the owner.
+// A personal organization that already exists is left untouched and org.ID stays nil.
+func (r *organizationRepository) CreateOrganization(ctx context.Context, org *organization.Organization) (err error) {
+	defer translateError(&err)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction after panic", "error", rollbackErr)
+			}
+			panic(p)
+		} else if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareNamedContext(ctx, createOrganizationQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var created struct {
+		ID        int       `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err = stmt.GetContext(ctx, &created, org); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+			return tx.Commit()
+		}
+		return err
+	}
+	org.ID = &created.ID
+	org.CreatedAt = &created.CreatedAt
+	org.UpdatedAt = &created.UpdatedAt
+
+	owner := organization.RoleOwner
+	membership := &organization.Membership{
+		OrganizationID: org.ID,
+		UserID:         org.CreatedBy,
+		Role:           &owner,
+	}
+	membershipStmt, err := tx.PrepareNamedContext(ctx, upsertMembershipQuery)
+	if err != nil {
+		return err
+	}
+	defer membershipStmt.Close()
+
+	var ts struct {
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err = membershipStmt.GetContext(ctx, &ts, membership); err != nil {
+		return err
+	}
+	org.Role = &owner
+
+	return tx.Commit()
+}
+
+func (r *organizationRepository) GetMembership(ctx context.Context, orgId int, userId string) (*organization.Membership, error) {
+	var m organization.Membership
+	if err := r.db.GetContext(ctx, &m, getMembershipQuery, orgId, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *organizationRepository) GetMembers(ctx context.Context, orgId int) ([]*organization.Membership, error) {
+	var members []*organization.Membership
+	if err := r.db.SelectContext(ctx, &members, getMembersQuery, orgId); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *organizationRepository) UpsertMembership(ctx context.Context, m *organization.Membership) (err error) {
+	defer translateError(&err)
+	rows, err := r.db.NamedQueryContext(ctx, upsertMembershipQuery, m)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var createdAt, updatedAt time.Time
+	if rows.Next() {
+		if err := rows.Scan(&createdAt, &updatedAt); err != nil {
+			return err
+		}
+		m.CreatedAt = &createdAt
+		m.UpdatedAt = &updatedAt
+	}
+	return nil
+}
+
+func (r *organizationRepository) DeleteMembership(ctx context.Context, orgId int, userId string) (err error) {
+	defer translateError(&err)
+	result, err := r.db.ExecContext(ctx, deleteMembershipQuery, orgId, userId)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *organizationRepository) CountOwners(ctx context.Context, orgId int) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, countOwnersQuery, orgId); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"clouding/backend/internal/pagination"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePrefix matches column values starting with prefix, ignoring case. Wildcards in
+// prefix are matched literally.
+func likePrefix(column string, prefix string) sq.ILike {
+	return sq.ILike{column: likeEscaper.Replace(prefix) + "%"}
+}
+
+// Sortable fields of the list endpoints, value reads the field from a row for the next cursor
+
+func intField[T any](column string, value func(T) *int) pagination.Field[T] {
+	return pagination.Field[T]{Column: column, Cast: "int", Value: func(item T) *string {
+		if v := value(item); v != nil {
+			s := strconv.Itoa(*v)
+			return &s
+		}
+		return nil
+	}}
+}
+
+func textField[T any](column string, value func(T) *string) pagination.Field[T] {
+	return pagination.Field[T]{Column: column, Cast: "text", Value: value}
+}
+
+func timeField[T any](column string, value func(T) *time.Time) pagination.Field[T] {
+	return pagination.Field[T]{Column: column, Cast: "timestamptz", Value: func(item T) *string {
+		return pagination.TimeValue(value(item))
+	}}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// ifMatch limits an update or delete to the versions of the row the client read, the
+// check is part of the write so that two clients can't both pass it. No versions match
+// any row.
+func ifMatch(versions []time.Time) sq.Sqlizer {
+	if versions == nil {
+		return sq.And{}
+	}
+	return sq.Eq{"updated_at": versions}
+}
+
+// missedWrite explains why a write limited by ifMatch matched no row, the row is
+// either gone or was changed since the client read it
+func missedWrite(ctx context.Context, q sqlx.QueryerContext, table string, id int, orgId int, versions []time.Time) error {
+	if versions == nil {
+		return sql.ErrNoRows
+	}
+	query, args, err := sq.Select("1").
+		Prefix("SELECT EXISTS (").
+		From(table).
+		Where(sq.Eq{"id": id, "org_id": orgId}).
+		Suffix(")").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	var exists bool
+	if err := sqlx.GetContext(ctx, q, &exists, query, args...); err != nil {
+		return err
+	}
+	if exists {
+		return apperrors.ErrPreconditionFailed
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	_ "embed" // Required for embedding
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RateLimitRepository stores token buckets shared by every API instance
+type RateLimitRepository interface {
+	TakeToken(ctx context.Context, key string, burst int, ratePerSecond float64) (float64, bool, error)
+	DeleteIdleBuckets(ctx context.Context, idleFor time.Duration) error
+}
+
+//go:embed sql/rateLimit/takeToken.sql
+var takeTokenQuery string
+
+//go:embed sql/rateLimit/deleteIdleBuckets.sql
+var deleteIdleBucketsQuery string
+
+type rateLimitRepository struct {
+	db *sqlx.DB
+}
+
+func NewRateLimitRepository(db *sqlx.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+// TakeToken returns the tokens left in the bucket and whether a token was taken
+func (r *rateLimitRepository) TakeToken(ctx context.Context, key string, burst int, ratePerSecond float64) (float64, bool, error) {
+	var tokens float64
+	var allowed bool
+	if err := r.db.QueryRowContext(ctx, takeTokenQuery, key, burst, ratePerSecond).Scan(&tokens, &allowed); err != nil {
+		return 0, false, err
+	}
+	return tokens, allowed, nil
+}
+
+func (r *rateLimitRepository) DeleteIdleBuckets(ctx context.Context, idleFor time.Duration) error {
+	_, err := r.db.ExecContext(ctx, deleteIdleBucketsQuery, interval(idleFor))
+	return err
+}
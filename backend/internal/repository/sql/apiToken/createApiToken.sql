@@ -0,0 +1,3 @@
+INSERT INTO api_tokens (user_id, org_id, name, token_prefix, token_hash, scopes, expires_at, created_at, updated_at)
+VALUES (:user_id, :org_id, :name, :token_prefix, :token_hash, :scopes, :expires_at, NOW(), NOW())
+RETURNING id, created_at, updated_at;
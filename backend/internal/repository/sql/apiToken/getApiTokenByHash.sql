@@ -0,0 +1,3 @@
+SELECT id, user_id, org_id, name, token_prefix, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at, updated_at
+FROM api_tokens
+WHERE token_hash = $1;
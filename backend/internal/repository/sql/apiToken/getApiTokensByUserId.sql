@@ -0,0 +1,4 @@
+SELECT id, user_id, org_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at, updated_at
+FROM api_tokens
+WHERE user_id = $1 AND org_id = $2
+ORDER BY created_at DESC;
@@ -0,0 +1,4 @@
+-- Only write once a minute so busy pipelines don't update the row on every request
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
@@ -0,0 +1,2 @@
+INSERT INTO audit_logs (org_id, actor_id, actor_type, action, resource_type, resource_id, request_id, source_ip, status_code, changes)
+VALUES (:org_id, :actor_id, :actor_type, :action, :resource_type, :resource_id, :request_id, :source_ip, :status_code, :changes);
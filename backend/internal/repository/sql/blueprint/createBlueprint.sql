@@ -1,3 +1,3 @@
-INSERT INTO blueprints (name, description, user_id, status, created_at, updated_at)
-VALUES (:name, :description, :user_id, :status, NOW(), NOW())
+INSERT INTO blueprints (name, description, user_id, org_id, status, created_at, updated_at)
+VALUES (:name, :description, :user_id, :org_id, :status, NOW(), NOW())
 RETURNING id; 
\ No newline at end of file
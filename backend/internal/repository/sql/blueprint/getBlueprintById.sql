@@ -1 +1 @@
-SELECT * FROM blueprints WHERE id = $1; 
\ No newline at end of file
+SELECT * FROM blueprints WHERE id = $1 AND org_id = $2; 
\ No newline at end of file
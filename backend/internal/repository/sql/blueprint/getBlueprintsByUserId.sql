@@ -1 +0,0 @@
-SELECT id, name, description, user_id, status, created_at, updated_at FROM blueprints WHERE user_id = $1; 
\ No newline at end of file
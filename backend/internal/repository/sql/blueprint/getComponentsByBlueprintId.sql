@@ -1,3 +1,4 @@
-SELECT id, blueprint_id, component_id, position, parameters, created_at, updated_at 
-FROM blueprint_components 
-WHERE blueprint_id = $1; 
\ No newline at end of file
+SELECT bc.id, bc.blueprint_id, bc.component_id, bc.position, bc.parameters, bc.created_at, bc.updated_at 
+FROM blueprint_components AS bc
+JOIN blueprints AS b ON b.id = bc.blueprint_id
+WHERE bc.blueprint_id = $1 AND b.org_id = $2; 
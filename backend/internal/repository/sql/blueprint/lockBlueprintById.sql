@@ -0,0 +1,4 @@
+SELECT id FROM blueprints
+WHERE id = $1 AND org_id = $2
+  AND ($3::timestamptz[] IS NULL OR updated_at = ANY($3))
+FOR UPDATE;
@@ -0,0 +1,3 @@
+UPDATE blueprints SET updated_at = NOW()
+WHERE id = $1
+RETURNING updated_at;
@@ -4,5 +4,6 @@ SET
   description = COALESCE(:description, description),
   status = COALESCE(:status, status),
   updated_at = NOW()
-WHERE id = :id
-RETURNING updated_at; 
\ No newline at end of file
+WHERE id = :id AND org_id = :org_id
+  AND (CAST(:if_match AS timestamptz[]) IS NULL OR updated_at = ANY(CAST(:if_match AS timestamptz[])))
+RETURNING updated_at; 
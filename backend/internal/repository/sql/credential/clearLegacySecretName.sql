@@ -0,0 +1,2 @@
+-- clearLegacySecretName.sql
+UPDATE credentials SET legacy_secret_name = NULL WHERE id = $1;
@@ -1,4 +1,4 @@
 -- createCredential.sql
-INSERT INTO credentials (name, type, user_id, expires_at) 
-VALUES (:name, :type, :user_id, :expires_at) 
+INSERT INTO credentials (name, type, user_id, org_id, expires_at) 
+VALUES (:name, :type, :user_id, :org_id, :expires_at) 
 RETURNING id; 
\ No newline at end of file
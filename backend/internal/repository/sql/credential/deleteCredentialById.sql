@@ -1 +1,3 @@
-DELETE FROM credentials WHERE id = $1; 
\ No newline at end of file
+DELETE FROM credentials
+WHERE id = $1 AND org_id = $2
+  AND ($3::timestamptz[] IS NULL OR updated_at = ANY($3));
@@ -0,0 +1,2 @@
+-- getAllCredentials.sql
+SELECT id, name, type, user_id, org_id, expires_at, created_at, updated_at, legacy_secret_name FROM credentials ORDER BY id;
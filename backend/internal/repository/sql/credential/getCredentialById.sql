@@ -1 +1 @@
-SELECT id, name, type, user_id, expires_at, created_at, updated_at FROM credentials WHERE id = $1; 
\ No newline at end of file
+SELECT id, name, type, user_id, org_id, expires_at, created_at, updated_at, legacy_secret_name FROM credentials WHERE id = $1 AND org_id = $2; 
\ No newline at end of file
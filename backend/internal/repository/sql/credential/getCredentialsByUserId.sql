@@ -1 +0,0 @@
-SELECT id, name, type, user_id, expires_at, created_at, updated_at FROM credentials WHERE user_id = $1; 
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- isLegacySecretShared.sql
+SELECT EXISTS (SELECT 1 FROM credentials WHERE legacy_secret_name = $1 AND id <> $2);
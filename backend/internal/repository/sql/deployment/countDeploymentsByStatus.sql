@@ -0,0 +1,2 @@
+-- countDeploymentsByStatus.sql
+SELECT status, COUNT(*) AS count FROM deployments GROUP BY status;
@@ -1,4 +1,5 @@
 SELECT 
-  id, user_id, blueprint_id, type, status, created_at, updated_at
+  id, user_id, org_id, blueprint_id, type, status, created_at, updated_at
 FROM deployments
-WHERE id = $1;
+WHERE id = $1
+  AND org_id = $2;
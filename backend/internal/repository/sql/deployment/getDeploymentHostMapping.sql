@@ -1,2 +1,5 @@
-SELECT deployment_id, host_id, status from deployment_host_mappings
-WHERE deployment_id = ANY($1); 
\ No newline at end of file
+SELECT dhm.deployment_id, dhm.host_id, dhm.status
+FROM deployment_host_mappings AS dhm
+JOIN deployments AS d ON d.id = dhm.deployment_id
+WHERE dhm.deployment_id = ANY($1)
+  AND d.org_id = $2; 
@@ -0,0 +1 @@
+SELECT status FROM deployments WHERE id = $1;
@@ -1,6 +1,7 @@
 SELECT
   id,
   user_id,
+  org_id,
   blueprint_id,
   type,
   status,
@@ -10,7 +11,8 @@ FROM
   deployments
 WHERE
   blueprint_id = $1
+  AND org_id = $2
 ORDER BY
   created_at DESC
 LIMIT
-  $2
\ No newline at end of file
+  $3
\ No newline at end of file
@@ -1,4 +1,4 @@
 -- createHost.sql
-INSERT INTO hosts (user_id, name, os, ip, meta_data, credential_id, created_at, updated_at)
-VALUES (:user_id, :name, :os, :ip, :meta_data, :credential_id, NOW(), NOW())
+INSERT INTO hosts (user_id, org_id, name, os, ip, meta_data, credential_id, created_at, updated_at)
+VALUES (:user_id, :org_id, :name, :os, :ip, :meta_data, :credential_id, NOW(), NOW())
 RETURNING id;
\ No newline at end of file
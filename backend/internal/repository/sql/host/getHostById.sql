@@ -1,2 +1,2 @@
 -- getHostById.sql
-SELECT id, user_id, name, ip, os, credential_id, meta_data, created_at, updated_at FROM hosts WHERE id = ANY($1);
\ No newline at end of file
+SELECT id, user_id, org_id, name, ip, os, credential_id, meta_data, created_at, updated_at FROM hosts WHERE id = ANY($1) AND org_id = $2;
\ No newline at end of file
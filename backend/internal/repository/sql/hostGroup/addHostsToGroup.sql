@@ -0,0 +1,7 @@
+INSERT INTO host_groups_to_host_mapping (host_group_id, host_id)
+SELECT hg.id, h.id
+FROM host_groups AS hg
+JOIN hosts AS h ON h.org_id = hg.org_id
+WHERE hg.id = $1
+  AND hg.org_id = $2
+  AND h.id = ANY($3);
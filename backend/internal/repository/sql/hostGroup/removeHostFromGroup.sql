@@ -1,2 +1,6 @@
-DELETE FROM host_groups_to_host_mapping
-WHERE host_id=$1 AND host_group_id=$2;
\ No newline at end of file
+DELETE FROM host_groups_to_host_mapping AS hgm
+USING host_groups AS hg
+WHERE hgm.host_group_id = hg.id
+  AND hgm.host_id = $1
+  AND hgm.host_group_id = $2
+  AND hg.org_id = $3;
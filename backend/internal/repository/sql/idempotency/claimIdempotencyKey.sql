@@ -0,0 +1,12 @@
+-- Claims a key for a request. A key whose response expired, or whose request never finished
+-- within the lock timeout, is taken over. No row is returned while the key is held.
+INSERT INTO idempotency_keys AS k (org_id, user_id, key, request_hash)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (org_id, user_id, key) DO UPDATE SET
+    request_hash = EXCLUDED.request_hash,
+    status_code = NULL,
+    response = NULL,
+    created_at = NOW()
+WHERE k.created_at < NOW() - $5::interval
+   OR (k.status_code IS NULL AND k.created_at < NOW() - $6::interval)
+RETURNING created_at;
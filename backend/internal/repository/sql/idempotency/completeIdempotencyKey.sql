@@ -0,0 +1,3 @@
+UPDATE idempotency_keys
+SET status_code = $4, response = $5
+WHERE org_id = $1 AND user_id = $2 AND key = $3 AND status_code IS NULL;
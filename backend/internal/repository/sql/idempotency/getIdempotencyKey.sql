@@ -0,0 +1,6 @@
+SELECT
+  org_id, user_id, key, request_hash, status_code, response, created_at
+FROM idempotency_keys
+WHERE org_id = $1
+  AND user_id = $2
+  AND key = $3;
@@ -0,0 +1,3 @@
+-- Frees a key whose request failed so that it can be retried, stored responses are kept
+DELETE FROM idempotency_keys
+WHERE org_id = $1 AND user_id = $2 AND key = $3 AND status_code IS NULL;
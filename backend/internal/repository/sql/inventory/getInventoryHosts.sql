@@ -0,0 +1,4 @@
+SELECT id, user_id, org_id, name, ip, os, credential_id, meta_data, created_at, updated_at
+FROM hosts
+WHERE org_id = $1
+ORDER BY id;
@@ -15,7 +15,7 @@ hosts_agg AS (
     ) AS added_prev
   FROM hosts h
   CROSS JOIN bounds b
-  WHERE h.user_id = $1
+  WHERE h.org_id = $1
 ),
 groups_agg AS (
   SELECT
@@ -28,7 +28,7 @@ groups_agg AS (
     ) AS added_prev
   FROM host_groups g
   CROSS JOIN bounds b
-  WHERE g.user_id = $1
+  WHERE g.org_id = $1
 ),
 creds_agg AS (
   SELECT
@@ -41,7 +41,7 @@ creds_agg AS (
     ) AS added_prev
   FROM credentials c
   CROSS JOIN bounds b
-  WHERE c.user_id = $1
+  WHERE c.org_id = $1
 ),
 blueprints_agg AS (
   SELECT
@@ -54,7 +54,7 @@ blueprints_agg AS (
     ) AS added_prev
   FROM blueprints bp
   CROSS JOIN bounds b
-  WHERE bp.user_id = $1
+  WHERE bp.org_id = $1
 ),
 deployments_agg AS (
   SELECT
@@ -67,7 +67,7 @@ deployments_agg AS (
     ) AS added_prev
   FROM deployments dp
   CROSS JOIN bounds b
-  WHERE dp.user_id = $1
+  WHERE dp.org_id = $1
 )
 SELECT 'vms'         AS entity, ha.added_curr AS currentMonth, ha.added_prev AS lastMonth, ha.total AS total
 FROM hosts_agg ha
@@ -0,0 +1 @@
+SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner';
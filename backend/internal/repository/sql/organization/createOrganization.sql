@@ -0,0 +1,4 @@
+INSERT INTO organizations (name, personal, created_by, created_at, updated_at)
+VALUES (:name, :personal, :created_by, NOW(), NOW())
+ON CONFLICT DO NOTHING
+RETURNING id, created_at, updated_at;
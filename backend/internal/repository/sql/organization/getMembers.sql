@@ -0,0 +1,4 @@
+SELECT organization_id, user_id, role, created_at, updated_at
+FROM organization_members
+WHERE organization_id = $1
+ORDER BY created_at;
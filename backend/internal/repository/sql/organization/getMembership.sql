@@ -0,0 +1,3 @@
+SELECT organization_id, user_id, role, created_at, updated_at
+FROM organization_members
+WHERE organization_id = $1 AND user_id = $2;
@@ -0,0 +1,5 @@
+SELECT o.id, o.name, o.personal, o.created_by, m.role, o.created_at, o.updated_at
+FROM organizations AS o
+JOIN organization_members AS m ON m.organization_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.personal DESC, o.created_at;
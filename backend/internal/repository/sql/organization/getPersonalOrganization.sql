@@ -0,0 +1,4 @@
+SELECT o.id, o.name, o.personal, o.created_by, m.role, o.created_at, o.updated_at
+FROM organizations AS o
+JOIN organization_members AS m ON m.organization_id = o.id AND m.user_id = o.created_by
+WHERE o.created_by = $1 AND o.personal;
@@ -0,0 +1,4 @@
+INSERT INTO organization_members (organization_id, user_id, role, created_at, updated_at)
+VALUES (:organization_id, :user_id, :role, NOW(), NOW())
+ON CONFLICT (organization_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = NOW()
+RETURNING created_at, updated_at;
@@ -0,0 +1 @@
+DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - $1::interval;
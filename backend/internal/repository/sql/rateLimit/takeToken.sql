@@ -0,0 +1,10 @@
+-- Refills the bucket for the time since it was last used and takes one token if available.
+-- The conflict branch runs on the locked row, so concurrent instances never over-admit.
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES ($1, $2::float8 - 1, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    allowed = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * $3::float8) >= 1,
+    tokens = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * $3::float8)
+        - CASE WHEN LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * $3::float8) >= 1 THEN 1 ELSE 0 END,
+    updated_at = NOW()
+RETURNING tokens, allowed;
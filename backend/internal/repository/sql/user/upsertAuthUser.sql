@@ -0,0 +1,12 @@
+-- upsertAuthUser.sql
+INSERT INTO auth.users (id, raw_user_meta_data, created_at, updated_at)
+VALUES (
+  COALESCE(NULLIF(:id, '')::uuid, gen_random_uuid()),
+  jsonb_strip_nulls(jsonb_build_object('name', :name::text, 'email', :email::text)),
+  NOW(),
+  NOW()
+)
+ON CONFLICT (id) DO UPDATE
+SET raw_user_meta_data = COALESCE(auth.users.raw_user_meta_data, '{}'::jsonb) || EXCLUDED.raw_user_meta_data,
+    updated_at = NOW()
+RETURNING id, created_at, updated_at;
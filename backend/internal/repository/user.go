@@ -19,6 +19,7 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, u *user.User) error
 	UpdateUser(ctx context.Context, u *user.User) error
 	DeleteUser(ctx context.Context, id string) error
+	UpsertAuthUser(ctx context.Context, u *user.User) error
 }
 
 // Queries
@@ -32,6 +33,9 @@ var createUserQuery string
 //go:embed sql/user/deleteUserById.sql
 var deleteUserQuery string
 
+//go:embed sql/user/upsertAuthUser.sql
+var upsertAuthUserQuery string
+
 type userRepository struct {
 	db *sqlx.DB
 }
@@ -57,7 +61,8 @@ func (r *userRepository) GetUser(ctx context.Context, id string) (*user.User, er
 	return &userObj, nil
 }
 
-func (r *userRepository) CreateUser(ctx context.Context, u *user.User) error {
+func (r *userRepository) CreateUser(ctx context.Context, u *user.User) (err error) {
+	defer translateError(&err)
 	rows, err := r.db.NamedQueryContext(ctx, createUserQuery, u)
 	if err != nil {
 		return err
@@ -70,7 +75,8 @@ func (r *userRepository) CreateUser(ctx context.Context, u *user.User) error {
 	return nil
 }
 
-func (r *userRepository) UpdateUser(ctx context.Context, u *user.User) error {
+func (r *userRepository) UpdateUser(ctx context.Context, u *user.User) (err error) {
+	defer translateError(&err)
 	builder := sq.
 		Update("users").
 		Set("updated_at", "NOW()").
@@ -100,7 +106,8 @@ func (r *userRepository) UpdateUser(ctx context.Context, u *user.User) error {
 	return nil
 }
 
-func (r *userRepository) DeleteUser(ctx context.Context, id string) error {
+func (r *userRepository) DeleteUser(ctx context.Context, id string) (err error) {
+	defer translateError(&err)
 	result, err := r.db.ExecContext(ctx, deleteUserQuery, id)
 	if err != nil {
 		return err
@@ -111,3 +118,30 @@ func (r *userRepository) DeleteUser(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+// UpsertAuthUser creates the account in auth.users, generating an id when u.ID is empty,
+// or merges the name and email into an existing one
+func (r *userRepository) UpsertAuthUser(ctx context.Context, u *user.User) (err error) {
+	defer translateError(&err)
+	id := ""
+	if u.ID != nil {
+		id = *u.ID
+	}
+	args := map[string]interface{}{"id": id, "name": u.Name, "email": u.Email}
+
+	rows, err := r.db.NamedQueryContext(ctx, upsertAuthUserQuery, args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&id, &createdAt, &updatedAt); err != nil {
+			return err
+		}
+		u.ID = &id
+		u.CreatedAt = &createdAt
+		u.UpdatedAt = &updatedAt
+	}
+	return rows.Err()
+}
@@ -1,21 +1,76 @@
 package router
 
 import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/queue"
+	"clouding/backend/internal/repository"
 	v1 "clouding/backend/internal/router/v1"
+	"clouding/backend/internal/service"
+	"clouding/backend/internal/utils/rateLimiter"
+	secretmanager "clouding/backend/internal/utils/secretManager"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 )
 
-func SetupRouter(ginRouteGroup *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher) {
-	v1.RegisterHostRoutes(ginRouteGroup, db)
-	v1.RegisterHostGroupRoutes(ginRouteGroup, db)
-	v1.RegisterUserRoutes(ginRouteGroup, db)
-	v1.RegisterCredentialRoutes(ginRouteGroup, db)
-	v1.RegisterComponentRoutes(ginRouteGroup, db)
-	v1.RegisterBlueprintRoutes(ginRouteGroup, db)
-	v1.RegisterDeploymentRoutes(ginRouteGroup, db, publisher)
-	v1.RegisterMetricRoutes(ginRouteGroup, db)
+// SetupRouter registers the user facing API, streams and background work are tied to lc
+func SetupRouter(ginRouteGroup *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher, secretsManager secretmanager.SecretsManager, lc *lifecycle.Lifecycle) {
+	limiter := rateLimiter.NewLimiterFromConfig(db, lc)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	// Rate limiting runs first so that rejected requests don't flood the audit log. Routes with
+	// their own rule are charged to it alone, see middleware.RateLimitGroup.
+	auditedRouteGroup := ginRouteGroup.Group("",
+		middleware.RateLimit(limiter, rateLimiter.DefaultGroup),
+		middleware.AuditMiddleware(auditService),
+	)
 
+	// Routes that are not scoped to a single organization
+	v1.RegisterUserRoutes(auditedRouteGroup, db)
+	v1.RegisterOrganizationRoutes(auditedRouteGroup, db)
+	v1.RegisterComponentRoutes(auditedRouteGroup, db)
+
+	orgService := service.NewOrganizationService(repository.NewOrganizationRepository(db))
+	orgRouteGroup := auditedRouteGroup.Group("", middleware.OrgMiddleware(orgService))
+	idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(db), config.Config.Idempotency.Retention, lc)
+
+	v1.RegisterHostRoutes(orgRouteGroup, db, secretsManager)
+	v1.RegisterHostGroupRoutes(orgRouteGroup, db)
+	v1.RegisterInventoryRoutes(orgRouteGroup, db, secretsManager, idempotencyService)
+	v1.RegisterCredentialRoutes(orgRouteGroup, db, secretsManager, idempotencyService)
+	v1.RegisterBlueprintRoutes(orgRouteGroup, db)
+	v1.RegisterDeploymentRoutes(orgRouteGroup, db, publisher, secretsManager, idempotencyService, lc)
+	v1.RegisterMetricRoutes(orgRouteGroup, db)
+	v1.RegisterApiTokenRoutes(orgRouteGroup, db)
+	v1.RegisterAuditRoutes(orgRouteGroup, db)
+
+}
+
+// SetupInternalRouter registers routes called by deployment workers rather than users
+func SetupInternalRouter(ginRouteGroup *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher) {
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	auditedRouteGroup := ginRouteGroup.Group("", middleware.AuditMiddleware(auditService))
+
+	v1.RegisterWorkerDeploymentRoutes(auditedRouteGroup, db, publisher)
+}
+
+// SetupHealthRouter registers the unauthenticated liveness and readiness probes
+func SetupHealthRouter(ginRouteGroup *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher, secretsManager secretmanager.SecretsManager) {
+	v1.RegisterHealthRoutes(ginRouteGroup, db, publisher, secretsManager)
+}
+
+// SetupOpenAPIRouter serves the OpenAPI document of the routes registered on the engine
+func SetupOpenAPIRouter(ginRouteGroup *gin.RouterGroup, spec *openapi.Spec) {
+	v1.RegisterOpenAPIRoutes(ginRouteGroup, spec)
+}
+
+// SetupMetricsRouter exposes Prometheus metrics, unauthenticated like the probes so
+// that scrapers don't need a user token
+func SetupMetricsRouter(ginRouteGroup *gin.RouterGroup, db *sqlx.DB) {
+	if !config.Config.Metrics.Enabled {
+		return
+	}
+	v1.RegisterPrometheusRoutes(ginRouteGroup, db, config.Config.Metrics.Path)
 }
@@ -0,0 +1,381 @@
+package router
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/database"
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/queue"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const testJwtSecret = "router-test-secret"
+
+// memorySecrets stands in for Vault, no route below reaches a secret of the other tenant
+type memorySecrets struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]interface{}
+}
+
+func (m *memorySecrets) GetSecret(ctx context.Context, secretName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.secrets[secretName]; !ok {
+		return "", fmt.Errorf("secret %s not found", secretName)
+	}
+	return "{}", nil
+}
+
+func (m *memorySecrets) SetSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[secretName] = secretMap
+	return nil
+}
+
+func (m *memorySecrets) UpdateSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) error {
+	return m.SetSecret(ctx, secretName, secretMap)
+}
+
+func (m *memorySecrets) DeleteSecret(ctx context.Context, secretName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets, secretName)
+	return nil
+}
+
+func (m *memorySecrets) Ping(ctx context.Context) error {
+	return nil
+}
+
+// tenant is what the test seeds for one user in their personal organization, every
+// name starts with marker so that a response leaking it can be recognized
+type tenant struct {
+	userID       string
+	orgID        int
+	credentialID int
+	hostID       int
+	hostGroupID  int
+	blueprintID  int
+	deploymentID string
+	apiTokenID   int
+	marker       string
+}
+
+// testDatabase connects to TEST_DATABASE_URL and migrates it, the tests that need a
+// database are skipped without one
+func testDatabase(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	flags := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	args := []string{
+		"--env-file=" + t.TempDir() + "/.env",
+		"--sql.dsn=" + dsn,
+		"--supabaseAuth.jwtSecret=" + testJwtSecret,
+		"--rateLimit.enabled=false",
+		"--metrics.enabled=false",
+	}
+	if err := config.LoadCloudingConfig(flags, args, config.SectionDatabase); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	db, err := database.NewSqlDatabase(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// seedTenant creates a user with one resource of every kind, componentID is added to
+// the blueprint
+func seedTenant(t *testing.T, db *sqlx.DB, componentID int) *tenant {
+	t.Helper()
+	ctx := context.Background()
+	tn := &tenant{userID: uuid.NewString()}
+	tn.marker = "tenant-" + tn.userID[:8]
+	name := func(kind string) string { return tn.marker + "-" + kind }
+
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	insert := func(dest any, query string, args ...any) {
+		t.Helper()
+		if err := db.GetContext(ctx, dest, query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+
+	exec(`INSERT INTO auth.users (id, raw_user_meta_data) VALUES ($1, jsonb_build_object('name', $2::text))`, tn.userID, name("user"))
+	t.Cleanup(func() {
+		db.ExecContext(context.Background(), `DELETE FROM auth.users WHERE id = $1`, tn.userID)
+	})
+	insert(&tn.orgID, `INSERT INTO organizations (name, personal, created_by) VALUES ($1, TRUE, $2) RETURNING id`, name("org"), tn.userID)
+	exec(`INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, 'owner')`, tn.orgID, tn.userID)
+
+	insert(&tn.credentialID, `INSERT INTO credentials (name, type, user_id, org_id) VALUES ($1, 'ssh_key', $2, $3) RETURNING id`, name("credential"), tn.userID, tn.orgID)
+	insert(&tn.hostID, `INSERT INTO hosts (name, ip, os, credential_id, user_id, org_id) VALUES ($1, '10.0.0.1', 'ubuntu', $2, $3, $4) RETURNING id`, name("host"), tn.credentialID, tn.userID, tn.orgID)
+	insert(&tn.hostGroupID, `INSERT INTO host_groups (name, user_id, org_id) VALUES ($1, $2, $3) RETURNING id`, name("hostgroup"), tn.userID, tn.orgID)
+	exec(`INSERT INTO host_groups_to_host_mapping (host_group_id, host_id) VALUES ($1, $2)`, tn.hostGroupID, tn.hostID)
+
+	insert(&tn.blueprintID, `INSERT INTO blueprints (name, user_id, org_id) VALUES ($1, $2, $3) RETURNING id`, name("blueprint"), tn.userID, tn.orgID)
+	exec(`INSERT INTO blueprint_components (blueprint_id, component_id, position, parameters) VALUES ($1, $2, 0, $3)`, tn.blueprintID, componentID, `{"marker": "`+name("parameter")+`"}`)
+
+	tn.deploymentID = uuid.NewString()
+	exec(`INSERT INTO deployments (id, user_id, org_id, blueprint_id, type, status) VALUES ($1, $2, $3, $4, 'deploy', 'completed')`, tn.deploymentID, tn.userID, tn.orgID, tn.blueprintID)
+	exec(`INSERT INTO deployment_host_mappings (deployment_id, host_id, status) VALUES ($1, $2, 'completed')`, tn.deploymentID, tn.hostID)
+
+	insert(&tn.apiTokenID, `INSERT INTO api_tokens (user_id, org_id, name, token_prefix, token_hash) VALUES ($1, $2, $3, 'cld_test', $4) RETURNING id`, tn.userID, tn.orgID, name("token"), tn.userID)
+	exec(`INSERT INTO audit_logs (org_id, actor_id, actor_type, action, resource_type, resource_id) VALUES ($1, $2, 'user', 'create', 'host', $3)`, tn.orgID, tn.userID, name("audit"))
+	return tn
+}
+
+func seedComponent(t *testing.T, db *sqlx.DB) int {
+	t.Helper()
+	var id int
+	name := "router-test-" + uuid.NewString()
+	if err := db.GetContext(context.Background(), &id, `INSERT INTO components (name, display_name, label) VALUES ($1, $1, 'test') RETURNING id`, name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.ExecContext(context.Background(), `DELETE FROM components WHERE id = $1`, id)
+	})
+	return id
+}
+
+// newTestEngine serves SetupRouter under /api/v1 behind the middlewares main.go puts in front of it
+func newTestEngine(t *testing.T, db *sqlx.DB) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(middleware.RequestIDMiddleware())
+	engine.Use(middleware.ErrorMiddleware())
+
+	// Nothing is published, the publisher only connects on first use
+	publisher := queue.NewPublisher("localhost", "5672", "guest", "guest", "router-test")
+	t.Cleanup(func() { publisher.Close() })
+	lc := lifecycle.New()
+	t.Cleanup(lc.Drain)
+
+	apiTokenService := service.NewApiTokenService(repository.NewApiTokenRepository(db))
+	v1RouteGroup := engine.Group("/api/v1", middleware.JWTAuthMiddleware(apiTokenService))
+	SetupRouter(v1RouteGroup, db, publisher, &memorySecrets{secrets: map[string]map[string]interface{}{}}, lc)
+	return engine
+}
+
+func sessionToken(t *testing.T, userID string) string {
+	t.Helper()
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": userID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	s, err := token.SignedString([]byte(testJwtSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestRoutesHideOtherOrganizations requests every route of SetupRouter as one user for
+// the resources of another, whether by id, through a reference in the body or by
+// naming the other organization, none of them may be found
+func TestRoutesHideOtherOrganizations(t *testing.T) {
+	db := testDatabase(t)
+	componentID := seedComponent(t, db)
+	a := seedTenant(t, db, componentID)
+	b := seedTenant(t, db, componentID)
+	engine := newTestEngine(t, db)
+
+	id := strconv.Itoa
+	tests := []struct {
+		// route is the method and path as registered
+		route string
+		path  string
+		body  string
+		// status is what b gets, contains must be in the response
+		status   int
+		contains string
+		// orgScoped routes act on the organization in X-Organization-ID
+		orgScoped bool
+	}{
+		{route: "GET /users/:id", path: "/users/" + a.userID, status: http.StatusOK, contains: b.userID},
+		{route: "PUT /users/:id", path: "/users/" + a.userID, body: `{"name": "renamed"}`, status: http.StatusOK, contains: b.userID},
+
+		{route: "GET /organizations", path: "/organizations", status: http.StatusOK, contains: b.marker},
+		{route: "GET /organizations/:id/members", path: "/organizations/" + id(a.orgID) + "/members", status: http.StatusNotFound},
+		{route: "PUT /organizations/:id/members/:userId", path: "/organizations/" + id(a.orgID) + "/members/" + b.userID, body: `{"role": "owner"}`, status: http.StatusNotFound},
+		{route: "DELETE /organizations/:id/members/:userId", path: "/organizations/" + id(a.orgID) + "/members/" + a.userID, status: http.StatusNotFound},
+
+		{route: "GET /hosts", path: "/hosts", status: http.StatusOK, orgScoped: true},
+		{route: "GET /hosts/:id", path: "/hosts/" + id(a.hostID), status: http.StatusNotFound, orgScoped: true},
+		{route: "POST /hosts", path: "/hosts", body: `{"name": "web1", "ip": "10.0.0.2", "os": "ubuntu", "credentialId": "` + id(a.credentialID) + `"}`, status: http.StatusBadRequest, contains: "credentialId", orgScoped: true},
+		{route: "PUT /hosts/:id", path: "/hosts/" + id(a.hostID), body: `{"name": "renamed"}`, status: http.StatusNotFound, orgScoped: true},
+		{route: "DELETE /hosts/:id", path: "/hosts/" + id(a.hostID), status: http.StatusNotFound, orgScoped: true},
+		{route: "GET /hosts/:id/health", path: "/hosts/" + id(a.hostID) + "/health", status: http.StatusNotFound, orgScoped: true},
+
+		{route: "POST /hosts/import", path: "/hosts/import", body: `{"format": "csv", "content": "name,ip,os\nweb1,10.0.0.2,ubuntu\n", "credentialId": "` + id(a.credentialID) + `"}`, status: http.StatusBadRequest, contains: "credentialId", orgScoped: true},
+		{route: "GET /hosts/export", path: "/hosts/export?format=csv", status: http.StatusOK, orgScoped: true},
+
+		{route: "GET /hostGroups", path: "/hostGroups", status: http.StatusOK, orgScoped: true},
+		{route: "GET /hostGroups/:id", path: "/hostGroups/" + id(a.hostGroupID), status: http.StatusNotFound, orgScoped: true},
+		{route: "POST /hostGroups", path: "/hostGroups", body: `{"name": "web"}`, status: http.StatusCreated, orgScoped: true},
+		{route: "PUT /hostGroups/:id", path: "/hostGroups/" + id(a.hostGroupID), body: `{"name": "renamed"}`, status: http.StatusNotFound, orgScoped: true},
+		{route: "POST /hostGroups/:id/hosts", path: "/hostGroups/" + id(a.hostGroupID) + "/hosts", body: `{"hostIds": [` + id(a.hostID) + `]}`, status: http.StatusBadRequest, contains: "hostIds[0]", orgScoped: true},
+		{route: "DELETE /hostGroups/:id/hosts/:hostId", path: "/hostGroups/" + id(a.hostGroupID) + "/hosts/" + id(a.hostID), status: http.StatusNotFound, orgScoped: true},
+		{route: "DELETE /hostGroups/:id", path: "/hostGroups/" + id(a.hostGroupID), status: http.StatusNotFound, orgScoped: true},
+
+		{route: "GET /credentials", path: "/credentials", status: http.StatusOK, orgScoped: true},
+		{route: "GET /credentials/:id", path: "/credentials/" + id(a.credentialID), status: http.StatusNotFound, orgScoped: true},
+		{route: "POST /credentials", path: "/credentials", body: `{"name": "key", "type": "ssh_key", "secret": {"privateKey": "x"}}`, status: http.StatusCreated, orgScoped: true},
+		{route: "PUT /credentials/:id", path: "/credentials/" + id(a.credentialID), body: `{"name": "renamed"}`, status: http.StatusNotFound, orgScoped: true},
+		{route: "DELETE /credentials/:id", path: "/credentials/" + id(a.credentialID), status: http.StatusNotFound, orgScoped: true},
+
+		{route: "GET /blueprints", path: "/blueprints", status: http.StatusOK, orgScoped: true},
+		{route: "GET /blueprints/:id", path: "/blueprints/" + id(a.blueprintID), status: http.StatusNotFound, orgScoped: true},
+		{route: "GET /blueprints/:id/components", path: "/blueprints/" + id(a.blueprintID) + "/components", status: http.StatusNotFound, orgScoped: true},
+		{route: "POST /blueprints", path: "/blueprints", body: `{"name": "stack"}`, status: http.StatusCreated, orgScoped: true},
+		{route: "PUT /blueprints/:id", path: "/blueprints/" + id(a.blueprintID), body: `{"name": "renamed"}`, status: http.StatusNotFound, orgScoped: true},
+		{route: "DELETE /blueprints/:id", path: "/blueprints/" + id(a.blueprintID), status: http.StatusNotFound, orgScoped: true},
+		{route: "GET /blueprints/:id/deployments", path: "/blueprints/" + id(a.blueprintID) + "/deployments", status: http.StatusNotFound, orgScoped: true},
+		{route: "PUT /blueprints/:id/components", path: "/blueprints/" + id(a.blueprintID) + "/components", body: `[{"componentId": ` + id(componentID) + `, "position": 0}]`, status: http.StatusNotFound, orgScoped: true},
+
+		{route: "POST /deployments/type/:type", path: "/deployments/type/deploy", body: `{"blueprintId": ` + id(a.blueprintID) + `, "hostIds": [` + id(a.hostID) + `]}`, status: http.StatusBadRequest, contains: "blueprintId", orgScoped: true},
+		{route: "GET /deployments/:id", path: "/deployments/" + a.deploymentID, status: http.StatusNotFound, orgScoped: true},
+		{route: "GET /deployments/type/:type", path: "/deployments/type/deploy", status: http.StatusOK, orgScoped: true},
+		{route: "GET /deployments/:id/hosts", path: "/deployments/" + a.deploymentID + "/hosts", status: http.StatusOK, orgScoped: true},
+		{route: "GET /deployments/progress/:jobId", path: "/deployments/progress/" + a.deploymentID, status: http.StatusNotFound, orgScoped: true},
+
+		{route: "GET /metrics/overview", path: "/metrics/overview", status: http.StatusOK, orgScoped: true},
+
+		{route: "GET /tokens", path: "/tokens", status: http.StatusOK, orgScoped: true},
+		{route: "POST /tokens", path: "/tokens", body: `{"name": "ci", "scopes": ["hosts:read"]}`, status: http.StatusCreated, orgScoped: true},
+		{route: "DELETE /tokens/:id", path: "/tokens/" + id(a.apiTokenID), status: http.StatusNotFound, orgScoped: true},
+
+		{route: "GET /audit", path: "/audit", status: http.StatusOK, orgScoped: true},
+	}
+	// Routes that no organization owns
+	shared := map[string]bool{
+		"POST /users":         true,
+		"POST /organizations": true,
+		"GET /components":     true,
+		"GET /components/:id": true,
+	}
+
+	// Acts on the caller whatever the id, it is sent last and the check at the end shows
+	// that the other user is left alone
+	const deleteUser = "DELETE /users/:id"
+
+	covered := map[string]bool{deleteUser: true}
+	for _, tt := range tests {
+		covered[tt.route] = true
+	}
+	for _, r := range engine.Routes() {
+		route := r.Method + " " + strings.TrimPrefix(r.Path, "/api/v1")
+		if !covered[route] && !shared[route] {
+			t.Errorf("route %s has no ownership test", route)
+		}
+	}
+
+	bToken := sessionToken(t, b.userID)
+	do := func(t *testing.T, method, path, body, orgHeader string) (int, string) {
+		t.Helper()
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, "/api/v1"+path, reader)
+		req.Header.Set("Authorization", "Bearer "+bToken)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if orgHeader != "" {
+			req.Header.Set(middleware.OrganizationHeader, orgHeader)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	// Asking for the other organization fails before any handler runs
+	for _, tt := range tests {
+		if !tt.orgScoped {
+			continue
+		}
+		t.Run(tt.route+" in the other organization", func(t *testing.T) {
+			method, _, _ := strings.Cut(tt.route, " ")
+			status, body := do(t, method, tt.path, tt.body, id(a.orgID))
+			if status != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d: %s", status, http.StatusNotFound, body)
+			}
+		})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			method, _, _ := strings.Cut(tt.route, " ")
+			status, body := do(t, method, tt.path, tt.body, "")
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %s", status, tt.status, body)
+			}
+			if !strings.Contains(body, tt.contains) {
+				t.Errorf("response does not mention %q: %s", tt.contains, body)
+			}
+			if strings.Contains(body, a.marker) || strings.Contains(body, a.deploymentID) {
+				t.Errorf("response leaks the other organization: %s", body)
+			}
+		})
+	}
+
+	do(t, http.MethodDelete, "/users/"+a.userID, "", "")
+
+	// Nothing of the other tenant was changed or removed
+	var remaining int
+	err := db.GetContext(context.Background(), &remaining, `
+		SELECT (SELECT COUNT(*) FROM hosts WHERE id = $1 AND name = $7)
+		     + (SELECT COUNT(*) FROM credentials WHERE id = $2 AND name = $8)
+		     + (SELECT COUNT(*) FROM host_groups_to_host_mapping WHERE host_group_id = $3 AND host_id = $1)
+		     + (SELECT COUNT(*) FROM blueprint_components WHERE blueprint_id = $4)
+		     + (SELECT COUNT(*) FROM deployments WHERE id = $5)
+		     + (SELECT COUNT(*) FROM api_tokens WHERE id = $6 AND revoked_at IS NULL)
+		     + (SELECT COUNT(*) FROM auth.users WHERE id = $9 AND raw_user_meta_data->>'name' = $10)`,
+		a.hostID, a.credentialID, a.hostGroupID, a.blueprintID, a.deploymentID, a.apiTokenID,
+		a.marker+"-host", a.marker+"-credential", a.userID, a.marker+"-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 7 {
+		t.Errorf("the other tenant's resources were changed, %d of 7 are as seeded", remaining)
+	}
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/apiToken"
+	"clouding/backend/internal/openapi"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func RegisterApiTokenRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
+	apiTokenRepository := repository.NewApiTokenRepository(db)
+	apiTokenService := service.NewApiTokenService(apiTokenRepository)
+	apiTokenController := v1.NewApiTokenController(apiTokenService)
+
+	group := rg.Group("/tokens", middleware.RequireSessionAuth())
+	{
+		group.GET("", apiTokenController.GetAll)
+		group.POST("", apiTokenController.Create)
+		group.DELETE("/:id", apiTokenController.Revoke)
+	}
+
+	openapi.Describe(apiTokenController.GetAll, openapi.Route{Summary: "List the caller's API tokens", Response: []*apiToken.ApiToken{}})
+	openapi.Describe(apiTokenController.Create, openapi.Route{
+		Summary:     "Create an API token",
+		Description: "The token is only returned once.",
+		Request:     apiToken.ApiToken{},
+		Response:    apiToken.CreateApiTokenResponse{},
+		Status:      http.StatusCreated,
+	})
+	openapi.Describe(apiTokenController.Revoke, openapi.Route{
+		Summary:  "Revoke an API token",
+		Response: apiToken.RevokeApiTokenResponse{},
+		Params:   []openapi.Param{openapi.Param{Name: "id", In: openapi.InPath, Type: "integer"}},
+	})
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/audit"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func RegisterAuditRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
+	auditRepository := repository.NewAuditRepository(db)
+	auditService := service.NewAuditService(auditRepository)
+	auditController := v1.NewAuditController(auditService)
+
+	rg.GET("/audit", middleware.RequirePermission(organization.PermAuditRead), auditController.GetAll)
+
+	openapi.RegisterEnum(audit.ActorUser, audit.ActorApiToken, audit.ActorWorker)
+	openapi.Describe(auditController.GetAll, openapi.Route{
+		Summary:  "List the organization's audit log, newest first",
+		Response: audit.ListAuditResponse{},
+		Params: []openapi.Param{
+			{Name: "actorId", In: openapi.InQuery},
+			{Name: "action", In: openapi.InQuery},
+			{Name: "resourceType", In: openapi.InQuery},
+			{Name: "resourceId", In: openapi.InQuery},
+			{Name: "from", In: openapi.InQuery, Format: "date-time"},
+			{Name: "to", In: openapi.InQuery, Format: "date-time"},
+			{Name: "limit", In: openapi.InQuery, Type: "integer"},
+			{Name: "cursor", In: openapi.InQuery},
+		},
+	})
+}
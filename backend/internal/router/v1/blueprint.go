@@ -2,8 +2,14 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/blueprint"
+	"clouding/backend/internal/model/deployment"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -16,12 +22,43 @@ func RegisterBlueprintRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
 	service := service.NewBlueprintService(blueprintRepo, componentRepo, deploymentRepo)
 	controller := v1.NewBlueprintController(service)
 
-	rg.GET("/blueprints", controller.GetAll)
-	rg.GET("/blueprints/:id", controller.GetById)
-	rg.GET("/blueprints/:id/components", controller.GetComponents)
-	rg.POST("/blueprints", controller.Create)
-	rg.PUT("/blueprints/:id", controller.Update)
-	rg.DELETE("/blueprints/:id", controller.Delete)
-	rg.GET("/blueprints/:id/deployments", controller.GetDeployments)
-	rg.PUT("/blueprints/:id/components", controller.UpdateBlueprintComponents)
+	read := middleware.RequirePermission(organization.PermBlueprintsRead)
+	write := middleware.RequirePermission(organization.PermBlueprintsWrite)
+
+	rg.GET("/blueprints", read, controller.GetAll)
+	rg.GET("/blueprints/:id", read, controller.GetById)
+	rg.GET("/blueprints/:id/components", read, controller.GetComponents)
+	rg.POST("/blueprints", write, controller.Create)
+	rg.PUT("/blueprints/:id", write, controller.Update)
+	rg.DELETE("/blueprints/:id", write, controller.Delete)
+	rg.GET("/blueprints/:id/deployments", read, middleware.RequirePermission(organization.PermDeploymentsRead), controller.GetDeployments)
+	rg.PUT("/blueprints/:id/components", write, controller.UpdateBlueprintComponents)
+
+	openapi.RegisterEnum(blueprint.BlueprintStatusDraft, blueprint.BlueprintStatusDeployed, blueprint.BlueprintStatusArchived)
+	idParam := openapi.Param{Name: "id", In: openapi.InPath, Type: "integer"}
+	openapi.Describe(controller.GetAll, openapi.Route{
+		Summary:  "List the organization's blueprints",
+		Response: []*blueprint.Blueprint{},
+		List:     true,
+		Params: []openapi.Param{
+			{Name: "status", In: openapi.InQuery},
+			{Name: "namePrefix", In: openapi.InQuery},
+		},
+	})
+	openapi.Describe(controller.GetById, openapi.Route{Summary: "Get a blueprint", Response: blueprint.Blueprint{}, Params: []openapi.Param{idParam}})
+	openapi.Describe(controller.GetComponents, openapi.Route{Summary: "List a blueprint's components", Response: []*blueprint.BlueprintComponent{}, Params: []openapi.Param{idParam}})
+	openapi.Describe(controller.Create, openapi.Route{Summary: "Create a blueprint", Request: blueprint.Blueprint{}, Response: blueprint.CreateBlueprintResponse{}, Status: http.StatusCreated})
+	openapi.Describe(controller.Update, openapi.Route{Summary: "Update a blueprint", Request: blueprint.Blueprint{}, Response: blueprint.UpdateBlueprintResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+	openapi.Describe(controller.Delete, openapi.Route{Summary: "Delete a blueprint", Response: blueprint.DeleteBlueprintResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+	openapi.Describe(controller.GetDeployments, openapi.Route{
+		Summary:  "List a blueprint's latest deployments",
+		Response: []*deployment.Deployment{},
+		Params:   []openapi.Param{idParam, {Name: "limit", In: openapi.InQuery, Type: "integer"}},
+	})
+	openapi.Describe(controller.UpdateBlueprintComponents, openapi.Route{
+		Summary:  "Replace a blueprint's components",
+		Request:  []*blueprint.BlueprintComponent{},
+		Response: []*blueprint.UpdateBlueprintComponentResponse{},
+		Params:   []openapi.Param{openapi.IfMatch, idParam},
+	})
 }
@@ -2,6 +2,8 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/model/component"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
 
@@ -16,4 +18,13 @@ func RegisterComponentRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
 
 	rg.GET("/components", componentController.GetAllComponents)
 	rg.GET("/components/:id", componentController.GetComponentByIds)
+
+	openapi.RegisterEnum(component.ValueTypeString, component.ValueTypeFileList)
+	openapi.RegisterEnum(component.UITypeText, component.UITypeSelect, component.UITypeFile, component.UITypeFileList, component.UITypeTextarea, component.UITypeNumber)
+	openapi.Describe(componentController.GetAllComponents, openapi.Route{Summary: "List the component catalog", Response: []*component.Component{}})
+	openapi.Describe(componentController.GetComponentByIds, openapi.Route{
+		Summary:  "Get components by ID",
+		Response: []*component.Component{},
+		Params:   []openapi.Param{{Name: "id", In: openapi.InPath, Description: "Comma separated component IDs"}},
+	})
 }
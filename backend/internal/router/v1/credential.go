@@ -2,23 +2,43 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/credential"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
 	secretmanager "clouding/backend/internal/utils/secretManager"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 )
 
-func RegisterCredentialRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
-	secretsManager := secretmanager.NewSecretManager()
+func RegisterCredentialRoutes(rg *gin.RouterGroup, db *sqlx.DB, secretsManager secretmanager.SecretsManager, idempotencyService service.IdempotencyService) {
 	repo := repository.NewCredentialRepository(db, secretsManager)
-	service := service.NewCredentialService(repo)
-	controller := v1.NewCredentialController(service)
+	credentialService := service.NewCredentialService(repo)
+	controller := v1.NewCredentialController(credentialService)
 
-	rg.GET("/credentials", controller.GetAllByUserId)
-	rg.GET("/credentials/:id", controller.GetById)
-	rg.POST("/credentials", controller.Create)
-	rg.PUT("/credentials/:id", controller.Update)
-	rg.DELETE("/credentials/:id", controller.Delete)
+	rg.GET("/credentials", middleware.RequirePermission(organization.PermCredentialsRead), controller.GetAllByOrgId)
+	rg.GET("/credentials/:id", middleware.RequirePermission(organization.PermCredentialsRead), controller.GetById)
+	rg.POST("/credentials", middleware.RequirePermission(organization.PermCredentialsWrite), middleware.Idempotency(idempotencyService), controller.Create)
+	rg.PUT("/credentials/:id", middleware.RequirePermission(organization.PermCredentialsWrite), controller.Update)
+	rg.DELETE("/credentials/:id", middleware.RequirePermission(organization.PermCredentialsWrite), controller.Delete)
+
+	openapi.RegisterEnum(credential.CredentialTypeSSHKey, credential.CredentialTypeSSL, credential.CredentialTypePassword, credential.CredentialTypeAPIKey)
+	idParam := openapi.Param{Name: "id", In: openapi.InPath, Type: "integer"}
+	openapi.Describe(controller.GetAllByOrgId, openapi.Route{
+		Summary:  "List the organization's credentials",
+		Response: []*credential.Credential{},
+		List:     true,
+		Params: []openapi.Param{
+			{Name: "type", In: openapi.InQuery},
+			{Name: "namePrefix", In: openapi.InQuery},
+		},
+	})
+	openapi.Describe(controller.GetById, openapi.Route{Summary: "Get a credential", Response: credential.Credential{}, Params: []openapi.Param{idParam}})
+	openapi.Describe(controller.Create, openapi.Route{Summary: "Create a credential", Request: credential.Credential{}, Response: credential.CreateCredentialResponse{}, Status: http.StatusCreated, Params: []openapi.Param{openapi.IdempotencyKey}})
+	openapi.Describe(controller.Update, openapi.Route{Summary: "Update a credential", Request: credential.Credential{}, Response: credential.UpdateCredentialResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+	openapi.Describe(controller.Delete, openapi.Route{Summary: "Delete a credential", Response: credential.DeleteCredentialResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
 }
@@ -2,26 +2,91 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/deployment"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/queue"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
 	"clouding/backend/internal/utils/logStreamer"
+	secretmanager "clouding/backend/internal/utils/secretManager"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 )
 
-func RegisterDeploymentRoutes(rg *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher) {
+func RegisterDeploymentRoutes(rg *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher, secretsManager secretmanager.SecretsManager, idempotencyService service.IdempotencyService, lc *lifecycle.Lifecycle) {
 	ls := logStreamer.NewLogStreamer()
 	deploymentRepository := repository.NewDeploymentRepository(db)
-	deploymentService := service.NewDeploymentService(deploymentRepository, publisher)
-	deploymentController := v1.NewDeploymentController(deploymentService, ls)
+	blueprintRepository := repository.NewBlueprintRepository(db)
+	hostRepository := repository.NewHostRepository(db)
+	credentialRepository := repository.NewCredentialRepository(db, secretsManager)
+	deploymentService := service.NewDeploymentService(deploymentRepository, blueprintRepository, hostRepository, credentialRepository, publisher)
+	deploymentController := v1.NewDeploymentController(deploymentService, ls, lc)
+
+	read := middleware.RequirePermission(organization.PermDeploymentsRead)
+	write := middleware.RequirePermission(organization.PermDeploymentsWrite)
+
+	rg.POST("/deployments/type/:type", write, middleware.Idempotency(idempotencyService), deploymentController.Create)
+	middleware.RateLimitGroup(deploymentController.Create, "deploymentCreate")
+	rg.GET("/deployments/:id", read, deploymentController.GetByID)
+	rg.GET("/deployments/type/:type", read, deploymentController.GetByOrgAndType)
+	rg.GET("/deployments/:id/hosts", read, deploymentController.GetDeploymentHostMappingByIds)
+	rg.GET("/deployments/progress/:jobId", read, deploymentController.StreamJobProgress)
+
+	openapi.RegisterEnum(deployment.DeploymentTypePlan, deployment.DeploymentTypeDeploy)
+	openapi.RegisterEnum(deployment.Statuses...)
+	typeParam := openapi.Param{Name: "type", In: openapi.InPath, Description: "plan or deploy"}
+	openapi.Describe(deploymentController.Create, openapi.Route{
+		Summary:     "Queue a deployment of a blueprint",
+		Description: "The deployment's id is generated by the server, an id in the body is ignored.",
+		Request:     deployment.Deployment{},
+		Response:    deployment.Deployment{},
+		Status:      http.StatusCreated,
+		Params:      []openapi.Param{openapi.IdempotencyKey, typeParam},
+	})
+	openapi.Describe(deploymentController.GetByID, openapi.Route{Summary: "Get a deployment", Response: deployment.Deployment{}})
+	openapi.Describe(deploymentController.GetByOrgAndType, openapi.Route{
+		Summary:  "List the organization's deployments, newest first",
+		Response: []*deployment.Deployment{},
+		List:     true,
+		Params: []openapi.Param{
+			typeParam,
+			{Name: "status", In: openapi.InQuery},
+			{Name: "blueprintId", In: openapi.InQuery, Type: "integer"},
+			{Name: "from", In: openapi.InQuery, Format: "date-time"},
+			{Name: "to", In: openapi.InQuery, Format: "date-time"},
+		},
+	})
+	openapi.Describe(deploymentController.GetDeploymentHostMappingByIds, openapi.Route{
+		Summary:  "List the hosts of deployments",
+		Response: []*deployment.DeploymentHostMapping{},
+		Params:   []openapi.Param{{Name: "id", In: openapi.InPath, Description: "Comma separated deployment IDs"}},
+	})
+	openapi.Describe(deploymentController.StreamJobProgress, openapi.Route{
+		Summary:     "Stream a deployment's logs",
+		Description: "Server sent events: logs, heartbeat, error, shutdown and end.",
+		Stream:      true,
+	})
+}
+
+// RegisterWorkerDeploymentRoutes registers the callbacks deployment workers use to report progress
+func RegisterWorkerDeploymentRoutes(rg *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher) {
+	deploymentRepository := repository.NewDeploymentRepository(db)
+	blueprintRepository := repository.NewBlueprintRepository(db)
+	hostRepository := repository.NewHostRepository(db)
+	// Workers only report status, deployments are never created through these routes
+	deploymentService := service.NewDeploymentService(deploymentRepository, blueprintRepository, hostRepository, nil, publisher)
+	deploymentController := v1.NewDeploymentController(deploymentService, nil, nil)
 
-	rg.POST("/deployments/type/:type", deploymentController.Create)
 	rg.PUT("/deployments/:id/status", deploymentController.UpdateStatus)
-	rg.GET("/deployments/:id", deploymentController.GetByID)
-	rg.GET("/deployments/type/:type", deploymentController.GetByUserAndType)
-	rg.GET("/deployments/:id/hosts", deploymentController.GetDeploymentHostMappingByIds)
-	rg.GET("/deployments/progress/:jobId", deploymentController.StreamJobProgress)
 
+	openapi.Describe(deploymentController.UpdateStatus, openapi.Route{
+		Summary:  "Report a deployment's status",
+		Request:  deployment.UpdateDeploymentStatusPayload{},
+		Response: deployment.UpdateDeploymentStatusPayload{},
+	})
 }
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/health"
+	"clouding/backend/internal/queue"
+	"clouding/backend/internal/utils/logStreamer"
+	secretmanager "clouding/backend/internal/utils/secretManager"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func RegisterHealthRoutes(rg *gin.RouterGroup, db *sqlx.DB, publisher *queue.Publisher, secretsManager secretmanager.SecretsManager) {
+	checker := health.NewChecker(3 * time.Second)
+	checker.Register("postgres", db.PingContext)
+	checker.Register("rabbitmq", publisher.Ping)
+	checker.Register("loki", logStreamer.NewLogStreamer().Ready)
+	checker.Register("secretManager", secretsManager.Ping)
+
+	healthController := v1.NewHealthController(checker)
+
+	rg.GET("/healthz", healthController.Liveness)
+	rg.GET("/readyz", healthController.Readiness)
+}
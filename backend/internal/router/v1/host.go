@@ -2,22 +2,48 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/host"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
+	secretmanager "clouding/backend/internal/utils/secretManager"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 )
 
-func RegisterHostRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
+func RegisterHostRoutes(rg *gin.RouterGroup, db *sqlx.DB, secretsManager secretmanager.SecretsManager) {
 	hostRepository := repository.NewHostRepository(db)
-	hostService := service.NewHostService(hostRepository)
+	credentialRepository := repository.NewCredentialRepository(db, secretsManager)
+	hostService := service.NewHostService(hostRepository, credentialRepository)
 	hostController := v1.NewHostController(hostService)
 
-	rg.GET("/hosts", hostController.GetAllHosts)
-	rg.GET("/hosts/:id", hostController.GetHost)
-	rg.POST("/hosts", hostController.CreateHost)
-	rg.PUT("/hosts/:id", hostController.UpdateHost)
-	rg.DELETE("/hosts/:id", hostController.DeleteHost)
-	rg.GET("/hosts/:id/health",hostController.GetHostsHealth)
+	rg.GET("/hosts", middleware.RequirePermission(organization.PermHostsRead), hostController.GetAllHosts)
+	rg.GET("/hosts/:id", middleware.RequirePermission(organization.PermHostsRead), hostController.GetHost)
+	rg.POST("/hosts", middleware.RequirePermission(organization.PermHostsWrite), hostController.CreateHost)
+	rg.PUT("/hosts/:id", middleware.RequirePermission(organization.PermHostsWrite), hostController.UpdateHost)
+	rg.DELETE("/hosts/:id", middleware.RequirePermission(organization.PermHostsWrite), hostController.DeleteHost)
+	rg.GET("/hosts/:id/health", middleware.RequirePermission(organization.PermHostsRead), hostController.GetHostsHealth)
+	middleware.RateLimitGroup(hostController.GetHostsHealth, "hostHealth")
+
+	idParam := openapi.Param{Name: "id", In: openapi.InPath, Type: "integer"}
+	idsParam := openapi.Param{Name: "id", In: openapi.InPath, Description: "Comma separated host IDs"}
+	openapi.Describe(hostController.GetAllHosts, openapi.Route{
+		Summary:  "List the organization's hosts",
+		Response: []*host.Host{},
+		List:     true,
+		Params: []openapi.Param{
+			{Name: "os", In: openapi.InQuery},
+			{Name: "namePrefix", In: openapi.InQuery},
+			{Name: "credentialId", In: openapi.InQuery, Type: "integer"},
+		},
+	})
+	openapi.Describe(hostController.GetHost, openapi.Route{Summary: "Get hosts by ID", Response: []*host.Host{}, Params: []openapi.Param{idsParam}})
+	openapi.Describe(hostController.CreateHost, openapi.Route{Summary: "Create a host", Request: host.Host{}, Response: host.CreateHostResponse{}, Status: http.StatusCreated})
+	openapi.Describe(hostController.UpdateHost, openapi.Route{Summary: "Update a host", Request: host.Host{}, Response: host.UpdateHostResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+	openapi.Describe(hostController.DeleteHost, openapi.Route{Summary: "Delete a host", Response: host.DeleteHostResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+	openapi.Describe(hostController.GetHostsHealth, openapi.Route{Summary: "Check that hosts are reachable", Response: []*host.HostHealth{}, Params: []openapi.Param{idsParam}})
 }
@@ -2,6 +2,10 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
 
@@ -11,19 +15,40 @@ import (
 
 func RegisterHostGroupRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
 	hostGroupRepository := repository.NewHostGroupRepository(db)
-	hostGroupService := service.NewHostGroupService(hostGroupRepository)
+	hostGroupService := service.NewHostGroupService(hostGroupRepository, repository.NewHostRepository(db))
 	hostGroupController := v1.NewHostGroupController(hostGroupService)
 
+	read := middleware.RequirePermission(organization.PermHostGroupsRead)
+	write := middleware.RequirePermission(organization.PermHostGroupsWrite)
+
 	group := rg.Group("/hostGroups")
 	{
-		group.GET("", hostGroupController.GetAllHostGroups)
-		group.GET("/:id", hostGroupController.GetHostGroupByID)
-		group.POST("", hostGroupController.CreateHostGroup)
-		group.PUT("/:id", hostGroupController.UpdateHostGroup)
-		group.POST("/:id/hosts", hostGroupController.AddHostsToGroup)
-		group.DELETE("/:id/hosts/:hostId", hostGroupController.RemoveHostFromGroup)
-		group.DELETE("/:id", hostGroupController.DeleteHostGroup)
+		group.GET("", read, hostGroupController.GetAllHostGroups)
+		group.GET("/:id", read, hostGroupController.GetHostGroupByID)
+		group.POST("", write, hostGroupController.CreateHostGroup)
+		group.PUT("/:id", write, hostGroupController.UpdateHostGroup)
+		group.POST("/:id/hosts", write, hostGroupController.AddHostsToGroup)
+		group.DELETE("/:id/hosts/:hostId", write, hostGroupController.RemoveHostFromGroup)
+		group.DELETE("/:id", write, hostGroupController.DeleteHostGroup)
 
 	}
 
+	idParam := openapi.Param{Name: "id", In: openapi.InPath, Type: "integer"}
+	openapi.Describe(hostGroupController.GetAllHostGroups, openapi.Route{
+		Summary:  "List the organization's host groups",
+		Response: []*hostgroup.HostGroup{},
+		List:     true,
+		Params:   []openapi.Param{{Name: "namePrefix", In: openapi.InQuery}},
+	})
+	openapi.Describe(hostGroupController.GetHostGroupByID, openapi.Route{Summary: "Get a host group", Response: hostgroup.HostGroup{}, Params: []openapi.Param{idParam}})
+	openapi.Describe(hostGroupController.CreateHostGroup, openapi.Route{Summary: "Create a host group", Request: hostgroup.HostGroup{}, Response: hostgroup.HostGroupCreateResponse{}})
+	openapi.Describe(hostGroupController.UpdateHostGroup, openapi.Route{Summary: "Update a host group", Request: hostgroup.HostGroup{}, Response: hostgroup.HostGroupUpdateResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+	openapi.Describe(hostGroupController.AddHostsToGroup, openapi.Route{Summary: "Add hosts to a group", Request: hostgroup.AddHostToHostgroupRequest{}, Response: "", Params: []openapi.Param{idParam}})
+	openapi.Describe(hostGroupController.RemoveHostFromGroup, openapi.Route{
+		Summary:  "Remove a host from a group",
+		Response: "",
+		Params:   []openapi.Param{idParam, {Name: "hostId", In: openapi.InPath, Type: "integer"}},
+	})
+	openapi.Describe(hostGroupController.DeleteHostGroup, openapi.Route{Summary: "Delete a host group", Response: hostgroup.HostGroupDeleteResponse{}, Params: []openapi.Param{openapi.IfMatch, idParam}})
+
 }
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/inventory"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+	secretmanager "clouding/backend/internal/utils/secretManager"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func RegisterInventoryRoutes(rg *gin.RouterGroup, db *sqlx.DB, secretsManager secretmanager.SecretsManager, idempotencyService service.IdempotencyService) {
+	inventoryRepository := repository.NewInventoryRepository(db)
+	credentialRepository := repository.NewCredentialRepository(db, secretsManager)
+	inventoryService := service.NewInventoryService(inventoryRepository, credentialRepository)
+	inventoryController := v1.NewInventoryController(inventoryService)
+
+	// Imports create hosts and groups, exports list both
+	rg.POST("/hosts/import",
+		middleware.RequirePermission(organization.PermHostsWrite),
+		middleware.RequirePermission(organization.PermHostGroupsWrite),
+		middleware.Idempotency(idempotencyService),
+		inventoryController.Import)
+	rg.GET("/hosts/export",
+		middleware.RequirePermission(organization.PermHostsRead),
+		middleware.RequirePermission(organization.PermHostGroupsRead),
+		inventoryController.Export)
+
+	openapi.RegisterEnum(inventory.Formats...)
+	openapi.RegisterEnum(inventory.ActionCreate, inventory.ActionSkip, inventory.ActionFail, inventory.ActionExisting)
+	openapi.Describe(inventoryController.Import, openapi.Route{
+		Summary:     "Import hosts and host groups from an inventory file",
+		Description: "Parses CSV, Ansible INI or YAML inventories or an OpenSSH config. Hosts that already exist by name are skipped, invalid ones are reported by line and the rest is created. dryRun previews the result without changing anything.",
+		Request:     inventory.ImportRequest{},
+		Response:    inventory.ImportResult{},
+		Params:      []openapi.Param{openapi.IdempotencyKey},
+	})
+	openapi.Describe(inventoryController.Export, openapi.Route{
+		Summary:    "Export hosts and host groups as an inventory file",
+		Response:   "",
+		Raw:        true,
+		MediaTypes: []string{"text/csv", "text/plain", "application/yaml"},
+		Params: []openapi.Param{
+			{Name: "format", In: openapi.InQuery, Required: true, Description: "csv, ansible_ini, ansible_yaml or ssh_config"},
+		},
+	})
+}
@@ -2,6 +2,10 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/metric"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
 
@@ -14,5 +18,7 @@ func RegisterMetricRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
 	metricService := service.NewMetricService(metricRepository)
 	metricController := v1.NewMetricController(metricService)
 
-	rg.GET("/metrics/overview", metricController.GetOverview)
+	rg.GET("/metrics/overview", middleware.RequirePermission(organization.PermMetricsRead), metricController.GetOverview)
+
+	openapi.Describe(metricController.GetOverview, openapi.Route{Summary: "Resource counts of the organization", Response: []*metric.Overview{}})
 }
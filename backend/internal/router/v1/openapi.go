@@ -0,0 +1,20 @@
+package v1
+
+import (
+	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOpenAPIRoutes(rg *gin.RouterGroup, spec *openapi.Spec) {
+	openAPIController := v1.NewOpenAPIController(spec)
+
+	rg.GET("/openapi.json", openAPIController.GetDocument)
+
+	openapi.Describe(openAPIController.GetDocument, openapi.Route{
+		Summary: "OpenAPI document of this API",
+		Public:  true,
+		Raw:     true,
+	})
+}
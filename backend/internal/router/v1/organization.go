@@ -0,0 +1,39 @@
+package v1
+
+import (
+	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/openapi"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func RegisterOrganizationRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
+	organizationRepository := repository.NewOrganizationRepository(db)
+	organizationService := service.NewOrganizationService(organizationRepository)
+	organizationController := v1.NewOrganizationController(organizationService)
+
+	// Membership changes can hand out any role, so they aren't left to api tokens whose
+	// scopes and organization binding only cover the organization scoped routes
+	group := rg.Group("/organizations", middleware.RequireSessionAuth())
+	{
+		group.GET("", organizationController.GetOrganizations)
+		group.POST("", organizationController.Create)
+		group.GET("/:id/members", organizationController.GetMembers)
+		group.PUT("/:id/members/:userId", organizationController.SetMember)
+		group.DELETE("/:id/members/:userId", organizationController.RemoveMember)
+	}
+
+	openapi.RegisterEnum(organization.RoleOwner, organization.RoleAdmin, organization.RoleOperator, organization.RoleViewer)
+	idParam := openapi.Param{Name: "id", In: openapi.InPath, Type: "integer"}
+	openapi.Describe(organizationController.GetOrganizations, openapi.Route{Summary: "List the caller's organizations", Response: []*organization.Organization{}})
+	openapi.Describe(organizationController.Create, openapi.Route{Summary: "Create an organization", Request: organization.Organization{}, Response: organization.CreateOrganizationResponse{}, Status: http.StatusCreated})
+	openapi.Describe(organizationController.GetMembers, openapi.Route{Summary: "List an organization's members", Response: []*organization.Membership{}, Params: []openapi.Param{idParam}})
+	openapi.Describe(organizationController.SetMember, openapi.Route{Summary: "Add a member or change their role", Request: organization.Membership{}, Response: organization.UpdateMembershipResponse{}, Params: []openapi.Param{idParam}})
+	openapi.Describe(organizationController.RemoveMember, openapi.Route{Summary: "Remove a member", Response: organization.DeleteMembershipResponse{}, Params: []openapi.Param{idParam}})
+}
@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"clouding/backend/internal/metrics"
+	"clouding/backend/internal/repository"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func RegisterPrometheusRoutes(rg *gin.RouterGroup, db *sqlx.DB, path string) {
+	if err := metrics.RegisterDBStats(db.DB); err != nil {
+		slog.Error("Failed to register database metrics", "ERR", err)
+	}
+	deploymentRepo := repository.NewDeploymentRepository(db)
+	if err := metrics.RegisterDeploymentStatus(deploymentRepo.CountByStatus); err != nil {
+		slog.Error("Failed to register deployment metrics", "ERR", err)
+	}
+
+	rg.GET(path, gin.WrapH(metrics.Handler()))
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"clouding/backend/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The account and organization routes are outside OrgMiddleware, so neither the scopes
+// nor the organization of an api token would be checked there
+func TestAccountRoutesRefuseApiTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.ErrorMiddleware())
+	// Stands in for JWTAuthMiddleware accepting a hosts:read token bound to organization 1
+	rg := engine.Group("", func(c *gin.Context) {
+		c.Set("userId", "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c11")
+		c.Set("apiTokenId", 1)
+		c.Set("apiTokenOrgId", 1)
+		c.Set("apiTokenScopes", []string{"hosts:read"})
+	})
+	// The handlers are never reached, so no database is needed
+	RegisterUserRoutes(rg, nil)
+	RegisterOrganizationRoutes(rg, nil)
+
+	routes := engine.Routes()
+	if len(routes) == 0 {
+		t.Fatal("no routes registered")
+	}
+	for _, r := range routes {
+		t.Run(r.Method+" "+r.Path, func(t *testing.T) {
+			path := strings.NewReplacer(":id", "2", ":userId", "6f1c1f54-8a7e-4a35-9a57-3b7a2a6e0c12").Replace(r.Path)
+			req := httptest.NewRequest(r.Method, path, strings.NewReader(`{"role": "owner"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "api_token_not_accepted") {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package v1
 
 import (
 	v1 "clouding/backend/internal/controller/v1"
+	"clouding/backend/internal/middleware"
+	"clouding/backend/internal/model/user"
+	"clouding/backend/internal/openapi"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/service"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -14,8 +18,17 @@ func RegisterUserRoutes(rg *gin.RouterGroup, db *sqlx.DB) {
 	userService := service.NewUserService(userRepository)
 	userController := v1.NewUserController(userService)
 
-	rg.GET("/users/:id", userController.GetUser)
-	rg.POST("/users", userController.CreateUser)
-	rg.PUT("/users/:id", userController.UpdateUser)
-	rg.DELETE("/users/:id", userController.DeleteUser)
+	// Api tokens are scoped to an organization, the account itself needs a session
+	group := rg.Group("/users", middleware.RequireSessionAuth())
+	{
+		group.GET("/:id", userController.GetUser)
+		group.POST("", userController.CreateUser)
+		group.PUT("/:id", userController.UpdateUser)
+		group.DELETE("/:id", userController.DeleteUser)
+	}
+
+	openapi.Describe(userController.GetUser, openapi.Route{Summary: "Get a user", Response: user.User{}})
+	openapi.Describe(userController.CreateUser, openapi.Route{Summary: "Create a user", Request: user.User{}, Response: user.CreateUserResponse{}, Status: http.StatusCreated})
+	openapi.Describe(userController.UpdateUser, openapi.Route{Summary: "Update a user", Request: user.User{}, Response: user.UpdateUserResponse{}})
+	openapi.Describe(userController.DeleteUser, openapi.Route{Summary: "Delete a user", Response: user.DeleteUserResponse{}})
 }
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"clouding/backend/internal/model/audit"
+	"clouding/backend/internal/repository"
+	"context"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type AuditService interface {
+	Record(ctx context.Context, e *audit.Entry) error
+	GetEntries(ctx context.Context, f *audit.Filter) (*audit.ListAuditResponse, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+func (s *auditService) Record(ctx context.Context, e *audit.Entry) error {
+	return s.repo.CreateEntry(ctx, e)
+}
+
+func (s *auditService) GetEntries(ctx context.Context, f *audit.Filter) (*audit.ListAuditResponse, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultAuditPageSize
+	}
+	if f.Limit > maxAuditPageSize {
+		f.Limit = maxAuditPageSize
+	}
+
+	// Fetch one extra entry to know whether there is a next page
+	pageSize := f.Limit
+	f.Limit++
+	entries, err := s.repo.GetEntries(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &audit.ListAuditResponse{Entries: entries}
+	if len(entries) > pageSize {
+		resp.Entries = entries[:pageSize]
+		resp.NextCursor = resp.Entries[pageSize-1].ID
+	}
+	if resp.Entries == nil {
+		resp.Entries = []*audit.Entry{}
+	}
+	return resp, nil
+}
@@ -1,23 +1,26 @@
 package service
 
 import (
+	apperrors "clouding/backend/internal/errors"
 	"clouding/backend/internal/model/blueprint"
 	"clouding/backend/internal/model/component"
 	"clouding/backend/internal/model/deployment"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/repository"
 	"context"
-	"fmt"
+	"database/sql"
+	"time"
 )
 
 type BlueprintService interface {
-	GetByID(ctx context.Context, id int) (*blueprint.Blueprint, error)
-	GetAllByUserID(ctx context.Context, userId string) ([]*blueprint.Blueprint, error)
-	GetComponentsByBlueprintID(ctx context.Context, blueprintId int) ([]*blueprint.BlueprintComponent, error)
-	GetDeployments(ctx context.Context, blueprintId int, limit int) ([]*deployment.Deployment, error)
+	GetByID(ctx context.Context, id int, orgId int) (*blueprint.Blueprint, error)
+	GetAllByOrgID(ctx context.Context, f *blueprint.Filter, p *pagination.Page) ([]*blueprint.Blueprint, *pagination.Meta, error)
+	GetComponentsByBlueprintID(ctx context.Context, blueprintId int, orgId int) ([]*blueprint.BlueprintComponent, error)
+	GetDeployments(ctx context.Context, blueprintId int, orgId int, limit int) ([]*deployment.Deployment, error)
 	Create(ctx context.Context, bp *blueprint.Blueprint) error
-	Update(ctx context.Context, bp *blueprint.Blueprint) error
-	UpdateBlueprintComponents(ctx context.Context, blueprintId int, components []*blueprint.BlueprintComponent) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, bp *blueprint.Blueprint, versions []time.Time) error
+	UpdateBlueprintComponents(ctx context.Context, blueprintId int, orgId int, components []*blueprint.BlueprintComponent, versions []time.Time) (*time.Time, error)
+	Delete(ctx context.Context, id int, orgId int, versions []time.Time) error
 }
 
 type blueprintService struct {
@@ -38,46 +41,54 @@ func NewBlueprintService(
 	}
 }
 
-func (s *blueprintService) GetByID(ctx context.Context, id int) (*blueprint.Blueprint, error) {
-	return s.blueprintRepo.GetBlueprint(ctx, id)
+func (s *blueprintService) GetByID(ctx context.Context, id int, orgId int) (*blueprint.Blueprint, error) {
+	return s.blueprintRepo.GetBlueprint(ctx, id, orgId)
 }
 
-func (s *blueprintService) GetAllByUserID(ctx context.Context, userId string) ([]*blueprint.Blueprint, error) {
-	return s.blueprintRepo.GetAllBlueprints(ctx, userId)
+func (s *blueprintService) GetAllByOrgID(ctx context.Context, f *blueprint.Filter, p *pagination.Page) ([]*blueprint.Blueprint, *pagination.Meta, error) {
+	return s.blueprintRepo.GetAllBlueprints(ctx, f, p)
 }
 
-func (s *blueprintService) GetComponentsByBlueprintID(ctx context.Context, blueprintId int) ([]*blueprint.BlueprintComponent, error) {
-	return s.blueprintRepo.GetComponentsByBlueprintID(ctx, blueprintId)
+func (s *blueprintService) GetComponentsByBlueprintID(ctx context.Context, blueprintId int, orgId int) ([]*blueprint.BlueprintComponent, error) {
+	if err := s.ensureOwnership(ctx, blueprintId, orgId); err != nil {
+		return nil, err
+	}
+	return s.blueprintRepo.GetComponentsByBlueprintID(ctx, blueprintId, orgId)
 }
 
-func (s *blueprintService) GetDeployments(ctx context.Context, blueprintId int, limit int) ([]*deployment.Deployment, error) {
-	return s.deploymentRepo.GetByBlueprintID(ctx, blueprintId, limit)
+func (s *blueprintService) GetDeployments(ctx context.Context, blueprintId int, orgId int, limit int) ([]*deployment.Deployment, error) {
+	if err := s.ensureOwnership(ctx, blueprintId, orgId); err != nil {
+		return nil, err
+	}
+	return s.deploymentRepo.GetByBlueprintID(ctx, blueprintId, orgId, limit)
 }
 
 func (s *blueprintService) Create(ctx context.Context, bp *blueprint.Blueprint) error {
 	return s.blueprintRepo.CreateBlueprint(ctx, bp)
 }
 
-func (s *blueprintService) Update(ctx context.Context, bp *blueprint.Blueprint) error {
-	return s.blueprintRepo.UpdateBlueprint(ctx, bp)
+func (s *blueprintService) Update(ctx context.Context, bp *blueprint.Blueprint, versions []time.Time) error {
+	return s.blueprintRepo.UpdateBlueprint(ctx, bp, versions)
 }
 
-func (s *blueprintService) UpdateBlueprintComponents(ctx context.Context, blueprintId int, components []*blueprint.BlueprintComponent) error {
+func (s *blueprintService) UpdateBlueprintComponents(ctx context.Context, blueprintId int, orgId int, components []*blueprint.BlueprintComponent, versions []time.Time) (*time.Time, error) {
 	var existingCompIds []int
 	for _, comp := range components {
 		if comp.ComponentID == nil {
-			return fmt.Errorf("componentId is required for each blueprint component")
+			return nil, apperrors.ErrInvalidComponent.Withf("componentId is required for each blueprint component")
 		}
 		existingCompIds = append(existingCompIds, *comp.ComponentID)
 	}
 
+	// A blueprint may use a component more than once
+	existingCompIds = uniqueIds(existingCompIds)
 	existingComps, err := s.componentRepo.GetComponentByIds(ctx, existingCompIds)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(existingComps) != len(existingCompIds) {
-		return fmt.Errorf("one or more components not found: expected %d, got %d", len(existingCompIds), len(existingComps))
+		return nil, apperrors.ErrComponentNotFound.Withf("one or more components not found: expected %d, got %d", len(existingCompIds), len(existingComps))
 	}
 
 	// Create a map for efficient lookup
@@ -92,18 +103,30 @@ func (s *blueprintService) UpdateBlueprintComponents(ctx context.Context, bluepr
 	for _, comp := range components {
 		existingComp := existingCompsMap[*comp.ComponentID]
 		if existingComp == nil {
-			return fmt.Errorf("componentId %d not found", *comp.ComponentID)
+			return nil, apperrors.ErrComponentNotFound.Withf("componentId %d not found", *comp.ComponentID)
 		}
 		err = blueprint.ValidateBlueprintParametersUsingComponentParameters(comp.Parameters, existingComp.Parameters)
 		if err != nil {
-			return fmt.Errorf("parameter validation failed for componentId %d: %w", *comp.ComponentID, err)
+			return nil, apperrors.ErrInvalidComponent.Withf("parameter validation failed for componentId %d: %v", *comp.ComponentID, err)
 		}
 	}
 
 	// Update blueprint
-	return s.blueprintRepo.UpdateBlueprintComponents(ctx, blueprintId, components)
+	return s.blueprintRepo.UpdateBlueprintComponents(ctx, blueprintId, orgId, components, versions)
+}
+
+func (s *blueprintService) Delete(ctx context.Context, id int, orgId int, versions []time.Time) error {
+	return s.blueprintRepo.DeleteBlueprint(ctx, id, orgId, versions)
 }
 
-func (s *blueprintService) Delete(ctx context.Context, id int) error {
-	return s.blueprintRepo.DeleteBlueprint(ctx, id)
+// ensureOwnership returns sql.ErrNoRows when the blueprint does not exist or belongs to another organization
+func (s *blueprintService) ensureOwnership(ctx context.Context, blueprintId int, orgId int) error {
+	bp, err := s.blueprintRepo.GetBlueprint(ctx, blueprintId, orgId)
+	if err != nil {
+		return err
+	}
+	if bp == nil {
+		return sql.ErrNoRows
+	}
+	return nil
 }
@@ -2,16 +2,22 @@ package service
 
 import (
 	"clouding/backend/internal/model/credential"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/repository"
 	"context"
+	"time"
 )
 
 type CredentialService interface {
-	GetAllByUserId(ctx context.Context, userId string) ([]*credential.Credential, error)
-	GetById(ctx context.Context, id int) (*credential.Credential, error)
+	GetAllByOrgId(ctx context.Context, f *credential.Filter, p *pagination.Page, withSecrets bool) ([]*credential.Credential, *pagination.Meta, error)
+	GetById(ctx context.Context, id int, orgId int, withSecret bool) (*credential.Credential, error)
 	Create(ctx context.Context, cred *credential.Credential) error
-	Update(ctx context.Context, cred *credential.Credential) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, cred *credential.Credential, versions []time.Time) error
+	Delete(ctx context.Context, id int, orgId int, versions []time.Time) error
+	// CheckSecrets verifies every credential of every organization has a readable secret
+	CheckSecrets(ctx context.Context) ([]*credential.SecretCheck, error)
+	// MigrateSecrets moves the secrets still under their legacy name to their current one
+	MigrateSecrets(ctx context.Context) ([]*credential.SecretMigration, error)
 }
 
 type credentialService struct {
@@ -22,13 +28,13 @@ func NewCredentialService(repo repository.CredentialRepository) CredentialServic
 	return &credentialService{repo: repo}
 }
 
-func (s *credentialService) GetAllByUserId(ctx context.Context, userId string) ([]*credential.Credential, error) {
-	return s.repo.GetAllCredentials(ctx, userId)
+func (s *credentialService) GetAllByOrgId(ctx context.Context, f *credential.Filter, p *pagination.Page, withSecrets bool) ([]*credential.Credential, *pagination.Meta, error) {
+	return s.repo.GetAllCredentials(ctx, f, p, withSecrets)
 
 }
 
-func (s *credentialService) GetById(ctx context.Context, id int) (*credential.Credential, error) {
-	return s.repo.GetCredential(ctx, id)
+func (s *credentialService) GetById(ctx context.Context, id int, orgId int, withSecret bool) (*credential.Credential, error) {
+	return s.repo.GetCredential(ctx, id, orgId, withSecret)
 }
 
 func (s *credentialService) Create(ctx context.Context, cred *credential.Credential) error {
@@ -36,10 +42,54 @@ func (s *credentialService) Create(ctx context.Context, cred *credential.Credent
 
 }
 
-func (s *credentialService) Update(ctx context.Context, cred *credential.Credential) error {
-	return s.repo.UpdateCredential(ctx, cred)
+func (s *credentialService) Update(ctx context.Context, cred *credential.Credential, versions []time.Time) error {
+	return s.repo.UpdateCredential(ctx, cred, versions)
 }
 
-func (s *credentialService) Delete(ctx context.Context, id int) error {
-	return s.repo.DeleteCredential(ctx, id)
+func (s *credentialService) Delete(ctx context.Context, id int, orgId int, versions []time.Time) error {
+	return s.repo.DeleteCredential(ctx, id, orgId, versions)
+}
+
+func (s *credentialService) CheckSecrets(ctx context.Context) ([]*credential.SecretCheck, error) {
+	creds, err := s.repo.GetAllCredentialsUnscoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]*credential.SecretCheck, 0, len(creds))
+	for _, cred := range creds {
+		check := &credential.SecretCheck{ID: cred.ID, OrgID: cred.OrgID, Name: cred.Name}
+		if err := s.repo.CheckSecret(ctx, cred); err != nil {
+			check.Error = err.Error()
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+func (s *credentialService) MigrateSecrets(ctx context.Context) ([]*credential.SecretMigration, error) {
+	creds, err := s.repo.GetAllCredentialsUnscoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := map[string]int{}
+	for _, cred := range creds {
+		if cred.LegacySecretName != nil {
+			users[*cred.LegacySecretName]++
+		}
+	}
+
+	migrations := []*credential.SecretMigration{}
+	for _, cred := range creds {
+		if cred.LegacySecretName == nil {
+			continue
+		}
+		m := &credential.SecretMigration{ID: cred.ID, OrgID: cred.OrgID, Name: cred.Name, Shared: users[*cred.LegacySecretName] > 1}
+		if err := s.repo.MigrateSecret(ctx, cred); err != nil {
+			m.Error = err.Error()
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, nil
 }
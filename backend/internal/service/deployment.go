@@ -1,51 +1,63 @@
 package service
 
 import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/logger"
 	"clouding/backend/internal/model/deployment"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/queue"
 	"clouding/backend/internal/repository"
+	"clouding/backend/internal/validation"
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
 )
 
 type DeploymentService interface {
 	Create(ctx context.Context, d *deployment.Deployment) error
 	UpdateStatus(ctx context.Context, id string, updateDeploymentStatusPayload *deployment.UpdateDeploymentStatusPayload) error
-	GetByID(ctx context.Context, id string) (*deployment.Deployment, error)
-	GetByUserAndType(ctx context.Context, userId string, dType string) ([]*deployment.Deployment, error)
-	GetDeploymentHostMappingByIds(ctx context.Context, ids []string) ([]*deployment.DeploymentHostMapping, error)
+	GetByID(ctx context.Context, id string, orgId int) (*deployment.Deployment, error)
+	GetByOrgAndType(ctx context.Context, f *deployment.Filter, p *pagination.Page) ([]*deployment.Deployment, *pagination.Meta, error)
+	GetDeploymentHostMappingByIds(ctx context.Context, ids []string, orgId int) ([]*deployment.DeploymentHostMapping, error)
 }
 
 type deploymentService struct {
-	repo      repository.DeploymentRepository
-	publisher *queue.Publisher
+	repo          repository.DeploymentRepository
+	blueprintRepo repository.BlueprintRepository
+	hostRepo      repository.HostRepository
+	credRepo      repository.CredentialRepository
+	publisher     *queue.Publisher
 }
 
-func NewDeploymentService(r repository.DeploymentRepository, publisher *queue.Publisher) DeploymentService {
-	return &deploymentService{repo: r, publisher: publisher}
+func NewDeploymentService(
+	r repository.DeploymentRepository,
+	blueprintRepo repository.BlueprintRepository,
+	hostRepo repository.HostRepository,
+	credRepo repository.CredentialRepository,
+	publisher *queue.Publisher,
+) DeploymentService {
+	return &deploymentService{repo: r, blueprintRepo: blueprintRepo, hostRepo: hostRepo, credRepo: credRepo, publisher: publisher}
 }
 
 func (s *deploymentService) Create(ctx context.Context, d *deployment.Deployment) error {
 
-	// Validation Could be done with query only
-	if d.BlueprintID == nil {
-		return fmt.Errorf("blueprintID is required to create a deployment")
+	// The blueprint and every target host must belong to the organization
+	var errs validation.Errors
+	bp, err := s.blueprintRepo.GetBlueprint(ctx, *d.BlueprintID, *d.OrgID)
+	if err != nil {
+		return err
 	}
-
-	existingDeployments, _ := s.repo.GetByBlueprintID(ctx, *d.BlueprintID, 5)
-	for _, d := range existingDeployments {
-		if d.Status == deployment.StatusPending || d.Status == deployment.StatusStarted {
-			return fmt.Errorf("blueprint already in deployment, skipping creation")
-		}
+	if bp == nil {
+		errs.Add("blueprintId", validation.CodeNotFound, fmt.Sprintf("blueprint %d does not exist in this organization", *d.BlueprintID))
 	}
-
-	existing, err := s.repo.GetByID(ctx, *d.ID)
-	if err == nil && existing != nil {
-		return fmt.Errorf("deployment already exists, skipping creation")
+	if err := checkHosts(ctx, s.hostRepo, &errs, "hostIds", d.HostIDs, *d.OrgID); err != nil {
+		return err
 	}
-
-	if err := s.repo.Create(ctx, d); err != nil {
+	if err := errs.Err(); err != nil {
 		return err
 	}
 
@@ -58,14 +70,35 @@ func (s *deploymentService) Create(ctx context.Context, d *deployment.Deployment
 	for id := range unique {
 		dedupedHostIDs = append(dedupedHostIDs, id)
 	}
+	slices.Sort(dedupedHostIDs)
+
+	secrets, err := s.credentialSecrets(ctx, dedupedHostIDs, *d.OrgID)
+	if err != nil {
+		return err
+	}
+
+	// IDs are generated here rather than trusted from the client, so they can't collide
+	id := uuid.NewString()
+	d.ID = &id
+	d.HostIDs = dedupedHostIDs
+
+	// A unique index allows one pending or started deployment per blueprint, a second one
+	// fails with ErrBlueprintBusy
+	if err := s.repo.Create(ctx, d); err != nil {
+		return err
+	}
 
 	msgPayload := deployment.DeploymentMessage{
 		JobID:       d.ID,
 		UserID:      d.UserID,
+		OrgID:       d.OrgID,
 		HostIDs:     dedupedHostIDs,
 		BlueprintID: d.BlueprintID,
 		Type:        d.Type,
 		CreatedAt:   d.CreatedAt,
+		RequestID:   logger.RequestID(ctx),
+
+		CredentialSecrets: secrets,
 	}
 
 	msg, err := json.Marshal(msgPayload)
@@ -73,25 +106,63 @@ func (s *deploymentService) Create(ctx context.Context, d *deployment.Deployment
 		return fmt.Errorf("failed to marshal message: %v", err)
 	}
 
-	if err := s.publisher.Publish(msg); err != nil {
-		return fmt.Errorf("failed to publish job: %v", err)
+	if err := s.publisher.Publish(ctx, msg); err != nil {
+		// No worker will pick the deployment up, left pending it would keep the blueprint busy
+		failed := &deployment.UpdateDeploymentStatusPayload{Status: deployment.StatusFailed}
+		if updateErr := s.repo.UpdateStatus(context.WithoutCancel(ctx), id, failed); updateErr != nil {
+			logger.FromContext(ctx).Error("Failed to mark unpublished deployment failed", "ID", id, "ERR", updateErr)
+		} else {
+			d.Status = deployment.StatusFailed
+		}
+		return apperrors.ErrQueueUnavailable.Wrap(err)
 	}
 
 	return nil
 }
 
+// credentialSecrets resolves the secret name of every credential used by the hosts, keyed by
+// credential ID. The worker can't derive it itself, it doesn't know who owns a credential.
+func (s *deploymentService) credentialSecrets(ctx context.Context, hostIDs []int, orgId int) (map[string]string, error) {
+	hosts, err := s.hostRepo.GetHosts(ctx, hostIDs, orgId)
+	if err != nil {
+		return nil, err
+	}
+	secrets := map[string]string{}
+	for _, h := range hosts {
+		if h.CredentialID == nil {
+			continue
+		}
+		if _, ok := secrets[*h.CredentialID]; ok {
+			continue
+		}
+		id, err := strconv.Atoi(*h.CredentialID)
+		if err != nil {
+			return nil, fmt.Errorf("host %d has an invalid credential id %q", *h.ID, *h.CredentialID)
+		}
+		cred, err := s.credRepo.GetCredential(ctx, id, orgId, false)
+		if err != nil {
+			return nil, err
+		}
+		if cred == nil {
+			continue
+		}
+		secrets[*h.CredentialID] = repository.SecretName(cred)
+	}
+	return secrets, nil
+}
+
 func (s *deploymentService) UpdateStatus(ctx context.Context, id string, updateDeploymentStatusPayload *deployment.UpdateDeploymentStatusPayload) error {
 	return s.repo.UpdateStatus(ctx, id, updateDeploymentStatusPayload)
 }
 
-func (s *deploymentService) GetByID(ctx context.Context, id string) (*deployment.Deployment, error) {
-	return s.repo.GetByID(ctx, id)
+func (s *deploymentService) GetByID(ctx context.Context, id string, orgId int) (*deployment.Deployment, error) {
+	return s.repo.GetByID(ctx, id, orgId)
 }
 
-func (s *deploymentService) GetByUserAndType(ctx context.Context, userId string, dType string) ([]*deployment.Deployment, error) {
-	return s.repo.GetByUserAndType(ctx, userId, dType)
+func (s *deploymentService) GetByOrgAndType(ctx context.Context, f *deployment.Filter, p *pagination.Page) ([]*deployment.Deployment, *pagination.Meta, error) {
+	return s.repo.GetByOrgAndType(ctx, f, p)
 }
 
-func (s *deploymentService) GetDeploymentHostMappingByIds(ctx context.Context, ids []string) ([]*deployment.DeploymentHostMapping, error) {
-	return s.repo.GetDeploymentHostMappingByIds(ctx, ids)
+func (s *deploymentService) GetDeploymentHostMappingByIds(ctx context.Context, ids []string, orgId int) ([]*deployment.DeploymentHostMapping, error) {
+	return s.repo.GetDeploymentHostMappingByIds(ctx, ids, orgId)
 }
@@ -2,53 +2,103 @@ package service
 
 import (
 	"clouding/backend/internal/model/host"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/repository"
 	"clouding/backend/internal/utils"
+	"clouding/backend/internal/validation"
 	"context"
+	"database/sql"
 	"sync"
 	"time"
 )
 
 // HostService defines business logic for hosts
 type HostService interface {
-	GetHosts(ctx context.Context, ids []int) ([]*host.Host, error)
-	GetAllHostsByUserId(ctx context.Context, userId string) ([]*host.Host, error)
+	GetHosts(ctx context.Context, ids []int, orgId int) ([]*host.Host, error)
+	GetAllHostsByOrgId(ctx context.Context, f *host.Filter, p *pagination.Page) ([]*host.Host, *pagination.Meta, error)
 	CreateHost(ctx context.Context, h *host.Host) error
-	UpdateHost(ctx context.Context, h *host.Host) error
-	DeleteHost(ctx context.Context, id int) error
-	GetHostsHealth(ctx context.Context, ids []int) ([]*host.HostHealth, error)
+	UpdateHost(ctx context.Context, h *host.Host, versions []time.Time) error
+	DeleteHost(ctx context.Context, id int, orgId int, versions []time.Time) error
+	GetHostsHealth(ctx context.Context, ids []int, orgId int) ([]*host.HostHealth, error)
 }
 
 type hostService struct {
-	repo repository.HostRepository
+	repo           repository.HostRepository
+	credentialRepo repository.CredentialRepository
 }
 
-func NewHostService(repo repository.HostRepository) HostService {
-	return &hostService{repo: repo}
+func NewHostService(repo repository.HostRepository, credentialRepo repository.CredentialRepository) HostService {
+	return &hostService{repo: repo, credentialRepo: credentialRepo}
 }
 
-func (s *hostService) GetHosts(ctx context.Context, ids []int) ([]*host.Host, error) {
-	return s.repo.GetHosts(ctx, ids)
+func (s *hostService) GetHosts(ctx context.Context, ids []int, orgId int) ([]*host.Host, error) {
+	ids = uniqueIds(ids)
+	hosts, err := s.repo.GetHosts(ctx, ids, orgId)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) != len(ids) {
+		return nil, sql.ErrNoRows
+	}
+	return hosts, nil
+}
+
+// uniqueIds drops repeated ids, keeping the order they were first given in, so that a
+// host asked for twice is still counted once against the hosts found
+func uniqueIds(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
-func (s *hostService) GetAllHostsByUserId(ctx context.Context, userId string) ([]*host.Host, error) {
-	return s.repo.GetAllHosts(ctx, userId)
+
+func (s *hostService) GetAllHostsByOrgId(ctx context.Context, f *host.Filter, p *pagination.Page) ([]*host.Host, *pagination.Meta, error) {
+	return s.repo.GetAllHosts(ctx, f, p)
 }
 
 func (s *hostService) CreateHost(ctx context.Context, h *host.Host) error {
+	if err := s.checkReferences(ctx, h); err != nil {
+		return err
+	}
 	return s.repo.CreateHost(ctx, h)
 }
-func (s *hostService) UpdateHost(ctx context.Context, h *host.Host) error {
-	return s.repo.UpdateHost(ctx, h)
+func (s *hostService) UpdateHost(ctx context.Context, h *host.Host, versions []time.Time) error {
+	if err := s.checkReferences(ctx, h); err != nil {
+		return err
+	}
+	return s.repo.UpdateHost(ctx, h, versions)
+}
+
+// checkReferences makes sure the host's credential belongs to its organization
+func (s *hostService) checkReferences(ctx context.Context, h *host.Host) error {
+	if h.CredentialID == nil {
+		return nil
+	}
+	var errs validation.Errors
+	if err := checkCredential(ctx, s.credentialRepo, &errs, *h.CredentialID, *h.OrgID); err != nil {
+		return err
+	}
+	return errs.Err()
 }
-func (s *hostService) DeleteHost(ctx context.Context, id int) error {
-	return s.repo.DeleteHost(ctx, id)
+func (s *hostService) DeleteHost(ctx context.Context, id int, orgId int, versions []time.Time) error {
+	return s.repo.DeleteHost(ctx, id, orgId, versions)
 }
 
-func (s *hostService) GetHostsHealth(ctx context.Context, ids []int) ([]*host.HostHealth, error) {
-	hosts, err := s.repo.GetHosts(ctx, ids)
+func (s *hostService) GetHostsHealth(ctx context.Context, ids []int, orgId int) ([]*host.HostHealth, error) {
+	ids = uniqueIds(ids)
+	hosts, err := s.repo.GetHosts(ctx, ids, orgId)
 	if err != nil {
 		return nil, err
 	}
+	// Refuse to dial anything unless every requested host belongs to the organization
+	if len(hosts) != len(ids) {
+		return nil, sql.ErrNoRows
+	}
 
 	var checkHosts []*host.Host
 	for _, h := range hosts {
@@ -2,53 +2,65 @@ package service
 
 import (
 	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/pagination"
 	"clouding/backend/internal/repository"
+	"clouding/backend/internal/validation"
 	"context"
+	"time"
 )
 
 type HostGroupService interface {
-	GetAllHostGroups(ctx context.Context, userId string) ([]*hostgroup.HostGroup, error)
-	GetHostGroupByID(ctx context.Context, id int) (*hostgroup.HostGroup, error)
+	GetAllHostGroups(ctx context.Context, f *hostgroup.Filter, p *pagination.Page) ([]*hostgroup.HostGroup, *pagination.Meta, error)
+	GetHostGroupByID(ctx context.Context, id int, orgId int) (*hostgroup.HostGroup, error)
 	CreateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error
-	UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error
-	AddHostsToGroup(ctx context.Context, groupID int, newHosts []int) error
-	RemoveHostFromGroup(ctx context.Context, groupID int, hostID int) error
-	DeleteHostGroup(ctx context.Context, id int) error
+	UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup, versions []time.Time) error
+	AddHostsToGroup(ctx context.Context, groupID int, orgId int, newHosts []int) error
+	RemoveHostFromGroup(ctx context.Context, groupID int, hostID int, orgId int) error
+	DeleteHostGroup(ctx context.Context, id int, orgId int, versions []time.Time) error
 }
 type hostGroupService struct {
-	repo repository.HostGroupRepository
+	repo     repository.HostGroupRepository
+	hostRepo repository.HostRepository
 }
 
-func NewHostGroupService(repo repository.HostGroupRepository) HostGroupService {
+func NewHostGroupService(repo repository.HostGroupRepository, hostRepo repository.HostRepository) HostGroupService {
 	return &hostGroupService{
-		repo: repo,
+		repo:     repo,
+		hostRepo: hostRepo,
 	}
 }
 
-func (s *hostGroupService) GetAllHostGroups(ctx context.Context, userId string) ([]*hostgroup.HostGroup, error) {
-	return s.repo.GetAllHostGroups(ctx, userId)
+func (s *hostGroupService) GetAllHostGroups(ctx context.Context, f *hostgroup.Filter, p *pagination.Page) ([]*hostgroup.HostGroup, *pagination.Meta, error) {
+	return s.repo.GetAllHostGroups(ctx, f, p)
 }
 
-func (s *hostGroupService) GetHostGroupByID(ctx context.Context, id int) (*hostgroup.HostGroup, error) {
-	return s.repo.GetHostGroupByID(ctx, id)
+func (s *hostGroupService) GetHostGroupByID(ctx context.Context, id int, orgId int) (*hostgroup.HostGroup, error) {
+	return s.repo.GetHostGroupByID(ctx, id, orgId)
 }
 
 func (s *hostGroupService) CreateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error {
 	return s.repo.CreateHostGroup(ctx, h)
 }
 
-func (s *hostGroupService) UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup) error {
-	return s.repo.UpdateHostGroup(ctx, h)
+func (s *hostGroupService) UpdateHostGroup(ctx context.Context, h *hostgroup.HostGroup, versions []time.Time) error {
+	return s.repo.UpdateHostGroup(ctx, h, versions)
 }
 
-func (s *hostGroupService) AddHostsToGroup(ctx context.Context, groupID int, newHosts []int) error {
-	return s.repo.AddHostsToGroup(ctx, groupID, newHosts)
+func (s *hostGroupService) AddHostsToGroup(ctx context.Context, groupID int, orgId int, newHosts []int) error {
+	var errs validation.Errors
+	if err := checkHosts(ctx, s.hostRepo, &errs, "hostIds", newHosts, orgId); err != nil {
+		return err
+	}
+	if err := errs.Err(); err != nil {
+		return err
+	}
+	return s.repo.AddHostsToGroup(ctx, groupID, orgId, newHosts)
 }
 
-func (s *hostGroupService) RemoveHostFromGroup(ctx context.Context, groupID int, hostID int) error {
-	return s.repo.RemoveHostFromGroup(ctx, groupID, hostID)
+func (s *hostGroupService) RemoveHostFromGroup(ctx context.Context, groupID int, hostID int, orgId int) error {
+	return s.repo.RemoveHostFromGroup(ctx, groupID, hostID, orgId)
 }
 
-func (s *hostGroupService) DeleteHostGroup(ctx context.Context, id int) error {
-	return s.repo.DeleteHostGroup(ctx, id)
+func (s *hostGroupService) DeleteHostGroup(ctx context.Context, id int, orgId int, versions []time.Time) error {
+	return s.repo.DeleteHostGroup(ctx, id, orgId, versions)
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/model/idempotency"
+	"clouding/backend/internal/repository"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotencyLockTimeout is how long a request holds its key before a retry may take it
+	// over, it only matters when an instance died while handling the request
+	idempotencyLockTimeout = time.Minute
+	// idempotencyCleanupInterval is how often expired keys are deleted
+	idempotencyCleanupInterval = 10 * time.Minute
+)
+
+type IdempotencyService interface {
+	// Begin claims key for a request. It returns nil when the request should run, or the
+	// stored key when the same request already succeeded and its response is to be replayed.
+	Begin(ctx context.Context, orgId int, userId string, key string, requestHash string) (*idempotency.Key, error)
+	// Complete stores the response of a successful request
+	Complete(ctx context.Context, orgId int, userId string, key string, statusCode int, response []byte) error
+	// Release frees the key of a failed request so that it can be retried
+	Release(ctx context.Context, orgId int, userId string, key string) error
+}
+
+type idempotencyService struct {
+	repo      repository.IdempotencyRepository
+	retention time.Duration
+	lifecycle *lifecycle.Lifecycle
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewIdempotencyService replays responses for retention after the request was first made
+func NewIdempotencyService(repo repository.IdempotencyRepository, retention time.Duration, lc *lifecycle.Lifecycle) IdempotencyService {
+	return &idempotencyService{repo: repo, retention: retention, lifecycle: lc, lastCleanup: time.Now()}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, orgId int, userId string, key string, requestHash string) (*idempotency.Key, error) {
+	s.cleanupExpired()
+
+	// A key released by a failed request between the claim and the read is claimed again
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := s.repo.ClaimKey(ctx, &idempotency.Key{
+			OrgID:       &orgId,
+			UserID:      &userId,
+			Key:         &key,
+			RequestHash: &requestHash,
+		}, s.retention, idempotencyLockTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, nil
+		}
+
+		existing, err := s.repo.GetKey(ctx, orgId, userId, key)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			continue
+		}
+		if *existing.RequestHash != requestHash {
+			return nil, apperrors.ErrIdempotencyKeyReused
+		}
+		if existing.StatusCode == nil {
+			return nil, apperrors.ErrIdempotencyKeyInProgress
+		}
+		return existing, nil
+	}
+	return nil, apperrors.ErrIdempotencyKeyInProgress
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, orgId int, userId string, key string, statusCode int, response []byte) error {
+	return s.repo.CompleteKey(ctx, orgId, userId, key, statusCode, response)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, orgId int, userId string, key string) error {
+	return s.repo.ReleaseKey(ctx, orgId, userId, key)
+}
+
+// cleanupExpired deletes expired keys at most once per idempotencyCleanupInterval, off the
+// request path. Claiming takes expired keys over, so this only keeps the table small.
+func (s *idempotencyService) cleanupExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastCleanup) < idempotencyCleanupInterval {
+		return
+	}
+	s.lastCleanup = time.Now()
+
+	s.lifecycle.Go("idempotency key cleanup", func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := s.repo.DeleteExpiredKeys(ctx, s.retention); err != nil {
+			slog.Warn("Failed to delete expired idempotency keys", "ERR", err)
+		}
+	})
+}
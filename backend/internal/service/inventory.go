@@ -0,0 +1,257 @@
+package service
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/host"
+	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/model/inventory"
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/utils/inventoryFile"
+	"clouding/backend/internal/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxInventoryHosts bounds the hosts a single import may list
+const maxInventoryHosts = 5000
+
+// InventoryService imports hosts and host groups from inventory files and exports them
+type InventoryService interface {
+	// Import creates the valid hosts of the file that don't exist yet by name and the groups
+	// they are listed in, nothing is changed on a dry run. Invalid hosts are reported by line.
+	Import(ctx context.Context, orgId int, userId string, req *inventory.ImportRequest) (*inventory.ImportResult, error)
+	Export(ctx context.Context, orgId int, format inventory.Format) ([]byte, error)
+}
+
+type inventoryService struct {
+	repo           repository.InventoryRepository
+	credentialRepo repository.CredentialRepository
+}
+
+func NewInventoryService(repo repository.InventoryRepository, credentialRepo repository.CredentialRepository) InventoryService {
+	return &inventoryService{repo: repo, credentialRepo: credentialRepo}
+}
+
+func (s *inventoryService) Import(ctx context.Context, orgId int, userId string, req *inventory.ImportRequest) (*inventory.ImportResult, error) {
+	parsed, err := inventoryFile.Parse(*req.Format, *req.Content)
+	if err != nil {
+		return nil, apperrors.ErrInvalidInventory.Withf("inventory could not be read: %s", err)
+	}
+	if len(parsed.Entries) > maxInventoryHosts {
+		return nil, apperrors.ErrInventoryTooLarge.Withf("inventory lists %d hosts, at most %d can be imported at once", len(parsed.Entries), maxInventoryHosts)
+	}
+	if req.CredentialID != nil {
+		var errs validation.Errors
+		if err := checkCredential(ctx, s.credentialRepo, &errs, *req.CredentialID, orgId); err != nil {
+			return nil, err
+		}
+		if err := errs.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	existingHosts, existingGroups, err := s.repo.GetInventory(ctx, orgId)
+	if err != nil {
+		return nil, err
+	}
+	hostIDs := make(map[string]*int, len(existingHosts))
+	for _, h := range existingHosts {
+		if _, ok := hostIDs[*h.Name]; !ok {
+			hostIDs[*h.Name] = h.ID
+		}
+	}
+	groups := make(map[string]*hostgroup.HostGroup, len(existingGroups))
+	for _, g := range existingGroups {
+		if _, ok := groups[*g.Name]; !ok {
+			groups[*g.Name] = g
+		}
+	}
+
+	result := &inventory.ImportResult{
+		DryRun:   req.DryRun,
+		Hosts:    []*inventory.ImportedHost{},
+		Groups:   []*inventory.ImportedGroup{},
+		Errors:   append([]inventory.Issue{}, parsed.Errors...),
+		Warnings: append([]inventory.Issue{}, parsed.Warnings...),
+	}
+
+	// Groups are matched by name, the missing ones are created
+	var newGroups []*hostgroup.HostGroup
+	imported := map[string]*inventory.ImportedGroup{}
+	for _, g := range parsed.Groups {
+		name := g.Name
+		if existing, ok := groups[name]; ok {
+			imported[name] = &inventory.ImportedGroup{ID: existing.ID, Name: name, Action: inventory.ActionExisting, Hosts: []string{}}
+			result.Groups = append(result.Groups, imported[name])
+			continue
+		}
+		hg := &hostgroup.HostGroup{Name: &name, UserID: &userId, OrgID: &orgId}
+		details, err := invalidFields(validation.Check(hg))
+		if err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			for _, d := range details {
+				result.Errors = append(result.Errors, inventory.Issue{Line: g.Line, Field: "group", Code: d.Code, Message: "group " + name + " " + d.Message})
+			}
+			continue
+		}
+		groups[name] = hg
+		newGroups = append(newGroups, hg)
+		imported[name] = &inventory.ImportedGroup{Name: name, Action: inventory.ActionCreate, Hosts: []string{}}
+		result.Groups = append(result.Groups, imported[name])
+	}
+
+	var newHosts []*host.Host
+	var newHostGroups []*hostgroup.HostGroup
+	var newRows []*inventory.ImportedHost
+	credentials := map[string]validation.Errors{}
+	seen := map[string]bool{}
+	for _, e := range parsed.Entries {
+		h := entryHost(e, orgId, userId, req)
+		var metaErr error
+		if len(e.Vars) > 0 {
+			var b []byte
+			if b, metaErr = json.Marshal(e.Vars); metaErr == nil {
+				meta := json.RawMessage(b)
+				h.MetaData = &meta
+			}
+		}
+		row := &inventory.ImportedHost{Line: e.Line, Name: h.Name, IP: h.IP, Os: h.Os, CredentialID: h.CredentialID, MetaData: h.MetaData}
+
+		errs, err := invalidFields(validation.Check(h))
+		if err != nil {
+			return nil, err
+		}
+		if metaErr != nil {
+			// YAML allows keys JSON doesn't, such as lists
+			errs.Add("metaData", validation.CodeInvalid, "host variables must have string keys")
+		}
+		if h.CredentialID != nil && !hasField(errs, "credentialId") {
+			credentialErrs, ok := credentials[*h.CredentialID]
+			if !ok {
+				if err := checkCredential(ctx, s.credentialRepo, &credentialErrs, *h.CredentialID, orgId); err != nil {
+					return nil, err
+				}
+				credentials[*h.CredentialID] = credentialErrs
+			}
+			errs = append(errs, credentialErrs...)
+		}
+		if seen[e.Name] {
+			errs.Add("name", inventoryFile.CodeDuplicate, "is listed more than once")
+		}
+		seen[e.Name] = true
+
+		var group *hostgroup.HostGroup
+		if len(e.Groups) > 0 {
+			name := e.Groups[0]
+			row.Group = &name
+			if imported[name] == nil {
+				errs.Add("group", validation.CodeInvalid, "group "+name+" is invalid")
+			}
+			group = groups[name]
+			// host_groups_to_host_mapping holds a single group per host
+			if len(e.Groups) > 1 {
+				result.Warnings = append(result.Warnings, inventory.Issue{
+					Line: e.Line, Host: e.Name, Field: "group", Code: inventoryFile.CodeIgnored,
+					Message: fmt.Sprintf("a host belongs to a single group, it is added to %s but not to %s", name, strings.Join(e.Groups[1:], ", ")),
+				})
+			}
+		}
+
+		switch {
+		case len(errs) > 0:
+			row.Action = inventory.ActionFail
+			result.Failed++
+			for _, d := range errs {
+				result.Errors = append(result.Errors, inventory.Issue{Line: e.Line, Host: e.Name, Field: d.Field, Code: d.Code, Message: d.Message})
+			}
+		case hostIDs[e.Name] != nil:
+			row.Action = inventory.ActionSkip
+			row.ID = hostIDs[e.Name]
+			result.Skipped++
+		default:
+			row.Action = inventory.ActionCreate
+			result.Created++
+			newHosts = append(newHosts, h)
+			newHostGroups = append(newHostGroups, group)
+			newRows = append(newRows, row)
+			if group != nil {
+				imported[*row.Group].Hosts = append(imported[*row.Group].Hosts, e.Name)
+			}
+		}
+		result.Hosts = append(result.Hosts, row)
+	}
+
+	// Problems found while reading the file and with its hosts are reported in file order
+	byLine := func(a, b inventory.Issue) int { return a.Line - b.Line }
+	slices.SortStableFunc(result.Errors, byLine)
+	slices.SortStableFunc(result.Warnings, byLine)
+
+	if req.DryRun || (len(newHosts) == 0 && len(newGroups) == 0) {
+		return result, nil
+	}
+	if err := s.repo.ImportInventory(ctx, orgId, newGroups, newHosts, newHostGroups); err != nil {
+		return nil, err
+	}
+	for i, h := range newHosts {
+		newRows[i].ID = h.ID
+	}
+	for _, g := range result.Groups {
+		g.ID = groups[g.Name].ID
+	}
+	return result, nil
+}
+
+// entryHost is the host an entry describes, the request's defaults fill in what it leaves out
+// and Ansible's convention of using the name as the address applies
+func entryHost(e *inventoryFile.Entry, orgId int, userId string, req *inventory.ImportRequest) *host.Host {
+	h := &host.Host{UserID: &userId, OrgID: &orgId, Name: &e.Name}
+	ip := e.IP
+	if ip == "" {
+		ip = e.Name
+	}
+	h.IP = &ip
+	h.Os = req.Os
+	if e.Os != "" {
+		h.Os = &e.Os
+	}
+	h.CredentialID = req.CredentialID
+	if e.CredentialID != "" {
+		h.CredentialID = &e.CredentialID
+	}
+	return h
+}
+
+func (s *inventoryService) Export(ctx context.Context, orgId int, format inventory.Format) ([]byte, error) {
+	hosts, groups, err := s.repo.GetInventory(ctx, orgId)
+	if err != nil {
+		return nil, err
+	}
+	return inventoryFile.Write(format, hosts, groups)
+}
+
+// invalidFields are the details of a validation error, any other error is returned as is
+func invalidFields(err error) (validation.Errors, error) {
+	if err == nil {
+		return nil, nil
+	}
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) && errors.Is(err, apperrors.ErrValidationFailed) {
+		return appErr.Details, nil
+	}
+	return nil, err
+}
+
+func hasField(errs validation.Errors, field string) bool {
+	for _, d := range errs {
+		if d.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -7,7 +7,7 @@ import (
 )
 
 type MetricService interface {
-	GetOverview(ctx context.Context, userId string) ([]*metric.Overview, error)
+	GetOverview(ctx context.Context, orgId int) ([]*metric.Overview, error)
 }
 
 type metricService struct {
@@ -20,6 +20,6 @@ func NewMetricService(repo repository.MetricRepository) MetricService {
 	}
 }
 
-func (r *metricService) GetOverview(ctx context.Context, userId string) ([]*metric.Overview, error) {
-	return r.repo.GetOverview(ctx, userId)
+func (r *metricService) GetOverview(ctx context.Context, orgId int) ([]*metric.Overview, error) {
+	return r.repo.GetOverview(ctx, orgId)
 }
@@ -0,0 +1,176 @@
+package service
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/organization"
+	"clouding/backend/internal/repository"
+	"context"
+	"database/sql"
+)
+
+const personalOrganizationName = "Personal"
+
+type OrganizationService interface {
+	ResolveMembership(ctx context.Context, userId string, orgId *int) (*organization.Membership, error)
+	GetOrganizations(ctx context.Context, userId string) ([]*organization.Organization, error)
+	Create(ctx context.Context, org *organization.Organization) error
+	GetMembers(ctx context.Context, orgId int, callerId string) ([]*organization.Membership, error)
+	SetMember(ctx context.Context, callerId string, m *organization.Membership) error
+	RemoveMember(ctx context.Context, orgId int, callerId string, userId string) error
+	// GrantMembership sets a member's role without checking a caller, for operator tooling
+	GrantMembership(ctx context.Context, m *organization.Membership) error
+}
+
+type organizationService struct {
+	repo repository.OrganizationRepository
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository) OrganizationService {
+	return &organizationService{repo: repo}
+}
+
+// ResolveMembership returns the caller's membership in orgId, or in their personal
+// organization when orgId is nil. The personal organization is created on first use.
+func (s *organizationService) ResolveMembership(ctx context.Context, userId string, orgId *int) (*organization.Membership, error) {
+	if orgId != nil {
+		m, err := s.repo.GetMembership(ctx, *orgId, userId)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, sql.ErrNoRows
+		}
+		return m, nil
+	}
+
+	org, err := s.repo.GetPersonalOrganization(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		name := personalOrganizationName
+		personal := true
+		org = &organization.Organization{Name: &name, Personal: &personal, CreatedBy: &userId}
+		if err := s.repo.CreateOrganization(ctx, org); err != nil {
+			return nil, err
+		}
+		// Lost a race with a concurrent request creating the same personal organization
+		if org.ID == nil {
+			if org, err = s.repo.GetPersonalOrganization(ctx, userId); err != nil {
+				return nil, err
+			}
+			if org == nil {
+				return nil, sql.ErrNoRows
+			}
+		}
+	}
+
+	return &organization.Membership{
+		OrganizationID: org.ID,
+		UserID:         &userId,
+		Role:           org.Role,
+	}, nil
+}
+
+func (s *organizationService) GetOrganizations(ctx context.Context, userId string) ([]*organization.Organization, error) {
+	return s.repo.GetOrganizationsByUserId(ctx, userId)
+}
+
+func (s *organizationService) Create(ctx context.Context, org *organization.Organization) error {
+	personal := false
+	org.Personal = &personal
+	return s.repo.CreateOrganization(ctx, org)
+}
+
+func (s *organizationService) GetMembers(ctx context.Context, orgId int, callerId string) ([]*organization.Membership, error) {
+	if _, err := s.authorize(ctx, orgId, callerId, organization.PermMembersRead); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMembers(ctx, orgId)
+}
+
+func (s *organizationService) SetMember(ctx context.Context, callerId string, m *organization.Membership) error {
+	caller, err := s.authorize(ctx, *m.OrganizationID, callerId, organization.PermMembersWrite)
+	if err != nil {
+		return err
+	}
+	// Nobody can hand out a role above their own
+	if !caller.Role.AtLeast(*m.Role) {
+		return apperrors.ErrForbidden
+	}
+
+	existing, err := s.repo.GetMembership(ctx, *m.OrganizationID, *m.UserID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && *existing.Role == organization.RoleOwner && *m.Role != organization.RoleOwner {
+		if *caller.Role != organization.RoleOwner {
+			return apperrors.ErrForbidden
+		}
+		if err := s.ensureAnotherOwner(ctx, *m.OrganizationID); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.UpsertMembership(ctx, m)
+}
+
+func (s *organizationService) GrantMembership(ctx context.Context, m *organization.Membership) error {
+	if m.Role == nil || !m.Role.IsValid() {
+		return apperrors.ErrInvalidRole
+	}
+	return s.repo.UpsertMembership(ctx, m)
+}
+
+func (s *organizationService) RemoveMember(ctx context.Context, orgId int, callerId string, userId string) error {
+	existing, err := s.repo.GetMembership(ctx, orgId, userId)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return sql.ErrNoRows
+	}
+
+	// Members can always leave, removing somebody else needs members:write
+	if callerId != userId {
+		caller, err := s.authorize(ctx, orgId, callerId, organization.PermMembersWrite)
+		if err != nil {
+			return err
+		}
+		if !caller.Role.AtLeast(*existing.Role) {
+			return apperrors.ErrForbidden
+		}
+	}
+	if *existing.Role == organization.RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, orgId); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.DeleteMembership(ctx, orgId, userId)
+}
+
+func (s *organizationService) authorize(ctx context.Context, orgId int, userId string, perm organization.Permission) (*organization.Membership, error) {
+	m, err := s.repo.GetMembership(ctx, orgId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, sql.ErrNoRows
+	}
+	if !m.Role.Can(perm) {
+		return nil, apperrors.ErrForbidden
+	}
+	return m, nil
+}
+
+func (s *organizationService) ensureAnotherOwner(ctx context.Context, orgId int) error {
+	owners, err := s.repo.CountOwners(ctx, orgId)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return apperrors.ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"clouding/backend/internal/repository"
+	"clouding/backend/internal/validation"
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Requests may only reference resources of the caller's organization. The checks below
+// add a not_found detail for every reference that isn't one, so the caller learns about
+// all of them at once.
+
+// checkHosts reports every id, as field[i], that is not a host of the organization
+func checkHosts(ctx context.Context, repo repository.HostRepository, errs *validation.Errors, field string, ids []int, orgId int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	hosts, err := repo.GetHosts(ctx, ids, orgId)
+	if err != nil {
+		return err
+	}
+	found := make(map[int]bool, len(hosts))
+	for _, h := range hosts {
+		found[*h.ID] = true
+	}
+	for i, id := range ids {
+		if !found[id] {
+			errs.Add(fmt.Sprintf("%s[%d]", field, i), validation.CodeNotFound, fmt.Sprintf("host %d does not exist in this organization", id))
+		}
+	}
+	return nil
+}
+
+// checkCredential reports credentialId when it is not a credential of the organization,
+// the id was already checked to be numeric
+func checkCredential(ctx context.Context, repo repository.CredentialRepository, errs *validation.Errors, credentialId string, orgId int) error {
+	id, err := strconv.Atoi(credentialId)
+	if err != nil {
+		errs.Add("credentialId", validation.CodeInvalid, "must be a number")
+		return nil
+	}
+	cred, err := repo.GetCredential(ctx, id, orgId, false)
+	if err != nil {
+		return err
+	}
+	if cred == nil {
+		errs.Add("credentialId", validation.CodeNotFound, fmt.Sprintf("credential %d does not exist in this organization", id))
+	}
+	return nil
+}
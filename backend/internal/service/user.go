@@ -12,6 +12,8 @@ type UserService interface {
 	CreateUser(ctx context.Context, u *user.User) error
 	UpdateUser(ctx context.Context, u *user.User) error
 	DeleteUser(ctx context.Context, id string) error
+	// ProvisionUser creates or updates an account that can sign in, for operator tooling
+	ProvisionUser(ctx context.Context, u *user.User) error
 }
 
 type userService struct {
@@ -37,3 +39,7 @@ func (s *userService) UpdateUser(ctx context.Context, u *user.User) error {
 func (s *userService) DeleteUser(ctx context.Context, id string) error {
 	return s.repo.DeleteUser(ctx, id)
 }
+
+func (s *userService) ProvisionUser(ctx context.Context, u *user.User) error {
+	return s.repo.UpsertAuthUser(ctx, u)
+}
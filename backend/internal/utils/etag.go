@@ -0,0 +1,93 @@
+package utils
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETags are the resource's updated_at in microseconds, the precision Postgres stores,
+// so a tag read back from If-Match compares equal to the column
+
+// SetETag sets the ETag header to the version of a resource, nothing is set for a nil version
+func SetETag(c *gin.Context, updatedAt *time.Time) {
+	if updatedAt == nil {
+		return
+	}
+	c.Header("ETag", `"`+strconv.FormatInt(updatedAt.UnixMicro(), 10)+`"`)
+}
+
+// IfMatch reads the If-Match header as the versions an update or delete must still find
+// one of. They are nil when the header is missing or *, the write is then unconditional.
+// Weak or unknown tags can never match, ErrPreconditionFailed is returned right away when
+// the header has no other tags. A header that isn't a list of tags is ErrInvalidIfMatch.
+func IfMatch(c *gin.Context) ([]time.Time, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	tags, ok := entityTags(header)
+	if !ok {
+		return nil, apperrors.ErrInvalidIfMatch
+	}
+	versions := []time.Time{}
+	for _, tag := range tags {
+		// Weak tags, W/"...", never match under the strong comparison If-Match uses
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		micros, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, time.UnixMicro(micros))
+	}
+	if len(versions) == 0 {
+		return nil, apperrors.ErrPreconditionFailed
+	}
+	return versions, nil
+}
+
+// entityTags splits a comma separated list of entity tags as RFC 9110 defines it, empty
+// elements are skipped and a tag may itself contain commas. ok is false when an element
+// is not a tag or the list has none.
+func entityTags(header string) (tags []string, ok bool) {
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return tags, len(tags) > 0
+		}
+		if rest[0] == ',' {
+			rest = rest[1:]
+			continue
+		}
+		opaque := strings.TrimPrefix(rest, "W/")
+		if !strings.HasPrefix(opaque, `"`) {
+			return nil, false
+		}
+		end := strings.IndexByte(opaque[1:], '"')
+		if end < 0 || !isETagText(opaque[1:1+end]) {
+			return nil, false
+		}
+		n := len(rest) - len(opaque) + end + 2
+		tags = append(tags, rest[:n])
+		rest = strings.TrimLeft(rest[n:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, false
+		}
+	}
+}
+
+// isETagText reports whether s only has the characters allowed between the quotes of a tag
+func isETagText(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b < 0x21 || b == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	v1, v2 := time.UnixMicro(1760693773286072), time.UnixMicro(1760693773286073)
+	tests := []struct {
+		name    string
+		header  string
+		want    []time.Time
+		wantErr error
+	}{
+		{"missing", "", nil, nil},
+		{"any", "*", nil, nil},
+		{"tag", `"1760693773286072"`, []time.Time{v1}, nil},
+		{"list", `"1760693773286072", "1760693773286073"`, []time.Time{v1, v2}, nil},
+		{"list without spaces", `"1760693773286072","1760693773286073"`, []time.Time{v1, v2}, nil},
+		{"empty elements", `, "1760693773286072",,`, []time.Time{v1}, nil},
+		{"weak and unknown tags are skipped", `W/"1760693773286073", "a,b", "1760693773286072"`, []time.Time{v1}, nil},
+		{"only a weak tag", `W/"1760693773286072"`, nil, apperrors.ErrPreconditionFailed},
+		{"unknown tag", `"abc"`, nil, apperrors.ErrPreconditionFailed},
+		{"unquoted", `1760693773286072`, nil, apperrors.ErrInvalidIfMatch},
+		{"unquoted entry", `"1760693773286072", 1760693773286073`, nil, apperrors.ErrInvalidIfMatch},
+		{"unclosed", `"1760693773286072`, nil, apperrors.ErrInvalidIfMatch},
+		{"missing comma", `"1760693773286072" "1760693773286073"`, nil, apperrors.ErrInvalidIfMatch},
+		{"star in a list", `*, "1760693773286072"`, nil, apperrors.ErrInvalidIfMatch},
+		{"only commas", `, ,`, nil, apperrors.ErrInvalidIfMatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("PUT", "/hosts/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+			got, err := IfMatch(c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package inventoryFile
+
+import (
+	"bytes"
+	"clouding/backend/internal/model/host"
+	hostgroup "clouding/backend/internal/model/hostGroup"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Host variables that are host fields rather than meta data
+const (
+	varAnsibleHost  = "ansible_host"
+	varOs           = "os"
+	varCredentialID = "credential_id"
+)
+
+// ansibleHost records a host listed under group, "" when ungrouped. Ansible merges a host
+// listed several times, so does this, its line is where it first appears.
+func (p *Parsed) ansibleHost(line int, name string, group string, vars map[string]any) {
+	var e *Entry
+	for _, existing := range p.Entries {
+		if existing.Name == name {
+			e = existing
+			break
+		}
+	}
+	if e == nil {
+		e = &Entry{Line: line, Name: name, Vars: map[string]any{}}
+		p.Entries = append(p.Entries, e)
+	}
+	for k, v := range vars {
+		switch k {
+		case varAnsibleHost:
+			e.IP = text(v)
+		case varOs:
+			e.Os = text(v)
+		case varCredentialID:
+			e.CredentialID = text(v)
+		default:
+			e.Vars[k] = v
+		}
+	}
+	if group == "" {
+		return
+	}
+	for _, g := range e.Groups {
+		if g == group {
+			return
+		}
+	}
+	e.Groups = append(e.Groups, group)
+}
+
+// parseAnsibleINI reads an INI inventory: hosts with key=value variables under [group]
+// sections. [group:vars] and [group:children] sections are skipped with a warning.
+func parseAnsibleINI(content string) (*Parsed, error) {
+	p := &Parsed{}
+	group := ""
+	skip := false
+	for i, raw := range lines(content) {
+		n := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			skip = true
+			if !strings.HasSuffix(line, "]") {
+				p.errorf(n, "", "", CodeSyntax, "section header is missing ]")
+				continue
+			}
+			name, kind, _ := strings.Cut(strings.TrimSpace(line[1:len(line)-1]), ":")
+			switch kind {
+			case "":
+				skip = false
+				group = name
+				if isUngrouped(name) {
+					group = ""
+				} else {
+					p.group(name, n)
+				}
+			case "vars":
+				p.warnf(n, "", "", CodeIgnored, "group variables of %s are not imported, set them on the hosts", name)
+			case "children":
+				p.warnf(n, "", "", CodeIgnored, "child groups of %s are not imported, hosts are added to the group that lists them", name)
+			default:
+				p.errorf(n, "", "", CodeSyntax, "unknown section type %s", kind)
+			}
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		tokens, err := fields(line)
+		if err != nil {
+			p.errorf(n, "", "", CodeSyntax, "%s", err)
+			continue
+		}
+		name := tokens[0]
+		if isHostRange(name) {
+			p.errorf(n, name, "name", CodeUnsupported, "host ranges are not supported, list the hosts one by one")
+			continue
+		}
+		vars := map[string]any{}
+		valid := true
+		for _, token := range tokens[1:] {
+			k, v, ok := strings.Cut(token, "=")
+			if !ok || k == "" {
+				p.errorf(n, name, "", CodeSyntax, "%q is not a key=value variable", token)
+				valid = false
+				break
+			}
+			vars[k] = v
+		}
+		if valid {
+			p.ansibleHost(n, name, group, vars)
+		}
+	}
+	return p, nil
+}
+
+// parseAnsibleYAML reads a YAML inventory, a mapping of groups, usually just all, with
+// hosts and their variables, child groups and group variables, which are skipped
+func parseAnsibleYAML(content string) (*Parsed, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	p := &Parsed{}
+	if len(doc.Content) == 0 {
+		return p, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: the inventory must be a mapping of groups", root.Line)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		p.yamlGroup(root.Content[i], root.Content[i+1])
+	}
+	return p, nil
+}
+
+func (p *Parsed) yamlGroup(key *yaml.Node, node *yaml.Node) {
+	name := key.Value
+	group := name
+	if isUngrouped(name) {
+		group = ""
+	} else {
+		p.group(name, key.Line)
+	}
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node.Line, "", "", CodeSyntax, "group %s must be a mapping of hosts, children and vars", name)
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		switch k.Value {
+		case "hosts":
+			p.yamlHosts(group, v)
+		case "children":
+			if isNull(v) {
+				continue
+			}
+			if v.Kind != yaml.MappingNode {
+				p.errorf(v.Line, "", "", CodeSyntax, "children of %s must be a mapping of groups", name)
+				continue
+			}
+			// Hosts of a child group are added to the child group only
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				p.yamlGroup(v.Content[j], v.Content[j+1])
+			}
+		case "vars":
+			p.warnf(k.Line, "", "", CodeIgnored, "group variables of %s are not imported, set them on the hosts", name)
+		default:
+			p.errorf(k.Line, "", "", CodeSyntax, "unknown key %s in group %s, expected hosts, children or vars", k.Value, name)
+		}
+	}
+}
+
+func (p *Parsed) yamlHosts(group string, node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node.Line, "", "", CodeSyntax, "hosts must be a mapping of host names to their variables")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		name := k.Value
+		if isHostRange(name) {
+			p.errorf(k.Line, name, "name", CodeUnsupported, "host ranges are not supported, list the hosts one by one")
+			continue
+		}
+		vars := map[string]any{}
+		if !isNull(v) {
+			if err := v.Decode(&vars); err != nil {
+				p.errorf(v.Line, name, "", CodeSyntax, "host variables must be a mapping")
+				continue
+			}
+		}
+		p.ansibleHost(k.Line, name, group, vars)
+	}
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// hostVars are the variables a host is exported with
+func hostVars(h *host.Host) map[string]any {
+	vars := map[string]any{}
+	for k, v := range metaVars(h) {
+		vars[identifier(k)] = v
+	}
+	vars[varAnsibleHost] = str(h.IP)
+	vars[varOs] = str(h.Os)
+	if h.CredentialID != nil {
+		vars[varCredentialID] = *h.CredentialID
+	}
+	return vars
+}
+
+// writeAnsibleINI lists ungrouped hosts first, then every group, hosts with their fields
+// and meta data as variables
+func writeAnsibleINI(hosts []*host.Host, groups []*hostgroup.HostGroup, groupOf map[int]string) []byte {
+	members := map[string][]*host.Host{}
+	for _, h := range hosts {
+		members[groupOf[*h.ID]] = append(members[groupOf[*h.ID]], h)
+	}
+
+	var buf bytes.Buffer
+	section := func(name string, hosts []*host.Host) {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "[%s]\n", name)
+		for _, h := range hosts {
+			vars := hostVars(h)
+			buf.WriteString(quote(str(h.Name)))
+			// The host's fields first, the meta data after in a stable order
+			for _, k := range []string{varAnsibleHost, varOs, varCredentialID} {
+				if v, ok := vars[k]; ok {
+					fmt.Fprintf(&buf, " %s=%s", k, quote(text(v)))
+					delete(vars, k)
+				}
+			}
+			for _, k := range sortedKeys(vars) {
+				fmt.Fprintf(&buf, " %s=%s", k, quote(text(vars[k])))
+			}
+			buf.WriteString("\n")
+		}
+	}
+
+	section("ungrouped", members[""])
+	for _, g := range groups {
+		section(identifier(str(g.Name)), members[str(g.Name)])
+	}
+	return buf.Bytes()
+}
+
+// writeAnsibleYAML nests every group under all's children, ungrouped hosts are all's own
+func writeAnsibleYAML(hosts []*host.Host, groups []*hostgroup.HostGroup, groupOf map[int]string) ([]byte, error) {
+	ungrouped := map[string]any{}
+	children := map[string]any{}
+	for _, g := range groups {
+		children[identifier(str(g.Name))] = map[string]any{}
+	}
+	for _, h := range hosts {
+		group, ok := groupOf[*h.ID]
+		if !ok {
+			ungrouped[str(h.Name)] = hostVars(h)
+			continue
+		}
+		g := children[identifier(group)].(map[string]any)
+		if g["hosts"] == nil {
+			g["hosts"] = map[string]any{}
+		}
+		g["hosts"].(map[string]any)[str(h.Name)] = hostVars(h)
+	}
+
+	all := map[string]any{}
+	if len(ungrouped) > 0 {
+		all["hosts"] = ungrouped
+	}
+	if len(children) > 0 {
+		all["children"] = children
+	}
+	return yaml.Marshal(map[string]any{"all": all})
+}
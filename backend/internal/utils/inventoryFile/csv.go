@@ -0,0 +1,144 @@
+package inventoryFile
+
+import (
+	"bytes"
+	"clouding/backend/internal/model/host"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// csvColumns maps the header names, lower cased and without separators, to the host's
+// fields. Other columns become meta data.
+var csvColumns = map[string]string{
+	"name":         "name",
+	"host":         "name",
+	"hostname":     "name",
+	"ip":           "ip",
+	"address":      "ip",
+	"ansiblehost":  "ip",
+	"os":           "os",
+	"credentialid": "credentialId",
+	"group":        "group",
+	"groups":       "group",
+	"metadata":     "metaData",
+}
+
+// parseCSV reads a header row followed by a host per row. group may list several groups
+// separated by ; and metaData holds a JSON object.
+func parseCSV(content string) (*Parsed, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the file is empty, a header row is expected")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(header))
+	hasName := false
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		header[i] = name
+		key := strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(name))
+		columns[i] = csvColumns[key]
+		hasName = hasName || columns[i] == "name"
+	}
+	if !hasName {
+		return nil, errors.New("the header row must have a name column")
+	}
+
+	p := &Parsed{}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		if len(record) > len(header) {
+			p.errorf(line, "", "", CodeSyntax, "row has %d fields but the header only %d", len(record), len(header))
+			continue
+		}
+
+		e := &Entry{Line: line, Vars: map[string]any{}}
+		var metaErr error
+		empty := true
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			empty = false
+			switch columns[i] {
+			case "name":
+				e.Name = value
+			case "ip":
+				e.IP = value
+			case "os":
+				e.Os = value
+			case "credentialId":
+				e.CredentialID = value
+			case "group":
+				for _, g := range strings.Split(value, ";") {
+					if g = strings.TrimSpace(g); g != "" {
+						e.Groups = append(e.Groups, g)
+					}
+				}
+			case "metaData":
+				var meta map[string]any
+				if err := json.Unmarshal([]byte(value), &meta); err != nil {
+					metaErr = err
+					continue
+				}
+				for k, v := range meta {
+					e.Vars[k] = v
+				}
+			default:
+				e.Vars[header[i]] = value
+			}
+		}
+		if empty {
+			continue
+		}
+		if metaErr != nil {
+			p.errorf(line, e.Name, "metaData", CodeSyntax, "must be a JSON object")
+			continue
+		}
+		for _, g := range e.Groups {
+			p.group(g, line)
+		}
+		p.Entries = append(p.Entries, e)
+	}
+	return p, nil
+}
+
+func writeCSV(hosts []*host.Host, groupOf map[int]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"name", "ip", "os", "credentialId", "group", "metaData"}); err != nil {
+		return nil, err
+	}
+	for _, h := range hosts {
+		meta := ""
+		if h.MetaData != nil && string(*h.MetaData) != "null" {
+			meta = string(*h.MetaData)
+		}
+		group := ""
+		if h.ID != nil {
+			group = groupOf[*h.ID]
+		}
+		if err := w.Write([]string{str(h.Name), str(h.IP), str(h.Os), str(h.CredentialID), group, meta}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,284 @@
+package inventoryFile
+
+import (
+	"clouding/backend/internal/model/host"
+	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/model/inventory"
+	"clouding/backend/internal/utils/redact"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Codes of the issues found while reading a file
+const (
+	CodeSyntax      = "syntax"
+	CodeUnsupported = "unsupported"
+	CodeIgnored     = "ignored"
+	CodeDuplicate   = "duplicate"
+)
+
+// Entry is a host as read from a file, fields the file doesn't set are empty
+type Entry struct {
+	Line         int
+	Name         string
+	IP           string
+	Os           string
+	CredentialID string
+	// Groups are the groups that list the host, in the order they appear
+	Groups []string
+	// Vars are the remaining settings of the host, they become its meta data
+	Vars map[string]any
+}
+
+// Group is a group declared by a file, it may have no hosts
+type Group struct {
+	Line int
+	Name string
+}
+
+// Parsed is the content of a file. Lines that could not be read are left out of Entries
+// and listed in Errors, Warnings list what the formats can express but hosts can't hold.
+type Parsed struct {
+	Entries  []*Entry
+	Groups   []Group
+	Errors   []inventory.Issue
+	Warnings []inventory.Issue
+}
+
+func (p *Parsed) errorf(line int, hostName string, field string, code string, format string, args ...any) {
+	p.Errors = append(p.Errors, inventory.Issue{Line: line, Host: hostName, Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *Parsed) warnf(line int, hostName string, field string, code string, format string, args ...any) {
+	p.Warnings = append(p.Warnings, inventory.Issue{Line: line, Host: hostName, Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// group declares a group once, where it first appears
+func (p *Parsed) group(name string, line int) {
+	for _, g := range p.Groups {
+		if g.Name == name {
+			return
+		}
+	}
+	p.Groups = append(p.Groups, Group{Line: line, Name: name})
+}
+
+// Parse reads a file of the given format. The error is set when the file can't be read
+// at all, e.g. a YAML syntax error, problems with single lines are listed in Errors.
+func Parse(format inventory.Format, content string) (*Parsed, error) {
+	var p *Parsed
+	var err error
+	switch format {
+	case inventory.FormatCSV:
+		p, err = parseCSV(content)
+	case inventory.FormatAnsibleINI:
+		p, err = parseAnsibleINI(content)
+	case inventory.FormatAnsibleYAML:
+		p, err = parseAnsibleYAML(content)
+	case inventory.FormatSSHConfig:
+		p, err = parseSSHConfig(content)
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.dropSecrets()
+	return p, nil
+}
+
+// passwordVars are the Ansible connection passwords redact doesn't recognize by name
+var passwordVars = map[string]bool{
+	"ansible_pass":        true,
+	"ansible_ssh_pass":    true,
+	"ansible_become_pass": true,
+	"ansible_sudo_pass":   true,
+	"ansible_su_pass":     true,
+}
+
+// dropSecrets removes the variables holding passwords or keys, meta data is stored and
+// returned in plain text. Each is reported so the secret can be moved to a credential.
+func (p *Parsed) dropSecrets() {
+	for _, e := range p.Entries {
+		for _, k := range sortedKeys(e.Vars) {
+			if redact.IsSensitive(k) || passwordVars[strings.ToLower(k)] {
+				delete(e.Vars, k)
+				p.warnf(e.Line, e.Name, k, CodeIgnored, "%s is not imported, create a credential for the host instead", k)
+			}
+		}
+	}
+}
+
+// Write renders the hosts and the groups they belong to in the given format
+func Write(format inventory.Format, hosts []*host.Host, groups []*hostgroup.HostGroup) ([]byte, error) {
+	// A host belongs to at most one group
+	groupOf := map[int]string{}
+	for _, g := range groups {
+		for _, id := range g.HostIds {
+			groupOf[int(id)] = str(g.Name)
+		}
+	}
+	switch format {
+	case inventory.FormatCSV:
+		return writeCSV(hosts, groupOf)
+	case inventory.FormatAnsibleINI:
+		return writeAnsibleINI(hosts, groups, groupOf), nil
+	case inventory.FormatAnsibleYAML:
+		return writeAnsibleYAML(hosts, groups, groupOf)
+	case inventory.FormatSSHConfig:
+		return writeSSHConfig(hosts), nil
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+}
+
+// ContentType is the media type of an exported file
+func ContentType(format inventory.Format) string {
+	switch format {
+	case inventory.FormatCSV:
+		return "text/csv; charset=utf-8"
+	case inventory.FormatAnsibleYAML:
+		return "application/yaml; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// FileName is the name an exported file is offered for download under
+func FileName(format inventory.Format) string {
+	switch format {
+	case inventory.FormatCSV:
+		return "inventory.csv"
+	case inventory.FormatAnsibleINI:
+		return "inventory.ini"
+	case inventory.FormatAnsibleYAML:
+		return "inventory.yaml"
+	default:
+		return "ssh_config"
+	}
+}
+
+// fields splits a line on white space, quoted text stays together as in Ansible's INI
+// inventories and ssh_config
+func fields(line string) ([]string, error) {
+	var out []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				field.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case unicode.IsSpace(r):
+			if inField {
+				out = append(out, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("quote is not closed")
+	}
+	if inField {
+		out = append(out, field.String())
+	}
+	return out, nil
+}
+
+// quote is the reverse of fields for a single value
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'#") {
+		return s
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	return "'" + s + "'"
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// identifier turns a group or variable name into one Ansible accepts, which are limited
+// to letters, digits and underscores
+func identifier(name string) string {
+	id := nonIdentifierChars.ReplaceAllString(name, "_")
+	if isUngrouped(id) {
+		id += "_group"
+	}
+	return id
+}
+
+// isUngrouped reports the groups every Ansible host implicitly belongs to
+func isUngrouped(group string) bool {
+	return group == "all" || group == "ungrouped"
+}
+
+func isHostRange(name string) bool {
+	return strings.ContainsAny(name, "[]")
+}
+
+// metaVars are a host's meta data as variables, meta data that isn't an object is kept
+// under meta_data
+func metaVars(h *host.Host) map[string]any {
+	vars := map[string]any{}
+	if h.MetaData == nil || len(*h.MetaData) == 0 {
+		return vars
+	}
+	var value any
+	if err := json.Unmarshal(*h.MetaData, &value); err != nil || value == nil {
+		return vars
+	}
+	if obj, ok := value.(map[string]any); ok {
+		return obj
+	}
+	vars["meta_data"] = value
+	return vars
+}
+
+// text renders a variable's value, anything but a string as JSON
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func lines(content string) []string {
+	return strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+}
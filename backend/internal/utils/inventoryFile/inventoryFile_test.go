@@ -0,0 +1,386 @@
+package inventoryFile
+
+import (
+	"clouding/backend/internal/model/host"
+	hostgroup "clouding/backend/internal/model/hostGroup"
+	"clouding/backend/internal/model/inventory"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func codes(issues []inventory.Issue) []string {
+	out := []string{}
+	for _, i := range issues {
+		out = append(out, i.Code)
+	}
+	return out
+}
+
+func entry(t *testing.T, p *Parsed, name string) *Entry {
+	t.Helper()
+	for _, e := range p.Entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	t.Fatalf("host %s was not parsed", name)
+	return nil
+}
+
+func TestParseCSV(t *testing.T) {
+	content := "Name, Address, os, credential_id, groups, metaData, rack\n" +
+		"web1, 10.0.0.1, ubuntu, 1, web; prod, \"{\"\"ansible_user\"\": \"\"deploy\"\"}\", r1\n" +
+		"\n" +
+		"db1, 10.0.0.2, debian, 2, , , \n" +
+		"bad, 10.0.0.3, ubuntu, 1, , not json, \n" +
+		"long, 10.0.0.4, ubuntu, 1, , , , extra\n"
+
+	p, err := Parse(inventory.FormatCSV, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(p.Entries))
+	}
+	web := entry(t, p, "web1")
+	if web.IP != "10.0.0.1" || web.Os != "ubuntu" || web.CredentialID != "1" || web.Line != 2 {
+		t.Errorf("web1 = %+v", web)
+	}
+	if !reflect.DeepEqual(web.Groups, []string{"web", "prod"}) {
+		t.Errorf("web1 groups = %v", web.Groups)
+	}
+	if !reflect.DeepEqual(web.Vars, map[string]any{"ansible_user": "deploy", "rack": "r1"}) {
+		t.Errorf("web1 vars = %v", web.Vars)
+	}
+	if got := entry(t, p, "db1"); len(got.Groups) != 0 || len(got.Vars) != 0 {
+		t.Errorf("db1 = %+v", got)
+	}
+	if len(p.Groups) != 2 || p.Groups[0].Name != "web" || p.Groups[1].Name != "prod" {
+		t.Errorf("groups = %+v", p.Groups)
+	}
+	if !reflect.DeepEqual(codes(p.Errors), []string{CodeSyntax, CodeSyntax}) {
+		t.Fatalf("errors = %+v", p.Errors)
+	}
+	if p.Errors[0].Line != 5 || p.Errors[0].Field != "metaData" || p.Errors[1].Line != 6 {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+}
+
+func TestParseCSVInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"no name column", "ip,os\n10.0.0.1,ubuntu\n"},
+		{"unclosed quote", "name,ip\n\"web1,10.0.0.1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(inventory.FormatCSV, tt.content); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseAnsibleINI(t *testing.T) {
+	content := `# comment
+ungrouped1 ansible_host=10.0.0.9
+
+[web]
+web1 ansible_host=10.0.0.1 os=ubuntu credential_id=1 note="two words"
+web[01:03].example.com
+web2 broken
+
+[db]
+web1 ansible_user=admin
+
+[web:vars]
+http_port=80
+
+[all:children]
+web
+
+[empty]
+
+[web:other]
+[unclosed
+`
+	p, err := Parse(inventory.FormatAnsibleINI, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(p.Entries), p.Entries)
+	}
+	if e := entry(t, p, "ungrouped1"); e.IP != "10.0.0.9" || len(e.Groups) != 0 {
+		t.Errorf("ungrouped1 = %+v", e)
+	}
+	web := entry(t, p, "web1")
+	if web.IP != "10.0.0.1" || web.Os != "ubuntu" || web.CredentialID != "1" || web.Line != 5 {
+		t.Errorf("web1 = %+v", web)
+	}
+	if !reflect.DeepEqual(web.Groups, []string{"web", "db"}) {
+		t.Errorf("web1 groups = %v", web.Groups)
+	}
+	if !reflect.DeepEqual(web.Vars, map[string]any{"note": "two words", "ansible_user": "admin"}) {
+		t.Errorf("web1 vars = %v", web.Vars)
+	}
+	var groups []string
+	for _, g := range p.Groups {
+		groups = append(groups, g.Name)
+	}
+	if !reflect.DeepEqual(groups, []string{"web", "db", "empty"}) {
+		t.Errorf("groups = %v", groups)
+	}
+	if !reflect.DeepEqual(codes(p.Errors), []string{CodeUnsupported, CodeSyntax, CodeSyntax, CodeSyntax}) {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+	if !reflect.DeepEqual(codes(p.Warnings), []string{CodeIgnored, CodeIgnored}) {
+		t.Errorf("warnings = %+v", p.Warnings)
+	}
+}
+
+func TestParseAnsibleYAML(t *testing.T) {
+	content := `all:
+  hosts:
+    solo:
+      ansible_host: 10.0.0.9
+      ansible_port: 2222
+  vars:
+    env: prod
+  children:
+    web:
+      hosts:
+        web1:
+          ansible_host: 10.0.0.1
+          os: ubuntu
+          credential_id: 1
+        web2:
+        web[01:03]:
+    db:
+    broken: [a, b]
+    other:
+      unknown: true
+`
+	p, err := Parse(inventory.FormatAnsibleYAML, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(p.Entries), p.Entries)
+	}
+	solo := entry(t, p, "solo")
+	if solo.IP != "10.0.0.9" || len(solo.Groups) != 0 || solo.Vars["ansible_port"] != 2222 {
+		t.Errorf("solo = %+v", solo)
+	}
+	web := entry(t, p, "web1")
+	if web.IP != "10.0.0.1" || web.Os != "ubuntu" || web.CredentialID != "1" {
+		t.Errorf("web1 = %+v", web)
+	}
+	if !reflect.DeepEqual(web.Groups, []string{"web"}) {
+		t.Errorf("web1 groups = %v", web.Groups)
+	}
+	if e := entry(t, p, "web2"); !reflect.DeepEqual(e.Groups, []string{"web"}) {
+		t.Errorf("web2 = %+v", e)
+	}
+	var groups []string
+	for _, g := range p.Groups {
+		groups = append(groups, g.Name)
+	}
+	if !reflect.DeepEqual(groups, []string{"web", "db", "broken", "other"}) {
+		t.Errorf("groups = %v", groups)
+	}
+	if !reflect.DeepEqual(codes(p.Errors), []string{CodeUnsupported, CodeSyntax, CodeSyntax}) {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+	if !reflect.DeepEqual(codes(p.Warnings), []string{CodeIgnored}) {
+		t.Errorf("warnings = %+v", p.Warnings)
+	}
+}
+
+func TestParseAnsibleYAMLInvalidFile(t *testing.T) {
+	for name, content := range map[string]string{
+		"syntax":     "all: [",
+		"not a map":  "- web1\n- web2\n",
+		"not a yaml": "all:\n\t- web1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(inventory.FormatAnsibleYAML, content); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+	p, err := Parse(inventory.FormatAnsibleYAML, "")
+	if err != nil || len(p.Entries) != 0 {
+		t.Fatalf("empty file = %+v, %v", p, err)
+	}
+}
+
+func TestParseSSHConfig(t *testing.T) {
+	content := `User global
+Include ~/.ssh/other
+
+Host web1 web2 *.internal
+    HostName %h.example.com
+    User deploy
+    user ignored
+    Port=2222
+
+Host *
+    ForwardAgent yes
+
+Host db1
+    HostName 10.0.0.2
+    IdentityFile "~/.ssh/my key"
+    Port
+
+Match host foo
+    User other
+`
+	p, err := Parse(inventory.FormatSSHConfig, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(p.Entries), p.Entries)
+	}
+	web := entry(t, p, "web2")
+	if web.IP != "web2.example.com" || web.Line != 4 {
+		t.Errorf("web2 = %+v", web)
+	}
+	if !reflect.DeepEqual(web.Vars, map[string]any{"user": "deploy", "port": "2222"}) {
+		t.Errorf("web2 vars = %v", web.Vars)
+	}
+	db := entry(t, p, "db1")
+	if db.IP != "10.0.0.2" || db.Vars["identityfile"] != "~/.ssh/my key" {
+		t.Errorf("db1 = %+v", db)
+	}
+	if !reflect.DeepEqual(codes(p.Errors), []string{CodeSyntax}) || p.Errors[0].Line != 16 {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+	// User and Include before the first Host, the pattern in Host, Host * and Match
+	if !reflect.DeepEqual(codes(p.Warnings), []string{CodeIgnored, CodeIgnored, CodeIgnored, CodeIgnored, CodeIgnored}) {
+		t.Errorf("warnings = %+v", p.Warnings)
+	}
+}
+
+func TestParseDropsSecrets(t *testing.T) {
+	tests := []struct {
+		format  inventory.Format
+		content string
+	}{
+		{inventory.FormatAnsibleINI, "web1 ansible_host=10.0.0.1 ansible_user=admin ansible_password=hunter2 ansible_ssh_pass=hunter2 ansible_become_pass=hunter2 ansible_ssh_private_key_file=~/.ssh/id_ed25519\n"},
+		{inventory.FormatAnsibleYAML, "all:\n  hosts:\n    web1:\n      ansible_host: 10.0.0.1\n      ansible_user: admin\n      ansible_password: hunter2\n      ansible_ssh_pass: hunter2\n      ansible_become_pass: hunter2\n      ansible_ssh_private_key_file: ~/.ssh/id_ed25519\n"},
+		{inventory.FormatCSV, "name,address,ansible_password,metaData\nweb1,10.0.0.1,hunter2,\"{\"\"ansible_user\"\": \"\"admin\"\", \"\"ansible_ssh_pass\"\": \"\"hunter2\"\", \"\"ansible_become_pass\"\": \"\"hunter2\"\", \"\"ansible_ssh_private_key_file\"\": \"\"~/.ssh/id_ed25519\"\"}\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			p, err := Parse(tt.format, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Errors) > 0 {
+				t.Fatalf("errors = %+v", p.Errors)
+			}
+			if got := entry(t, p, "web1").Vars; !reflect.DeepEqual(got, map[string]any{"ansible_user": "admin"}) {
+				t.Errorf("vars = %v", got)
+			}
+			var fields []string
+			for _, w := range p.Warnings {
+				if w.Host != "web1" || w.Code != CodeIgnored || w.Line == 0 {
+					t.Errorf("warning = %+v", w)
+				}
+				fields = append(fields, w.Field)
+			}
+			want := []string{"ansible_become_pass", "ansible_password", "ansible_ssh_pass", "ansible_ssh_private_key_file"}
+			if !reflect.DeepEqual(fields, want) {
+				t.Errorf("warned about %v, want %v", fields, want)
+			}
+		})
+	}
+}
+
+func testHosts(t *testing.T) ([]*host.Host, []*hostgroup.HostGroup) {
+	t.Helper()
+	str := func(s string) *string { return &s }
+	id := func(i int) *int { return &i }
+	meta := json.RawMessage(`{"ansible_user": "deploy", "rack": "r1"}`)
+	hosts := []*host.Host{
+		{ID: id(1), Name: str("web1"), IP: str("10.0.0.1"), Os: str("ubuntu"), CredentialID: str("1"), MetaData: &meta},
+		{ID: id(2), Name: str("db one"), IP: str("10.0.0.2"), Os: str("debian"), CredentialID: str("2")},
+		{ID: id(3), Name: str("solo"), IP: str("10.0.0.3"), Os: str("alpine"), CredentialID: str("1")},
+	}
+	groups := []*hostgroup.HostGroup{
+		{Name: str("web"), HostIds: pq.Int64Array{1}},
+		{Name: str("db-servers"), HostIds: pq.Int64Array{2}},
+		{Name: str("empty")},
+	}
+	return hosts, groups
+}
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	hosts, groups := testHosts(t)
+	tests := []struct {
+		format inventory.Format
+		// groups are the names the hosts are read back in, "" for none
+		groups map[string]string
+		vars   map[string]any
+	}{
+		{inventory.FormatCSV, map[string]string{"web1": "web", "db one": "db-servers", "solo": ""}, map[string]any{"ansible_user": "deploy", "rack": "r1"}},
+		{inventory.FormatAnsibleINI, map[string]string{"web1": "web", "db one": "db_servers", "solo": ""}, map[string]any{"ansible_user": "deploy", "rack": "r1"}},
+		{inventory.FormatAnsibleYAML, map[string]string{"web1": "web", "db one": "db_servers", "solo": ""}, map[string]any{"ansible_user": "deploy", "rack": "r1"}},
+		// ssh_config has no groups and keeps only ssh keywords
+		{inventory.FormatSSHConfig, map[string]string{"web1": "", "db one": "", "solo": ""}, map[string]any{"user": "deploy"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			out, err := Write(tt.format, hosts, groups)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := Parse(tt.format, string(out))
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			if len(p.Errors) > 0 || len(p.Warnings) > 0 {
+				t.Fatalf("errors = %+v, warnings = %+v\n%s", p.Errors, p.Warnings, out)
+			}
+			if len(p.Entries) != len(hosts) {
+				t.Fatalf("got %d entries, want %d\n%s", len(p.Entries), len(hosts), out)
+			}
+			for _, h := range hosts {
+				e := entry(t, p, *h.Name)
+				if e.IP != *h.IP {
+					t.Errorf("%s ip = %q, want %q", e.Name, e.IP, *h.IP)
+				}
+				if tt.format != inventory.FormatSSHConfig && (e.Os != *h.Os || e.CredentialID != *h.CredentialID) {
+					t.Errorf("%s = %+v", e.Name, e)
+				}
+				var group string
+				if len(e.Groups) > 0 {
+					group = e.Groups[0]
+				}
+				if group != tt.groups[e.Name] || len(e.Groups) > 1 {
+					t.Errorf("%s groups = %v, want %q", e.Name, e.Groups, tt.groups[e.Name])
+				}
+			}
+			if got := entry(t, p, "web1").Vars; !reflect.DeepEqual(got, tt.vars) {
+				t.Errorf("web1 vars = %v, want %v", got, tt.vars)
+			}
+		})
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	if _, err := Parse("xml", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := Write("xml", nil, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,147 @@
+package inventoryFile
+
+import (
+	"bytes"
+	"clouding/backend/internal/model/host"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// sshKeywords are the ssh_config keywords that are exported from meta data, by their
+// lower cased name as they are imported
+var sshKeywords = map[string]string{
+	"user":                     "User",
+	"port":                     "Port",
+	"identityfile":             "IdentityFile",
+	"identitiesonly":           "IdentitiesOnly",
+	"certificatefile":          "CertificateFile",
+	"proxyjump":                "ProxyJump",
+	"proxycommand":             "ProxyCommand",
+	"forwardagent":             "ForwardAgent",
+	"addkeystoagent":           "AddKeysToAgent",
+	"stricthostkeychecking":    "StrictHostKeyChecking",
+	"userknownhostsfile":       "UserKnownHostsFile",
+	"hostkeyalias":             "HostKeyAlias",
+	"preferredauthentications": "PreferredAuthentications",
+	"connecttimeout":           "ConnectTimeout",
+	"serveraliveinterval":      "ServerAliveInterval",
+	"serveralivecountmax":      "ServerAliveCountMax",
+	"compression":              "Compression",
+	"loglevel":                 "LogLevel",
+}
+
+// ansibleSSHVars are the Ansible variables an ssh_config keyword is exported from when
+// the meta data doesn't have the keyword itself
+var ansibleSSHVars = map[string][]string{
+	"user":         {"ansible_user"},
+	"port":         {"ansible_port"},
+	"identityfile": {"ansible_ssh_private_key_file", "ansible_private_key_file"},
+}
+
+// parseSSHConfig reads the Host blocks of an ssh_config file, every alias that isn't a
+// pattern is a host. HostName is its address, the alias when unset, other keywords are
+// kept as meta data by their lower cased name. Match blocks and Include are skipped.
+func parseSSHConfig(content string) (*Parsed, error) {
+	p := &Parsed{}
+	var block []*Entry
+	inBlock := false
+	for i, raw := range lines(content) {
+		n := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		// Keyword and arguments are separated by white space or =
+		keyword, args := line, ""
+		if i := strings.IndexFunc(line, func(r rune) bool { return unicode.IsSpace(r) || r == '=' }); i >= 0 {
+			keyword = line[:i]
+			args = strings.TrimPrefix(strings.TrimSpace(line[i:]), "=")
+		}
+		tokens, err := fields(args)
+		if err != nil {
+			p.errorf(n, "", "", CodeSyntax, "%s", err)
+			continue
+		}
+
+		switch key := strings.ToLower(keyword); key {
+		case "host":
+			inBlock = true
+			block = nil
+			for _, alias := range tokens {
+				if strings.ContainsAny(alias, "*?!") {
+					continue
+				}
+				e := &Entry{Line: n, Name: alias, Vars: map[string]any{}}
+				block = append(block, e)
+				p.Entries = append(p.Entries, e)
+			}
+			switch {
+			case len(block) == 0:
+				p.warnf(n, "", "", CodeIgnored, "Host blocks with only patterns are not imported")
+			case len(block) < len(tokens):
+				p.warnf(n, "", "", CodeIgnored, "patterns in Host are not imported")
+			}
+		case "match":
+			inBlock = true
+			block = nil
+			p.warnf(n, "", "", CodeIgnored, "Match blocks are not imported")
+		case "include":
+			p.warnf(n, "", "", CodeIgnored, "included files are not imported, import them separately")
+		default:
+			if !inBlock {
+				p.warnf(n, "", "", CodeIgnored, "%s outside of a Host block is not imported", keyword)
+				continue
+			}
+			if len(tokens) == 0 {
+				p.errorf(n, "", "", CodeSyntax, "%s has no value", keyword)
+				continue
+			}
+			value := strings.Join(tokens, " ")
+			for _, e := range block {
+				if key == "hostname" {
+					if e.IP == "" {
+						e.IP = strings.ReplaceAll(value, "%h", e.Name)
+					}
+					continue
+				}
+				// As in ssh the first value of a keyword applies
+				if _, ok := e.Vars[key]; !ok {
+					e.Vars[key] = value
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// writeSSHConfig writes a Host block per host, groups have no equivalent
+func writeSSHConfig(hosts []*host.Host) []byte {
+	var buf bytes.Buffer
+	for i, h := range hosts {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "Host %s\n", quote(str(h.Name)))
+		fmt.Fprintf(&buf, "    HostName %s\n", quote(str(h.IP)))
+
+		vars := metaVars(h)
+		settings := map[string]any{}
+		for k, v := range vars {
+			if _, ok := sshKeywords[strings.ToLower(k)]; ok {
+				settings[strings.ToLower(k)] = v
+			}
+		}
+		for keyword, names := range ansibleSSHVars {
+			for _, name := range names {
+				if v, ok := vars[name]; ok && settings[keyword] == nil {
+					settings[keyword] = v
+				}
+			}
+		}
+		for _, k := range sortedKeys(settings) {
+			fmt.Fprintf(&buf, "    %s %s\n", sshKeywords[k], quote(text(settings[k])))
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,281 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRefreshInterval stops tokens with unknown kids from hammering the JWKS source
+const minRefreshInterval = 10 * time.Second
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// verificationKey is a parsed signing key, alg is empty when the JWK doesn't restrict it
+type verificationKey struct {
+	key any
+	alg string
+}
+
+// KeySet holds the public keys of a JWKS document, loaded from a URL or a local file.
+// Keys are cached for the configured TTL and refetched early when a token
+// references a kid that is not cached yet, which is how key rotation is picked up.
+// Reloads run outside the lock and are shared by all requests waiting for them.
+type KeySet struct {
+	source string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]verificationKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	lastErr     error
+	refreshing  chan struct{} // closed when the running reload finishes, nil when none runs
+}
+
+func NewKeySet(source string, ttl time.Duration) *KeySet {
+	return &KeySet{
+		source: source,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   map[string]verificationKey{},
+	}
+}
+
+// Keyfunc resolves the verification key for a token by its kid header. A token with an
+// unknown kid waits for a reload until ctx is done, an expired cache is reloaded in the
+// background while the cached keys keep being served.
+func (ks *KeySet) Keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token has no kid header")
+		}
+
+		k, ok, fresh := ks.lookup(kid)
+		if !ok {
+			if err := ks.refresh(ctx); err != nil {
+				return nil, fmt.Errorf("unknown kid %q: %w", kid, err)
+			}
+			k, ok, _ = ks.lookup(kid)
+		} else if !fresh {
+			ks.startRefresh(ctx)
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		return k.check(kid, token.Method)
+	}
+}
+
+// check verifies that the key can verify tokens signed with method
+func (k verificationKey) check(kid string, method jwt.SigningMethod) (any, error) {
+	if k.alg != "" && k.alg != method.Alg() {
+		return nil, fmt.Errorf("kid %q is for %s, not %s", kid, k.alg, method.Alg())
+	}
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := k.key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("kid %q is not an RSA key", kid)
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := k.key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("kid %q is not an EC key", kid)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", method.Alg())
+	}
+	return k.key, nil
+}
+
+func (ks *KeySet) lookup(kid string) (verificationKey, bool, bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	k, ok := ks.keys[kid]
+	return k, ok, time.Since(ks.fetchedAt) < ks.ttl
+}
+
+// refresh reloads the key set and waits for the result until ctx is done
+func (ks *KeySet) refresh(ctx context.Context) error {
+	done := ks.startRefresh(ctx)
+	if done == nil {
+		return fmt.Errorf("JWKS refreshed less than %s ago", minRefreshInterval)
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.lastErr
+}
+
+// startRefresh starts a reload unless one is running already and returns the channel closed
+// when it finishes. It returns nil when the last reload started less than minRefreshInterval
+// ago, whether it was triggered by an unknown kid or by the cache expiring.
+func (ks *KeySet) startRefresh(ctx context.Context) <-chan struct{} {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.refreshing != nil {
+		return ks.refreshing
+	}
+	if time.Since(ks.lastAttempt) < minRefreshInterval {
+		return nil
+	}
+	ks.lastAttempt = time.Now()
+	done := make(chan struct{})
+	ks.refreshing = done
+
+	// The reload is shared, so it must not be cancelled with the request that started it.
+	// The http client's timeout bounds it instead.
+	go ks.load(context.WithoutCancel(ctx), done)
+	return done
+}
+
+func (ks *KeySet) load(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	raw, err := ks.fetch(ctx)
+	var keys map[string]verificationKey
+	if err == nil {
+		keys, err = parseKeySet(raw)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.refreshing = nil
+	ks.lastErr = err
+	if err != nil {
+		slog.Warn("Failed to refresh JWKS", "Source", ks.source, "ERR", err)
+		return
+	}
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	slog.Debug("Loaded JWKS", "Source", ks.source, "Keys", len(keys))
+}
+
+func (ks *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(ks.source, "http://") && !strings.HasPrefix(ks.source, "https://") {
+		return os.ReadFile(ks.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func parseKeySet(raw []byte) (map[string]verificationKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %w", err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("Skipping JWKS key", "Kid", k.Kid, "ERR", err)
+			continue
+		}
+		keys[k.Kid] = verificationKey{key: key, alg: k.Alg}
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,181 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func rsaJWK(t *testing.T, kid, alg string) (*rsa.PrivateKey, jsonWebKey) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, jsonWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		Alg: alg,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(priv.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(priv.E)).Bytes()),
+	}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, jsonWebKey) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, jsonWebKey{
+		Kid: kid,
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(priv.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(priv.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func writeKeySet(t *testing.T, path string, keys ...jsonWebKey) {
+	t.Helper()
+	raw, err := json.Marshal(jsonWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Hour).Unix()})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func parse(ks *KeySet, token string) error {
+	_, err := jwt.NewParser().Parse(token, ks.Keyfunc(context.Background()))
+	return err
+}
+
+func TestKeyfunc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	rsaKey, rsaJwk := rsaJWK(t, "rsa", "RS256")
+	restricted, restrictedJwk := rsaJWK(t, "rs384-only", "RS384")
+	ecKey, ecJwk := ecJWK(t, "ec")
+	encKey, encJwk := rsaJWK(t, "enc", "")
+	encJwk.Use = "enc"
+	writeKeySet(t, path, rsaJwk, restrictedJwk, ecJwk, encJwk)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"rsa key", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey), false},
+		{"ec key", sign(t, jwt.SigningMethodES256, "ec", ecKey), false},
+		{"missing kid", sign(t, jwt.SigningMethodRS256, "", rsaKey), true},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "other", rsaKey), true},
+		{"alg differs from the jwk", sign(t, jwt.SigningMethodRS256, "rs384-only", restricted), true},
+		{"key type differs from the method", sign(t, jwt.SigningMethodES256, "rsa", ecKey), true},
+		{"encryption key", sign(t, jwt.SigningMethodRS256, "enc", encKey), true},
+		{"wrong signature", sign(t, jwt.SigningMethodRS256, "rsa", restricted), true},
+	}
+	ks := NewKeySet(path, time.Hour)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parse(ks, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyfuncRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	oldKey, oldJwk := rsaJWK(t, "old", "RS256")
+	newKey, newJwk := rsaJWK(t, "new", "RS256")
+	writeKeySet(t, path, oldJwk)
+
+	ks := NewKeySet(path, time.Hour)
+	if err := parse(ks, sign(t, jwt.SigningMethodRS256, "old", oldKey)); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	// The source rotated, but it was just loaded so the unknown kid is throttled
+	writeKeySet(t, path, newJwk)
+	newToken := sign(t, jwt.SigningMethodRS256, "new", newKey)
+	if err := parse(ks, newToken); err == nil {
+		t.Fatal("expected a throttled refresh to reject the new kid")
+	}
+
+	ks.mu.Lock()
+	ks.lastAttempt = time.Now().Add(-minRefreshInterval)
+	ks.mu.Unlock()
+	if err := parse(ks, newToken); err != nil {
+		t.Fatalf("new key after the throttle interval: %v", err)
+	}
+	if err := parse(ks, sign(t, jwt.SigningMethodRS256, "old", oldKey)); err == nil {
+		t.Fatal("expected the rotated out key to be rejected")
+	}
+}
+
+func TestKeyfuncServesStaleKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	key, jwk := rsaJWK(t, "rsa", "RS256")
+	writeKeySet(t, path, jwk)
+
+	ks := NewKeySet(path, time.Hour)
+	token := sign(t, jwt.SigningMethodRS256, "rsa", key)
+	if err := parse(ks, token); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cache expired and the source is gone, the cached key keeps working
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	ks.mu.Lock()
+	ks.fetchedAt = time.Now().Add(-2 * time.Hour)
+	ks.lastAttempt = time.Now().Add(-minRefreshInterval)
+	ks.mu.Unlock()
+	if err := parse(ks, token); err != nil {
+		t.Fatalf("stale key: %v", err)
+	}
+
+	// Wait for the background reload, it fails and leaves the keys in place
+	ks.mu.Lock()
+	done := ks.refreshing
+	ks.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.lastErr == nil {
+		t.Fatal("expected the background reload to fail")
+	}
+	if _, ok := ks.keys["rsa"]; !ok {
+		t.Fatal("expected the cached key to survive a failed reload")
+	}
+}
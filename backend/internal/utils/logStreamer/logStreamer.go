@@ -9,6 +9,8 @@ import (
 type LogStreamer interface {
 	StreamLogs(ctx context.Context, jobId string) (<-chan *joblogs.Log, error)
 	GetLogs(ctx context.Context, jobId string, start, end time.Time) ([]*joblogs.Log, int64, error)
+	// Ready checks that the log backend can serve queries
+	Ready(ctx context.Context) error
 }
 
 func NewLogStreamer() LogStreamer {
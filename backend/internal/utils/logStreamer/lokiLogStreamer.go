@@ -3,15 +3,23 @@ package logStreamer
 import (
 	"clouding/backend/internal/config"
 	"clouding/backend/internal/errors"
+	"clouding/backend/internal/metrics"
 	joblogs "clouding/backend/internal/model/jobLogs"
+	"clouding/backend/internal/tracing"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type LokiLogStreamer struct {
@@ -24,18 +32,33 @@ func NewLokiLogStreamer() *LokiLogStreamer {
 		panic(err)
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(baseURL.String() + "/ready")
+	l := &LokiLogStreamer{URL: baseURL.String()}
+
+	// Loki being down only affects log streaming, /readyz reports it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.Ready(ctx); err != nil {
+		slog.Warn("Loki is not ready, job logs are unavailable until it is", "ERR", err)
+	}
+
+	return l
+}
+
+func (l *LokiLogStreamer) Ready(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URL+"/ready", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		panic(fmt.Sprintf("cannot reach loki: %v", err))
+		return fmt.Errorf("cannot reach loki: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		panic(fmt.Sprintf("loki not ready: status %d", resp.StatusCode))
+		return fmt.Errorf("loki not ready: status %d", resp.StatusCode)
 	}
-
-	return &LokiLogStreamer{URL: baseURL.String()}
+	return nil
 }
 
 func (l *LokiLogStreamer) StreamLogs(ctx context.Context, jobId string) (<-chan *joblogs.Log, error) {
@@ -54,14 +77,16 @@ func (l *LokiLogStreamer) StreamLogs(ctx context.Context, jobId string) (<-chan
 			case <-ticker.C:
 				logs, latestTs, err := l.GetLogs(ctx, jobId, start, end)
 				if err != nil {
-					logsChan <- &joblogs.Log{Error: err.Error()}
+					send(ctx, logsChan, &joblogs.Log{Error: err.Error()})
 					return
 				}
 				if latestTs > 0 {
 					start = time.Unix(0, latestTs).Add(1 * time.Nanosecond)
 				}
 				for _, log := range logs {
-					logsChan <- log
+					if !send(ctx, logsChan, log) {
+						return
+					}
 					// End the stream when the job is finished
 					if log.Event == "playbook_on_stats" {
 						return
@@ -74,7 +99,29 @@ func (l *LokiLogStreamer) StreamLogs(ctx context.Context, jobId string) (<-chan
 	return logsChan, nil
 }
 
-func (l *LokiLogStreamer) GetLogs(ctx context.Context, jobId string, start, end time.Time) ([]*joblogs.Log, int64, error) {
+// send delivers log unless ctx is done first, so the poller can't block forever on a
+// reader that has gone away
+func send(ctx context.Context, logsChan chan<- *joblogs.Log, log *joblogs.Log) bool {
+	select {
+	case logsChan <- log:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *LokiLogStreamer) GetLogs(ctx context.Context, jobId string, start, end time.Time) (result []*joblogs.Log, latest int64, err error) {
+	queryStart := time.Now()
+	ctx, span := tracing.Start(ctx, "loki query_range",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("job.id", jobId)),
+	)
+	defer func() {
+		metrics.LokiQueryDuration.WithLabelValues(metrics.Result(err)).Observe(time.Since(queryStart).Seconds())
+		span.SetAttributes(attribute.Int("loki.logs", len(result)))
+		tracing.End(span, err)
+	}()
+
 	u, _ := url.Parse(l.URL)
 	u.Path = "/loki/api/v1/query_range"
 	q := u.Query()
@@ -86,13 +133,14 @@ func (l *LokiLogStreamer) GetLogs(ctx context.Context, jobId string, start, end
 	q.Set("direction", "forward") // "forward" -> oldest first
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, 0, err
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	client := http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req.WithContext(ctx))
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, 0, err
 	}
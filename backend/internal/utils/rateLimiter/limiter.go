@@ -0,0 +1,83 @@
+package rateLimiter
+
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const DefaultGroup = "default"
+
+// defaultGroupRules protect the most expensive routes unless overridden by RATELIMIT.GROUPS
+var defaultGroupRules = map[string]Rule{
+	// Dials every requested host over SSH
+	"hostHealth": {Requests: 6, Period: time.Minute},
+	// Publishes a job that runs against real infrastructure
+	"deploymentCreate": {Requests: 10, Period: time.Minute},
+}
+
+// Limiter applies a rule per route group, keyed by caller
+type Limiter struct {
+	store       Store
+	defaultRule Rule
+	groupRules  map[string]Rule
+}
+
+func NewLimiter(store Store, defaultRule Rule, groupRules map[string]Rule) *Limiter {
+	return &Limiter{store: store, defaultRule: defaultRule, groupRules: groupRules}
+}
+
+// NewLimiterFromConfig builds the limiter from RATELIMIT.* settings. It returns nil when rate
+// limiting is disabled and panics on invalid rules, like the rest of startup configuration.
+func NewLimiterFromConfig(db *sqlx.DB, lc *lifecycle.Lifecycle) *Limiter {
+	cfg := config.Config.RateLimit
+	if !cfg.Enabled {
+		return nil
+	}
+
+	defaultRule, err := ParseRule(cfg.Default)
+	if err != nil {
+		panic(err)
+	}
+
+	groupRules := map[string]Rule{}
+	for group, rule := range defaultGroupRules {
+		groupRules[group] = rule
+	}
+	for group, value := range cfg.Groups {
+		rule, err := ParseRule(value)
+		if err != nil {
+			panic(fmt.Errorf("rate limit group %s: %w", group, err))
+		}
+		groupRules[group] = rule
+	}
+
+	var store Store
+	switch cfg.Store {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "postgres":
+		store = NewPostgresStore(repository.NewRateLimitRepository(db), lc)
+	default:
+		panic(fmt.Sprintf("unknown rate limit store %q, expected memory or postgres", cfg.Store))
+	}
+
+	return NewLimiter(store, defaultRule, groupRules)
+}
+
+func (l *Limiter) Rule(group string) Rule {
+	if rule, ok := l.groupRules[group]; ok {
+		return rule
+	}
+	return l.defaultRule
+}
+
+// Take counts a request by key against the group's bucket
+func (l *Limiter) Take(ctx context.Context, group string, key string) (*Result, error) {
+	return l.store.Take(ctx, group+":"+key, l.Rule(group))
+}
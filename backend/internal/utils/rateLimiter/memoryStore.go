@@ -0,0 +1,64 @@
+package rateLimiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from memory
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rule      Rule
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, rule Rule) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Requests), updatedAt: now, rule: rule}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(rule.Requests), b.tokens+now.Sub(b.updatedAt).Seconds()*rule.RatePerSecond())
+	b.updatedAt = now
+	b.rule = rule
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(allowed, b.tokens, rule), nil
+}
+
+// sweep drops buckets that have refilled completely, they behave the same as missing ones
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*b.rule.RatePerSecond() >= float64(b.rule.Requests) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
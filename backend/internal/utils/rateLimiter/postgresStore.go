@@ -0,0 +1,55 @@
+package rateLimiter
+
+import (
+	"clouding/backend/internal/lifecycle"
+	"clouding/backend/internal/repository"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// bucketRetention is how long an unused bucket is kept. It only needs to outlive the
+// longest period, after that a missing bucket is the same as a full one.
+const bucketRetention = 2 * time.Hour
+
+// PostgresStore keeps buckets in Postgres so that limits hold across instances
+type PostgresStore struct {
+	repo      repository.RateLimitRepository
+	lifecycle *lifecycle.Lifecycle
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewPostgresStore(repo repository.RateLimitRepository, lc *lifecycle.Lifecycle) *PostgresStore {
+	return &PostgresStore{repo: repo, lifecycle: lc, lastCleanup: time.Now()}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule) (*Result, error) {
+	s.cleanupIdle()
+
+	tokens, allowed, err := s.repo.TakeToken(ctx, key, rule.Requests, rule.RatePerSecond())
+	if err != nil {
+		return nil, err
+	}
+	return newResult(allowed, tokens, rule), nil
+}
+
+// cleanupIdle deletes idle buckets at most once per sweepInterval, off the request path
+func (s *PostgresStore) cleanupIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastCleanup) < sweepInterval {
+		return
+	}
+	s.lastCleanup = time.Now()
+
+	s.lifecycle.Go("rate limit cleanup", func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := s.repo.DeleteIdleBuckets(ctx, bucketRetention); err != nil {
+			slog.Warn("Failed to delete idle rate limit buckets", "ERR", err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule allows Requests per Period, refilled continuously, with bursts of up to Requests
+type Rule struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseRule parses rules such as "20/s", "100/m" or "1000/h"
+func ParseRule(s string) (Rule, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rate limit rule %q, expected <requests>/<s|m|h>", s)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests <= 0 {
+		return Rule{}, fmt.Errorf("invalid request count in rate limit rule %q", s)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[unit]
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid period in rate limit rule %q", s)
+	}
+	return Rule{Requests: requests, Period: period}, nil
+}
+
+// RatePerSecond is how fast the bucket refills
+func (r Rule) RatePerSecond() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+// Result describes the bucket after a request was counted against it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request would be allowed, zero when allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Store keeps token buckets. Take counts one request against the bucket at key.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule) (*Result, error)
+}
+
+// newResult builds a Result from the tokens left in the bucket
+func newResult(allowed bool, tokens float64, rule Rule) *Result {
+	rate := rule.RatePerSecond()
+	res := &Result{
+		Allowed:    allowed,
+		Limit:      rule.Requests,
+		Remaining:  int(math.Max(0, math.Floor(tokens))),
+		ResetAfter: time.Duration((float64(rule.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
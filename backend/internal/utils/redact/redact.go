@@ -0,0 +1,69 @@
+// Package redact decides which keys hold secrets. The logs and the audit log share it, so a
+// value hidden in one never shows up in the other.
+package redact
+
+import "strings"
+
+const Placeholder = "[REDACTED]"
+
+// sensitiveKeys match the whole key, compared lowercased with -, _ and . removed
+var sensitiveKeys = map[string]bool{
+	"authorization":      true,
+	"proxyauthorization": true,
+	"cookie":             true,
+	"setcookie":          true,
+	"token":              true,
+	"apikey":             true,
+	"xapikey":            true,
+	"xworkersignature":   true,
+}
+
+// sensitiveParts match anywhere in the normalized key, e.g. sshPrivateKey or JWT_SECRET
+var sensitiveParts = []string{
+	"secret",
+	"password",
+	"passphrase",
+	"privatekey",
+	"accesstoken",
+	"refreshtoken",
+}
+
+var keyNormalizer = strings.NewReplacer("-", "", "_", "", ".", "")
+
+// IsSensitive reports whether the value of an attribute, header or field named key must be hidden
+func IsSensitive(key string) bool {
+	key = keyNormalizer.Replace(strings.ToLower(key))
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, part := range sensitiveParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns a copy of a decoded JSON value with the values of sensitive keys replaced,
+// in nested objects and arrays too. Other values are returned as they are.
+func Value(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		clean := make(map[string]any, len(t))
+		for k, value := range t {
+			if IsSensitive(k) {
+				clean[k] = Placeholder
+				continue
+			}
+			clean[k] = Value(value)
+		}
+		return clean
+	case []any:
+		clean := make([]any, len(t))
+		for i, value := range t {
+			clean[i] = Value(value)
+		}
+		return clean
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+package redact
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsSensitive(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"Authorization", true},
+		{"X-Api-Key", true},
+		{"x-worker-signature", true},
+		{"token", true},
+		{"secret", true},
+		{"secretId", true},
+		{"JWT_SECRET", true},
+		{"supabase.jwt.secret", true},
+		{"sshPrivateKey", true},
+		{"dbPassword", true},
+		{"refresh_token", true},
+		{"tokenPrefix", false},
+		{"apiTokenId", false},
+		{"name", false},
+		{"credentialId", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := IsSensitive(tt.key); got != tt.want {
+				t.Errorf("IsSensitive(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	in := map[string]any{
+		"name":   "db",
+		"secret": map[string]any{"password": "hunter2"},
+		"hosts": []any{
+			map[string]any{"id": float64(1), "privateKey": "key"},
+		},
+	}
+	want := map[string]any{
+		"name":   "db",
+		"secret": Placeholder,
+		"hosts": []any{
+			map[string]any{"id": float64(1), "privateKey": Placeholder},
+		},
+	}
+	if got := Value(in); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Value() = %v, want %v", got, want)
+	}
+	if in["secret"] == Placeholder {
+		t.Fatal("Value() modified its input")
+	}
+}
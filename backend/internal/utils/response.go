@@ -1,35 +1,39 @@
 package utils
 
-import "github.com/gin-gonic/gin"
+import (
+	"clouding/backend/internal/logger"
+	"clouding/backend/internal/pagination"
+	"context"
 
-func NewApiErrorResponse(err string) gin.H {
-	return gin.H{
-		"error":   err,
-		"success": false,
-		"data":    nil,
-	}
-}
+	"github.com/gin-gonic/gin"
+)
 
-func NewWrongParamResponse(err string) gin.H {
+// Every envelope carries the ID of the request it answers, ctx is usually the *gin.Context
+
+// NewErrorResponse is the envelope of a failed request, code is one of the stable
+// codes from the errors package that clients can branch on
+func NewErrorResponse(ctx context.Context, code string, err string) gin.H {
 	return gin.H{
-		"error":   "Parameter(s) not correct. " + err,
-		"success": false,
-		"data":    nil,
+		"error":     err,
+		"code":      code,
+		"success":   false,
+		"data":      nil,
+		"requestId": logger.RequestID(ctx),
 	}
 }
 
-func NewInternalErrorResponse(err string) gin.H {
+func NewSuccessResponse(ctx context.Context, data any) gin.H {
 	return gin.H{
-		"error":   "Internal Exception: " + err,
-		"success": false,
-		"data":    nil,
+		"success":   true,
+		"error":     nil,
+		"data":      data,
+		"requestId": logger.RequestID(ctx),
 	}
 }
 
-func NewSuccessResponse(data any) gin.H {
-	return gin.H{
-		"success": true,
-		"error":   nil,
-		"data":    data,
-	}
+// NewListResponse is the envelope of a paginated list, meta holds the cursor of the next page
+func NewListResponse(ctx context.Context, data any, meta *pagination.Meta) gin.H {
+	resp := NewSuccessResponse(ctx, data)
+	resp["meta"] = meta
+	return resp
 }
@@ -1,9 +1,10 @@
 package secretmanager
 
 import (
+	"clouding/backend/internal/tracing"
 	"context"
 	"encoding/json"
-	"log/slog"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	awsSecretManagerCore "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
@@ -13,36 +14,39 @@ type AwsSecretsManager struct {
 	secretsmanagerClient *awsSecretManagerCore.Client
 }
 
-func NewAwsSecretManager() SecretsManager {
+func NewAwsSecretManager() (SecretsManager, error) {
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
-		slog.Error("Error creating secrets manager")
-		panic(err)
+		return nil, fmt.Errorf("loading AWS config: %w", err)
 	}
 	// proactively resolve credentials
 	_, err = cfg.Credentials.Retrieve(context.TODO())
 	if err != nil {
-		slog.Error("Unable to retrieve AWS credentials")
-		panic(err)
+		return nil, fmt.Errorf("retrieving AWS credentials: %w", err)
 	}
 	smClient := awsSecretManagerCore.NewFromConfig(cfg)
 	return &AwsSecretsManager{
 		secretsmanagerClient: smClient,
-	}
+	}, nil
 }
 
-func (aws *AwsSecretsManager) GetSecret(secretName string) (string, error) {
+func (aws *AwsSecretsManager) GetSecret(ctx context.Context, secretName string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "aws", "GetSecretValue", secretName)
+	defer func() { tracing.End(span, err) }()
+
 	getSecretInput := &awsSecretManagerCore.GetSecretValueInput{
 		SecretId: &secretName,
 	}
-	secretOutput, err := aws.secretsmanagerClient.GetSecretValue(context.Background(), getSecretInput)
+	secretOutput, err := aws.secretsmanagerClient.GetSecretValue(ctx, getSecretInput)
 	if err != nil {
 		return "", err
 	}
 	return *secretOutput.SecretString, nil
 }
 
-func (aws *AwsSecretsManager) SetSecret(secretName string, secretMap map[string]interface{}) error {
+func (aws *AwsSecretsManager) SetSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "aws", "CreateSecret", secretName)
+	defer func() { tracing.End(span, err) }()
 
 	secret, err := json.Marshal(secretMap)
 	if err != nil {
@@ -54,7 +58,7 @@ func (aws *AwsSecretsManager) SetSecret(secretName string, secretMap map[string]
 		Name:         &secretName,
 		SecretString: &secretStr,
 	}
-	_, err = aws.secretsmanagerClient.CreateSecret(context.Background(), setSecretInput)
+	_, err = aws.secretsmanagerClient.CreateSecret(ctx, setSecretInput)
 
 	if err != nil {
 		return err
@@ -62,18 +66,24 @@ func (aws *AwsSecretsManager) SetSecret(secretName string, secretMap map[string]
 	return nil
 }
 
-func (aws *AwsSecretsManager) DeleteSecret(secretName string) error {
+func (aws *AwsSecretsManager) DeleteSecret(ctx context.Context, secretName string) (err error) {
+	ctx, span := startSpan(ctx, "aws", "DeleteSecret", secretName)
+	defer func() { tracing.End(span, err) }()
+
 	// Force deleting the cred
 	forceDelete := true
 	deleteSecretInput := &awsSecretManagerCore.DeleteSecretInput{
 		SecretId:                   &secretName,
 		ForceDeleteWithoutRecovery: &forceDelete,
 	}
-	_, err := aws.secretsmanagerClient.DeleteSecret(context.Background(), deleteSecretInput)
+	_, err = aws.secretsmanagerClient.DeleteSecret(ctx, deleteSecretInput)
 	return err
 }
 
-func (aws *AwsSecretsManager) UpdateSecret(secretName string, secretMap map[string]interface{}) error {
+func (aws *AwsSecretsManager) UpdateSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "aws", "UpdateSecret", secretName)
+	defer func() { tracing.End(span, err) }()
+
 	secret, err := json.Marshal(secretMap)
 	if err != nil {
 		return err
@@ -83,6 +93,14 @@ func (aws *AwsSecretsManager) UpdateSecret(secretName string, secretMap map[stri
 		SecretId:     &secretName,
 		SecretString: &secretStr,
 	}
-	_, err = aws.secretsmanagerClient.UpdateSecret(context.Background(), updateSecretInput)
+	_, err = aws.secretsmanagerClient.UpdateSecret(ctx, updateSecretInput)
+	return err
+}
+
+func (aws *AwsSecretsManager) Ping(ctx context.Context) error {
+	maxResults := int32(1)
+	_, err := aws.secretsmanagerClient.ListSecrets(ctx, &awsSecretManagerCore.ListSecretsInput{
+		MaxResults: &maxResults,
+	})
 	return err
 }
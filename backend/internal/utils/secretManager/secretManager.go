@@ -1,12 +1,54 @@
 package secretmanager
 
+import (
+	"clouding/backend/internal/config"
+	"clouding/backend/internal/tracing"
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type SecretsManager interface {
-	GetSecret(secretName string) (string, error)
-	SetSecret(secretName string, secretMap map[string]interface{}) error
-	UpdateSecret(secretName string, secretMap map[string]interface{}) error
-	DeleteSecret(secretName string) error
+	GetSecret(ctx context.Context, secretName string) (string, error)
+	SetSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) error
+	UpdateSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) error
+	DeleteSecret(ctx context.Context, secretName string) error
+	// Ping checks that the backend is reachable and usable
+	Ping(ctx context.Context) error
+}
+
+// Factory creates a secret backend from config.Config
+type Factory func() (SecretsManager, error)
+
+var backends = map[string]Factory{
+	"vault": NewVaultSecretManager,
+	"aws":   NewAwsSecretManager,
+}
+
+// RegisterBackend makes another backend selectable through secretManager.backend
+func RegisterBackend(name string, factory Factory) {
+	backends[name] = factory
+}
+
+// NewSecretManager creates the backend named by secretManager.backend
+func NewSecretManager() (SecretsManager, error) {
+	factory, ok := backends[config.Config.SecretManager.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown secret manager backend %q", config.Config.SecretManager.Backend)
+	}
+	return factory()
 }
 
-func NewSecretManager() SecretsManager {
-	return NewVaultSecretManager()
+// startSpan opens a span for one secret backend call, the secret name is recorded
+// but never its value
+func startSpan(ctx context.Context, backend, operation, secretName string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, backend+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("secret.backend", backend),
+			attribute.String("secret.name", secretName),
+		),
+	)
 }
@@ -0,0 +1,116 @@
+package secretmanager
+
+import (
+	"clouding/backend/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// reloginBackoff is how long to wait between failed attempts to log in again
+const reloginBackoff = 10 * time.Second
+
+// login authenticates the client with the configured auth method and returns the
+// auth secret to renew, or nil when the token can not be renewed
+func login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	var (
+		path string
+		data map[string]interface{}
+	)
+
+	switch config.Config.Vault.AuthMethod {
+	case "approle":
+		path = "auth/" + config.Config.Vault.AppRoleMount + "/login"
+		data = map[string]interface{}{
+			"role_id":   config.Config.Vault.RoleID,
+			"secret_id": config.Config.Vault.SecretID,
+		}
+	case "kubernetes":
+		jwt, err := os.ReadFile(config.Config.Vault.KubernetesTokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading service account token: %w", err)
+		}
+		path = "auth/" + config.Config.Vault.KubernetesMount + "/login"
+		data = map[string]interface{}{
+			"role": config.Config.Vault.KubernetesRole,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		}
+	default:
+		return tokenLogin(ctx, client)
+	}
+
+	secret, err := client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("no token returned by %s", path)
+	}
+	client.SetToken(secret.Auth.ClientToken)
+	return secret, nil
+}
+
+// tokenLogin uses the ambient VAULT_TOKEN, it is only kept alive when renewable
+func tokenLogin(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	if client.Token() == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN is not set")
+	}
+	self, err := client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	renewable, err := self.TokenIsRenewable()
+	if err != nil || !renewable {
+		return nil, err
+	}
+	return client.Auth().Token().RenewSelfWithContext(ctx, 0)
+}
+
+// keepTokenAlive renews the token until it reaches its max TTL, then logs in again
+func (v *VaultSecretsManager) keepTokenAlive(authSecret *vault.Secret) {
+	for authSecret != nil {
+		watcher, err := v.client.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: authSecret})
+		if err != nil {
+			slog.Error("Unable to watch Vault token", "ERR", err)
+			return
+		}
+		go watcher.Start()
+		watchToken(watcher)
+		watcher.Stop()
+
+		if config.Config.Vault.AuthMethod == "token" {
+			slog.Error("Vault token can not be renewed any further, set a new VAULT_TOKEN")
+			return
+		}
+
+		for {
+			authSecret, err = login(context.Background(), v.client)
+			if err == nil {
+				slog.Info("Logged in to Vault again", "Method", config.Config.Vault.AuthMethod)
+				break
+			}
+			slog.Error("Failed to log in to Vault again", "ERR", err)
+			time.Sleep(reloginBackoff)
+		}
+	}
+}
+
+// watchToken blocks until the token can not be renewed any more
+func watchToken(watcher *vault.LifetimeWatcher) {
+	for {
+		select {
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				slog.Warn("Vault token renewal stopped", "ERR", err)
+			}
+			return
+		case renewal := <-watcher.RenewCh():
+			slog.Debug("Renewed Vault token", "At", renewal.RenewedAt)
+		}
+	}
+}
@@ -2,74 +2,175 @@ package secretmanager
 
 import (
 	"clouding/backend/internal/config"
+	"clouding/backend/internal/tracing"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	vault "github.com/hashicorp/vault/api"
 )
 
 type VaultSecretsManager struct {
-	client       *vault.Client
-	path         string
+	client    *vault.Client
+	kvVersion int
+	// path is where secrets are read and written
+	path string
+	// metadataPath is where secrets are deleted, on KV v2 this removes every version
 	metadataPath string
 }
 
-func NewVaultSecretManager() SecretsManager {
+func NewVaultSecretManager() (SecretsManager, error) {
 	vaultConfig := vault.DefaultConfig()
 	client, err := vault.NewClient(vaultConfig)
 	if err != nil {
-		slog.Error("Failed to create Vault client")
-		panic(err)
+		return nil, fmt.Errorf("creating Vault client: %w", err)
 	}
 
-	return &VaultSecretsManager{
+	ctx := context.Background()
+	authSecret, err := login(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("logging in to Vault with %s auth: %w", config.Config.Vault.AuthMethod, err)
+	}
+
+	mount, prefix := kvLocation()
+	kvVersion, err := detectKVVersion(ctx, client, mount)
+	if err != nil {
+		// Keeps working with tokens that may not read mount information
+		slog.Warn("Could not detect KV version, assuming v2", "Mount", mount, "ERR", err)
+		kvVersion = 2
+	}
+	slog.Info("Using Vault KV mount", "Mount", mount, "Version", kvVersion)
+
+	v := &VaultSecretsManager{
 		client:       client,
-		path:         config.Config.Vault.VaultSecretEnginePath,
-		metadataPath: config.Config.Vault.VaultSecretEnginePath,
+		kvVersion:    kvVersion,
+		path:         mount + "/" + prefix,
+		metadataPath: mount + "/" + prefix,
+	}
+	if kvVersion == 2 {
+		v.path = mount + "/data/" + prefix
+		v.metadataPath = mount + "/metadata/" + prefix
+		if config.Config.Vault.VaultSecretEngineMetaPath != "" {
+			v.metadataPath = config.Config.Vault.VaultSecretEngineMetaPath
+		}
+	}
+
+	if authSecret != nil {
+		go v.keepTokenAlive(authSecret)
 	}
+	return v, nil
 }
 
-func (v *VaultSecretsManager) GetSecret(secretName string) (string, error) {
+func (v *VaultSecretsManager) GetSecret(ctx context.Context, secretName string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "vault", "read", secretName)
+	defer func() { tracing.End(span, err) }()
+
 	fullPath := v.path + secretName
-	secret, err := v.client.Logical().Read(fullPath)
+	secret, err := v.client.Logical().ReadWithContext(ctx, fullPath)
 	if err != nil {
 		return "", err
 	}
-	if secret == nil || secret.Data == nil || secret.Data["data"] == nil {
+	if secret == nil || secret.Data == nil {
 		return "", fmt.Errorf("secret not found %s", secretName)
 	}
-	jsonData, err := json.Marshal(getVaultData(secret.Data))
+
+	data := secret.Data
+	if v.kvVersion == 2 {
+		if secret.Data["data"] == nil {
+			return "", fmt.Errorf("secret not found %s", secretName)
+		}
+		data = getVaultData(secret.Data)
+	}
+	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return "", err
 	}
 	return string(jsonData), nil
 }
 
-func (v *VaultSecretsManager) SetSecret(secretName string, secretMap map[string]interface{}) error {
+func (v *VaultSecretsManager) SetSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "vault", "write", secretName)
+	defer func() { tracing.End(span, err) }()
+
 	fullPath := v.path + secretName
-	_, err := v.client.Logical().Write(fullPath, createVaultData(secretMap))
+	data := secretMap
+	if v.kvVersion == 2 {
+		data = createVaultData(secretMap)
+	}
+	_, err = v.client.Logical().WriteWithContext(ctx, fullPath, data)
 	return err
 }
 
-func (v *VaultSecretsManager) UpdateSecret(secretName string, secretMap map[string]interface{}) error {
-	_, err := v.GetSecret(secretName)
+func (v *VaultSecretsManager) UpdateSecret(ctx context.Context, secretName string, secretMap map[string]interface{}) error {
+	_, err := v.GetSecret(ctx, secretName)
 
 	if err != nil {
 		return err
 	}
 
-	return v.SetSecret(secretName, secretMap)
+	return v.SetSecret(ctx, secretName, secretMap)
 }
 
-func (v *VaultSecretsManager) DeleteSecret(secretName string) error {
+func (v *VaultSecretsManager) DeleteSecret(ctx context.Context, secretName string) (err error) {
+	ctx, span := startSpan(ctx, "vault", "delete", secretName)
+	defer func() { tracing.End(span, err) }()
 
 	fullPath := v.metadataPath + secretName
 
-	_, err := v.client.Logical().Delete(fullPath)
+	_, err = v.client.Logical().DeleteWithContext(ctx, fullPath)
 	return err
 }
 
+func (v *VaultSecretsManager) Ping(ctx context.Context) error {
+	health, err := v.client.Sys().HealthWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	if health.Sealed {
+		return fmt.Errorf("vault is sealed")
+	}
+	return nil
+}
+
+// kvLocation returns the KV mount and the prefix under it, falling back to the
+// deprecated VAULT_SECRET_ENGINE path, e.g. secret/data/clouding/ -> secret, clouding/
+func kvLocation() (string, string) {
+	mount := strings.Trim(config.Config.Vault.Mount, "/")
+	prefix := config.Config.Vault.Prefix
+	if mount == "" {
+		enginePath := strings.Trim(config.Config.Vault.VaultSecretEnginePath, "/")
+		mount, prefix, _ = strings.Cut(enginePath, "/")
+		if prefix == "data" {
+			prefix = ""
+		}
+		prefix = strings.TrimPrefix(prefix, "data/")
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return mount, prefix
+}
+
+// detectKVVersion asks Vault how the mount is configured, this endpoint is readable
+// by any token with a capability on the mount
+func detectKVVersion(ctx context.Context, client *vault.Client, mount string) (int, error) {
+	secret, err := client.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+mount)
+	if err != nil {
+		return 0, err
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, fmt.Errorf("mount %s not found", mount)
+	}
+	if options, ok := secret.Data["options"].(map[string]interface{}); ok && options["version"] == "2" {
+		return 2, nil
+	}
+	return 1, nil
+}
+
 func createVaultData(m map[string]interface{}) map[string]interface{} {
 	return map[string]interface{}{
 		"data": m,
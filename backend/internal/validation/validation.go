@@ -0,0 +1,194 @@
+package validation
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Request models declare their rules with validate tags, e.g.
+//
+//	Name *string `json:"name" validate:"required,min=1,max=100,name"`
+//
+// Besides the validator's own rules there are:
+//   - name: letters, digits, spaces and . _ - starting with a letter or digit
+//   - hostaddr: an IP address or an RFC 1123 hostname
+
+// Codes of the details that are not named after a rule
+const (
+	CodeNotFound = "not_found"
+	CodeInvalid  = "invalid"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._-]*$`)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.SetTagName("validate")
+	// Details name fields as they are sent, e.g. credentialId rather than CredentialID
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	must(v.RegisterValidation("name", func(fl validator.FieldLevel) bool {
+		return namePattern.MatchString(fl.Field().String())
+	}))
+	must(v.RegisterValidation("hostaddr", func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		if net.ParseIP(s) != nil {
+			return true
+		}
+		// Top level domains are never numeric, 300.1.2.3 is a broken address rather than a name
+		labels := strings.Split(s, ".")
+		if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+			return false
+		}
+		return v.Var(s, "hostname_rfc1123") == nil
+	}))
+	return v
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Check checks every rule of a request model, as sent to create a resource. A slice of
+// models is checked item by item, details are named after the item, e.g. [1].componentId.
+func Check(model any) error {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	if v.Kind() != reflect.Slice {
+		return fromValidator(validate.Struct(model))
+	}
+
+	var errs Errors
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() == reflect.Pointer && item.IsNil() {
+			errs.Add(fmt.Sprintf("[%d]", i), "required", "is required")
+			continue
+		}
+		err := fromValidator(validate.Struct(item.Interface()))
+		if err == nil {
+			continue
+		}
+		appErr := apperrors.From(err)
+		if appErr == nil || len(appErr.Details) == 0 {
+			return err
+		}
+		for _, d := range appErr.Details {
+			errs.Add(fmt.Sprintf("[%d].%s", i, d.Field), d.Code, d.Message)
+		}
+	}
+	return errs.Err()
+}
+
+// CheckPartial checks the fields sent in a partial update. Fields left out are nil and keep
+// their value, so their rules, required in particular, don't apply.
+func CheckPartial(model any) error {
+	present := map[string]bool{}
+	v := reflect.Indirect(reflect.ValueOf(model))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			if field.IsNil() {
+				continue
+			}
+		}
+		present[t.Name()+"."+t.Field(i).Name] = true
+	}
+	return fromValidator(validate.StructFiltered(model, func(ns []byte) bool {
+		// ns is the path of the field by Go names, e.g. Host.Name or Deployment.HostIDs[0]
+		top := string(ns)
+		if i := strings.IndexAny(top[len(t.Name())+1:], ".["); i >= 0 {
+			top = top[:len(t.Name())+1+i]
+		}
+		return !present[top]
+	}))
+}
+
+// Errors collects the invalid fields of a request, for checks that need more than the
+// model, such as references to other resources
+type Errors []apperrors.Detail
+
+func (e *Errors) Add(field string, code string, message string) {
+	*e = append(*e, apperrors.Detail{Field: field, Code: code, Message: message})
+}
+
+// Err is ErrValidationFailed listing the collected fields, nil when there are none
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return apperrors.ErrValidationFailed.WithDetails(e)
+}
+
+func fromValidator(err error) error {
+	if err == nil {
+		return nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return apperrors.InvalidParameter(err.Error())
+	}
+	var errs Errors
+	for _, fe := range fieldErrs {
+		field := fe.Namespace()
+		// Drop the model's name, details are relative to the request body
+		if _, rest, ok := strings.Cut(field, "."); ok {
+			field = rest
+		}
+		errs.Add(field, fe.Tag(), message(fe))
+	}
+	return errs.Err()
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "max":
+		if fe.Tag() == "min" && fe.Param() == "1" && fe.Kind() != reflect.Int {
+			return "must not be empty"
+		}
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be " + bound + " " + fe.Param() + " characters long"
+		case reflect.Slice, reflect.Map:
+			return "must have " + bound + " " + fe.Param() + " items"
+		default:
+			return "must be " + bound + " " + fe.Param()
+		}
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "name":
+		return "must start with a letter or digit and contain only letters, digits, spaces, '.', '_' and '-'"
+	case "hostaddr":
+		return "must be an IP address or a hostname"
+	case "numeric":
+		return "must be a number"
+	default:
+		return "is invalid"
+	}
+}
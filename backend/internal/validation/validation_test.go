@@ -0,0 +1,103 @@
+package validation
+
+import (
+	apperrors "clouding/backend/internal/errors"
+	"clouding/backend/internal/model/blueprint"
+	"clouding/backend/internal/model/host"
+	"reflect"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// fields returns field:code of every detail of a validation error
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	appErr := apperrors.From(err)
+	if appErr == nil {
+		t.Fatalf("error = %v, want a validation error", err)
+	}
+	var got []string
+	for _, d := range appErr.Details {
+		got = append(got, d.Field+":"+d.Code)
+	}
+	return got
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		model any
+		want  []string
+	}{
+		{
+			"valid host",
+			&host.Host{Name: ptr("web-1"), IP: ptr("10.0.0.1"), Os: ptr("linux"), CredentialID: ptr("1")},
+			nil,
+		},
+		{
+			"every invalid field is reported",
+			&host.Host{Name: ptr("-web"), IP: ptr("300.1.2.3"), CredentialID: ptr("one")},
+			[]string{"name:name", "ip:hostaddr", "os:required", "credentialId:numeric"},
+		},
+		{
+			"hostname address",
+			&host.Host{Name: ptr("web"), IP: ptr("web.example.com"), Os: ptr("linux"), CredentialID: ptr("1")},
+			nil,
+		},
+		{
+			"valid components",
+			[]*blueprint.BlueprintComponent{{ComponentID: ptr(1), Position: ptr(0)}, {ComponentID: ptr(2), Position: ptr(1)}},
+			nil,
+		},
+		{
+			"components are named by index",
+			[]*blueprint.BlueprintComponent{{ComponentID: ptr(1), Position: ptr(1)}, {Position: ptr(-1)}, nil},
+			[]string{"[1].componentId:required", "[1].position:min", "[2]:required"},
+		},
+		{"no components", []*blueprint.BlueprintComponent{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fields(t, Check(tt.model))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Check() details = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPartial(t *testing.T) {
+	tests := []struct {
+		name  string
+		model *host.Host
+		want  []string
+	}{
+		{"nothing sent", &host.Host{}, nil},
+		{"valid field", &host.Host{Name: ptr("web-2")}, nil},
+		{"invalid field", &host.Host{Name: ptr(""), IP: ptr("not an address")}, []string{"name:min", "ip:hostaddr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fields(t, CheckPartial(tt.model))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CheckPartial() details = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var errs Errors
+	if errs.Err() != nil {
+		t.Fatal("no details must not be an error")
+	}
+	errs.Add("hostIds[0]", CodeNotFound, "host 1 does not exist in this organization")
+	appErr := apperrors.From(errs.Err())
+	if appErr == nil || appErr.Code != apperrors.ErrValidationFailed.Code || len(appErr.Details) != 1 {
+		t.Fatalf("Err() = %v, want validation_failed with one detail", errs.Err())
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
-import "clouding/backend/cmd/server"
+import (
+	"clouding/backend/cmd"
+	"os"
+)
 
 func main() {
-	server.Start()
+	os.Exit(cmd.Execute(os.Args[1:]))
 }
@@ -18,6 +18,7 @@ class AnsibleRunner:
     def __init__(self, lokiEndPoint, payload: deploymentModels.DeploymentRabbitMqPayload, playbookInfo: PlaybookInfo):
         self.lokiEndPoint = lokiEndPoint
         self.jobId = playbookInfo.jobId
+        self.requestId = payload.requestId
         self.playbookName = playbookInfo.playbookName
         self.workDir = playbookInfo.playbookDir
         self.isPlan = payload.dtype == 'plan'
@@ -93,13 +94,17 @@ class AnsibleRunner:
 
     def sendToLoki(self, data):
         timestamp = str(int(time.time() * 1e9))
+        stream = {
+            "job": "ansible",
+            "jobId": self.jobId
+        }
+        # Joins the job logs to the backend request that created the deployment
+        if self.requestId:
+            stream["requestId"] = self.requestId
         payload = {
             "streams": [
                 {
-                    "stream": {
-                        "job": "ansible",
-                        "jobId": self.jobId
-                    },
+                    "stream": stream,
                     "values": [
                         [timestamp, json.dumps(data)]
                     ]
@@ -90,7 +90,9 @@ class RabbitMQConsumer:
                 hostIds=messageData.get('hostIds'),
                 blueprintId=messageData.get('blueprintId'),
                 userId=messageData.get('userId'),
-                dtype = messageData.get('type')
+                dtype = messageData.get('type'),
+                requestId = messageData.get('requestId'),
+                credentialSecrets = messageData.get('credentialSecrets') or {}
             )
             
             hostsWithCredentials = hostRepository.getHostsWithCredentials(deploymentRabbitMqPlayload.hostIds)
@@ -98,10 +100,12 @@ class RabbitMQConsumer:
             # Populate credential values from Vault
             for _host, credential in hostsWithCredentials:
                 if credential and credential.name:
-                    vaultValue = getCredentialsByName(f"{credential.name}-{deploymentRabbitMqPlayload.userId}")
+                    # The backend resolves secret names, messages queued before it did fall back to the old naming
+                    secretName = deploymentRabbitMqPlayload.credentialSecrets.get(str(credential.id), f"{credential.name}-{deploymentRabbitMqPlayload.userId}")
+                    vaultValue = getCredentialsByName(secretName)
                     credential.value = vaultValue
             
-            logger.info(f"Fetched {len(hostsWithCredentials)} hosts with credentials")
+            logger.info(f"Fetched {len(hostsWithCredentials)} hosts with credentials for job {deploymentRabbitMqPlayload.jobId} (request {deploymentRabbitMqPlayload.requestId})")
             
             # Process the deployment using the existing controller
             playbookInfo = ansibleGenerator.generateNotebook(deploymentRabbitMqPlayload)
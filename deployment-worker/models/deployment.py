@@ -1,5 +1,5 @@
-from dataclasses import dataclass
-from typing import List
+from dataclasses import dataclass, field
+from typing import Dict, List, Optional
 from datetime import datetime
 from uuid import UUID
 from enum import Enum
@@ -23,6 +23,9 @@ class DeploymentRabbitMqPayload:
     blueprintId: int
     userId: str
     dtype: str
+    requestId: Optional[str] = None
+    # Secret name of every credential used by the hosts, keyed by credential id
+    credentialSecrets: Dict[str, str] = field(default_factory=dict)
 
 @dataclass
 class Deployment: